@@ -1,13 +1,9 @@
 package asr
 
-import (
-	"context"
-
-	"github.com/gayhub/4subs/internal/subtitle"
-)
+import "context"
 
 type Provider interface {
 	Name() string
 	Ready() bool
-	Transcribe(ctx context.Context, audioPath string, sourceLanguage string) ([]subtitle.Block, error)
+	Transcribe(ctx context.Context, audioPath string, sourceLanguage string) ([]Segment, error)
 }
@@ -0,0 +1,281 @@
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gayhub/4subs/internal/media"
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+const maxRetryAfter = 2 * time.Minute
+
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ASR 请求失败: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ASR 请求失败: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+type ChunkedOptions struct {
+	Workers      int
+	MaxRetries   int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	Segmentation SegmentOptions
+	Filter       *FilterOptions
+}
+
+type ChunkedStats struct {
+	Chunks   int
+	Requests int
+	Retries  int
+	Dropped  int
+	Filtered []FilterRemoval
+	Language string
+}
+
+func DefaultChunkedOptions() ChunkedOptions {
+	return ChunkedOptions{Workers: 2, MaxRetries: 3, BaseBackoff: 2 * time.Second, MaxBackoff: time.Minute}
+}
+
+func TranscribeChunks(ctx context.Context, provider Provider, chunks []media.AudioChunk, sourceLanguage string, options ChunkedOptions) ([]subtitle.Block, ChunkedStats, error) {
+	defaults := DefaultChunkedOptions()
+	if options.Workers <= 0 {
+		options.Workers = defaults.Workers
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = defaults.BaseBackoff
+	}
+	if options.MaxBackoff < options.BaseBackoff {
+		options.MaxBackoff = defaults.MaxBackoff
+	}
+	stats := ChunkedStats{Chunks: len(chunks)}
+	if len(chunks) == 0 {
+		return nil, stats, errors.New("没有可转写的音频分段")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]subtitle.Block, len(chunks))
+	tasks := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	languages := map[string]time.Duration{}
+	workers := options.Workers
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range tasks {
+				segments, attempts, err := transcribeWithRetry(runCtx, provider, chunks[index].Path, sourceLanguage, options)
+				var removed []FilterRemoval
+				if options.Filter != nil {
+					segments, removed = FilterSegments(segments, *options.Filter)
+				}
+				blocks, _ := Resegment(segments, options.Segmentation)
+				mu.Lock()
+				for _, segment := range segments {
+					if segment.Language != "" {
+						languages[strings.ToLower(segment.Language)] += max(segment.End-segment.Start, time.Millisecond)
+					}
+				}
+				for _, removal := range removed {
+					removal.Start += chunks[index].Start
+					removal.End += chunks[index].Start
+					if chunks[index].Keeps(removal.Start + (removal.End-removal.Start)/2) {
+						stats.Filtered = append(stats.Filtered, removal)
+					}
+				}
+				stats.Requests += attempts
+				if attempts > 1 {
+					stats.Retries += attempts - 1
+				}
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("第 %d 段音频转写失败: %w", chunks[index].Index, err)
+					cancel()
+				}
+				results[index] = blocks
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for index := range chunks {
+		select {
+		case tasks <- index:
+		case <-runCtx.Done():
+			break dispatch
+		}
+	}
+	close(tasks)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, stats, err
+	}
+	if firstErr != nil {
+		return nil, stats, firstErr
+	}
+	sort.SliceStable(stats.Filtered, func(i, j int) bool {
+		return stats.Filtered[i].Start < stats.Filtered[j].Start
+	})
+	for language, duration := range languages {
+		if stats.Language == "" || duration > languages[stats.Language] || (duration == languages[stats.Language] && language < stats.Language) {
+			stats.Language = language
+		}
+	}
+	blocks, dropped := stitchChunks(chunks, results)
+	stats.Dropped = dropped
+	if len(blocks) == 0 {
+		if len(stats.Filtered) > 0 {
+			return nil, stats, fmt.Errorf("ASR 的 %d 条结果全部被幻觉过滤规则移除，音频可能没有人声", len(stats.Filtered))
+		}
+		return nil, stats, errors.New("ASR segment 为空")
+	}
+	return blocks, stats, nil
+}
+
+func transcribeWithRetry(ctx context.Context, provider Provider, path string, sourceLanguage string, options ChunkedOptions) ([]Segment, int, error) {
+	attempts := 0
+	for {
+		segments, err := provider.Transcribe(ctx, path, sourceLanguage)
+		attempts++
+		if err == nil {
+			return segments, attempts, nil
+		}
+		wait, retryable := backoff(err, attempts-1, options)
+		if !retryable || attempts > options.MaxRetries || ctx.Err() != nil {
+			return nil, attempts, err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempts, err
+		case <-timer.C:
+		}
+	}
+}
+
+func backoff(err error, attempt int, options ChunkedOptions) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if !statusErr.Retryable() {
+			return 0, false
+		}
+	} else {
+		var netErr net.Error
+		if !errors.As(err, &netErr) {
+			return 0, false
+		}
+	}
+	wait := options.BaseBackoff << uint(attempt)
+	if wait > options.MaxBackoff || wait <= 0 {
+		wait = options.MaxBackoff
+	}
+	wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+	if statusErr != nil && statusErr.RetryAfter > wait {
+		wait = statusErr.RetryAfter
+		if wait > maxRetryAfter {
+			wait = maxRetryAfter
+		}
+	}
+	return wait, true
+}
+
+func stitchChunks(chunks []media.AudioChunk, results [][]subtitle.Block) ([]subtitle.Block, int) {
+	stitched := make([]subtitle.Block, 0, 256)
+	dropped := 0
+	for index, chunk := range chunks {
+		for _, block := range results[index] {
+			block.Start += chunk.Start
+			block.End += chunk.Start
+			if block.End < block.Start {
+				block.End = block.Start
+			}
+			if !chunk.Keeps(block.Start + (block.End-block.Start)/2) {
+				dropped++
+				continue
+			}
+			if len(stitched) > 0 && duplicateSegment(stitched[len(stitched)-1], block) {
+				last := &stitched[len(stitched)-1]
+				if block.End > last.End {
+					last.End = block.End
+				}
+				dropped++
+				continue
+			}
+			stitched = append(stitched, block)
+		}
+	}
+	sort.SliceStable(stitched, func(i, j int) bool {
+		return stitched[i].Start < stitched[j].Start
+	})
+	for index := range stitched {
+		stitched[index].Index = index + 1
+	}
+	return stitched, dropped
+}
+
+func duplicateSegment(previous subtitle.Block, next subtitle.Block) bool {
+	if next.Start >= previous.End {
+		return false
+	}
+	return comparableText(subtitle.JoinText(previous.Lines)) == comparableText(subtitle.JoinText(next.Lines))
+}
+
+func comparableText(value string) string {
+	var builder strings.Builder
+	for _, r := range value {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			continue
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
@@ -0,0 +1,161 @@
+package asr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type FilterOptions struct {
+	MaxNoSpeechProb float64
+	MinAvgLogprob   float64
+	MaxRepeats      int
+	Blocklist       []string
+}
+
+type FilterRemoval struct {
+	Start  time.Duration
+	End    time.Duration
+	Text   string
+	Reason string
+}
+
+func DefaultFilterOptions() FilterOptions {
+	return FilterOptions{MaxNoSpeechProb: 0.8, MinAvgLogprob: -1.5, MaxRepeats: 2}
+}
+
+func ParseBlocklist(raw string) []string {
+	entries := make([]string, 0, 16)
+	seen := map[string]bool{}
+	for _, line := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		key := comparableText(line)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, line)
+	}
+	return entries
+}
+
+func FilterSegments(segments []Segment, options FilterOptions) ([]Segment, []FilterRemoval) {
+	defaults := DefaultFilterOptions()
+	if options.MaxNoSpeechProb <= 0 || options.MaxNoSpeechProb > 1 {
+		options.MaxNoSpeechProb = defaults.MaxNoSpeechProb
+	}
+	if options.MinAvgLogprob >= 0 {
+		options.MinAvgLogprob = defaults.MinAvgLogprob
+	}
+	if options.MaxRepeats <= 0 {
+		options.MaxRepeats = defaults.MaxRepeats
+	}
+	kept := make([]Segment, 0, len(segments))
+	removed := make([]FilterRemoval, 0)
+	drop := func(segment Segment, reason string) {
+		removed = append(removed, FilterRemoval{Start: segment.Start, End: segment.End, Text: strings.TrimSpace(segment.Text), Reason: reason})
+	}
+	for _, segment := range segments {
+		if segment.NoSpeechProb != nil && *segment.NoSpeechProb > options.MaxNoSpeechProb {
+			drop(segment, fmt.Sprintf("静音概率 %.2f", *segment.NoSpeechProb))
+			continue
+		}
+		if segment.AvgLogprob != nil && *segment.AvgLogprob < options.MinAvgLogprob {
+			drop(segment, fmt.Sprintf("平均对数概率 %.2f", *segment.AvgLogprob))
+			continue
+		}
+		if entry := matchBlocklist(segment.Text, options.Blocklist); entry != "" {
+			drop(segment, fmt.Sprintf("命中屏蔽词「%s」", entry))
+			continue
+		}
+		if collapsed, times := collapseRepeatedSentences(segment.Text, options.MaxRepeats); times > 0 {
+			drop(segment, fmt.Sprintf("删除句内重复 %d 次", times))
+			segment.Text = collapsed
+			segment.Words = nil
+		}
+		if count := len(kept); count > 0 {
+			previous := &kept[count-1]
+			key := comparableText(segment.Text)
+			if key != "" && key == comparableText(previous.Text) {
+				run := 1
+				for index := count - 1; index >= 0 && comparableText(kept[index].Text) == key; index-- {
+					run++
+				}
+				if run > options.MaxRepeats {
+					drop(segment, fmt.Sprintf("连续重复超过 %d 次，已并入上一条", options.MaxRepeats))
+					if segment.End > previous.End {
+						previous.End = segment.End
+					}
+					continue
+				}
+			}
+		}
+		kept = append(kept, segment)
+	}
+	return kept, removed
+}
+
+func matchBlocklist(text string, blocklist []string) string {
+	key := comparableText(text)
+	if key == "" {
+		return ""
+	}
+	keyLength := len([]rune(key))
+	for _, entry := range blocklist {
+		entryKey := comparableText(entry)
+		if entryKey == "" || !strings.Contains(key, entryKey) {
+			continue
+		}
+		if len([]rune(entryKey))*2 >= keyLength {
+			return entry
+		}
+	}
+	return ""
+}
+
+func collapseRepeatedSentences(text string, maxRepeats int) (string, int) {
+	sentences := splitSentences(text)
+	if len(sentences) <= maxRepeats {
+		return text, 0
+	}
+	result := make([]string, 0, len(sentences))
+	removed := 0
+	run := 0
+	for index, sentence := range sentences {
+		if index > 0 && comparableText(sentence) != "" && comparableText(sentence) == comparableText(sentences[index-1]) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > maxRepeats {
+			removed++
+			continue
+		}
+		result = append(result, sentence)
+	}
+	if removed == 0 {
+		return text, 0
+	}
+	return strings.TrimSpace(strings.Join(result, "")), removed
+}
+
+func splitSentences(text string) []string {
+	sentences := make([]string, 0, 4)
+	var builder strings.Builder
+	closing := false
+	for _, char := range text {
+		if closing && !strings.ContainsRune(".!?…。！？\"'”’」』）)", char) {
+			sentences = append(sentences, builder.String())
+			builder.Reset()
+			closing = false
+		}
+		builder.WriteRune(char)
+		if strings.ContainsRune(".!?…。！？", char) {
+			closing = true
+		}
+	}
+	if builder.Len() > 0 {
+		sentences = append(sentences, builder.String())
+	}
+	return sentences
+}
@@ -6,34 +6,49 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/gayhub/4subs/internal/subtitle"
+	"github.com/gayhub/4subs/internal/asr"
+	"github.com/gayhub/4subs/internal/media"
 )
 
 type Client struct {
-	BaseURL    string
-	APIKey     string
-	Model      string
-	HTTPClient *http.Client
+	BaseURL        string
+	APIKey         string
+	Model          string
+	WordTimestamps bool
+	HTTPClient     *http.Client
 }
 
 type verboseResponse struct {
 	Text     string            `json:"text"`
+	Language string            `json:"language"`
 	Segments []segmentResponse `json:"segments"`
+	Words    []wordResponse    `json:"words"`
 }
 
 type segmentResponse struct {
-	ID    int     `json:"id"`
+	ID           int            `json:"id"`
+	Start        float64        `json:"start"`
+	End          float64        `json:"end"`
+	Text         string         `json:"text"`
+	Words        []wordResponse `json:"words"`
+	NoSpeechProb *float64       `json:"no_speech_prob"`
+	AvgLogprob   *float64       `json:"avg_logprob"`
+}
+
+type wordResponse struct {
+	Word  string  `json:"word"`
 	Start float64 `json:"start"`
 	End   float64 `json:"end"`
-	Text  string  `json:"text"`
 }
 
 func (c Client) Name() string {
@@ -44,19 +59,54 @@ func (c Client) Ready() bool {
 	return strings.TrimSpace(c.APIKey) != "" && strings.TrimSpace(c.Model) != "" && strings.TrimSpace(c.BaseURL) != ""
 }
 
-func (c Client) Transcribe(ctx context.Context, audioPath string, sourceLanguage string) ([]subtitle.Block, error) {
+func (c Client) Transcribe(ctx context.Context, audioPath string, sourceLanguage string) ([]asr.Segment, error) {
 	if !c.Ready() {
 		return nil, errors.New("ASR 尚未配置，请先填写 ASR_API_KEY")
 	}
-	contentType, body, err := c.buildMultipartBody(audioPath, sourceLanguage)
+	payload, err := c.requestTranscription(ctx, audioPath, sourceLanguage, c.WordTimestamps)
+	var statusErr *asr.StatusError
+	if c.WordTimestamps && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+		payload, err = c.requestTranscription(ctx, audioPath, sourceLanguage, false)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if len(payload.Segments) == 0 {
+		if strings.TrimSpace(payload.Text) == "" {
+			return nil, nil
+		}
+		return nil, errors.New("ASR 未返回带时间轴的 segment，请确认模型支持 verbose_json + segment 时间戳")
+	}
+	segments := make([]asr.Segment, 0, len(payload.Segments))
+	for _, item := range payload.Segments {
+		segments = append(segments, asr.Segment{
+			Start:        seconds(item.Start),
+			End:          seconds(item.End),
+			Text:         item.Text,
+			Words:        convertWords(item.Words),
+			NoSpeechProb: item.NoSpeechProb,
+			AvgLogprob:   item.AvgLogprob,
+			Language:     strings.TrimSpace(payload.Language),
+		})
+	}
+	if len(payload.Words) > 0 {
+		segments = asr.AssignWords(segments, convertWords(payload.Words))
+	}
+	return segments, nil
+}
+
+func (c Client) requestTranscription(ctx context.Context, audioPath string, sourceLanguage string, words bool) (verboseResponse, error) {
+	body, contentType, contentLength, err := c.streamMultipartBody(audioPath, sourceLanguage, words)
+	if err != nil {
+		return verboseResponse{}, err
+	}
+	defer func() { _ = body.Close() }()
 	endpoint := strings.TrimRight(strings.TrimSpace(c.BaseURL), "/") + "/audio/transcriptions"
 	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
 	if err != nil {
-		return nil, err
+		return verboseResponse{}, err
 	}
+	request.ContentLength = contentLength
 	request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(c.APIKey))
 	request.Header.Set("Content-Type", contentType)
 
@@ -66,65 +116,104 @@ func (c Client) Transcribe(ctx context.Context, audioPath string, sourceLanguage
 	}
 	response, err := httpClient.Do(request)
 	if err != nil {
-		return nil, err
+		return verboseResponse{}, err
 	}
 	defer func() { _ = response.Body.Close() }()
 
-	var payload verboseResponse
-	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return nil, fmt.Errorf("ASR 响应解析失败: %w", err)
-	}
 	if response.StatusCode >= 400 {
-		return nil, fmt.Errorf("ASR 请求失败: %s", payload.Text)
+		raw, _ := io.ReadAll(io.LimitReader(response.Body, 64<<10))
+		return verboseResponse{}, &asr.StatusError{
+			StatusCode: response.StatusCode,
+			RetryAfter: asr.ParseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+			Message:    errorMessage(raw),
+		}
 	}
-	if len(payload.Segments) == 0 {
-		return nil, errors.New("ASR 未返回带时间轴的 segment，请确认模型支持 verbose_json + segment 时间戳")
+	var payload verboseResponse
+	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return verboseResponse{}, fmt.Errorf("ASR 响应解析失败: %w", err)
 	}
-	blocks := make([]subtitle.Block, 0, len(payload.Segments))
-	for index, segment := range payload.Segments {
-		text := strings.TrimSpace(segment.Text)
-		if text == "" {
+	return payload, nil
+}
+
+func convertWords(items []wordResponse) []asr.Word {
+	words := make([]asr.Word, 0, len(items))
+	for _, item := range items {
+		if strings.TrimSpace(item.Word) == "" {
 			continue
 		}
-		blocks = append(blocks, subtitle.Block{
-			Index: index + 1,
-			Start: time.Duration(segment.Start * float64(time.Second)),
-			End:   time.Duration(segment.End * float64(time.Second)),
-			Lines: []string{text},
-		})
+		words = append(words, asr.Word{Text: item.Word, Start: seconds(item.Start), End: seconds(item.End)})
 	}
-	if len(blocks) == 0 {
-		return nil, errors.New("ASR segment 为空")
+	return words
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+func errorMessage(raw []byte) string {
+	var payload struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &payload); err == nil {
+		if payload.Error != nil && payload.Error.Message != "" {
+			return payload.Error.Message
+		}
+		if payload.Text != "" {
+			return payload.Text
+		}
 	}
-	return blocks, nil
+	return strings.TrimSpace(string(raw))
 }
 
-func (c Client) buildMultipartBody(audioPath string, sourceLanguage string) (string, *bytes.Buffer, error) {
+func (c Client) streamMultipartBody(audioPath string, sourceLanguage string, words bool) (io.ReadCloser, string, int64, error) {
 	file, err := os.Open(audioPath)
 	if err != nil {
-		return "", nil, err
+		return nil, "", 0, err
 	}
-	defer func() { _ = file.Close() }()
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	fileWriter, err := writer.CreateFormFile("file", filepath.Base(audioPath))
+	stat, err := file.Stat()
 	if err != nil {
-		return "", nil, err
-	}
-	if _, err := file.WriteTo(fileWriter); err != nil {
-		return "", nil, err
+		_ = file.Close()
+		return nil, "", 0, err
 	}
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
 	_ = writer.WriteField("model", c.Model)
 	_ = writer.WriteField("response_format", "verbose_json")
 	_ = writer.WriteField("timestamp_granularities[]", "segment")
+	if words {
+		_ = writer.WriteField("timestamp_granularities[]", "word")
+	}
 	if strings.TrimSpace(sourceLanguage) != "" && strings.TrimSpace(sourceLanguage) != "auto" {
 		_ = writer.WriteField("language", normalizeLanguageCode(sourceLanguage))
 	}
-	if err := writer.Close(); err != nil {
-		return "", nil, err
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(filepath.Base(audioPath))))
+	header.Set("Content-Type", media.AudioContentType(audioPath))
+	if _, err := writer.CreatePart(header); err != nil {
+		_ = file.Close()
+		return nil, "", 0, err
 	}
-	return writer.FormDataContentType(), body, nil
+	prefix := append([]byte(nil), buffer.Bytes()...)
+	buffer.Reset()
+	if err := writer.Close(); err != nil {
+		_ = file.Close()
+		return nil, "", 0, err
+	}
+	suffix := append([]byte(nil), buffer.Bytes()...)
+	body := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), file, bytes.NewReader(suffix)), file}
+	return body, writer.FormDataContentType(), int64(len(prefix)) + stat.Size() + int64(len(suffix)), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(value string) string {
+	return quoteEscaper.Replace(value)
 }
 
 func normalizeLanguageCode(value string) string {
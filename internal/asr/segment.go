@@ -0,0 +1,329 @@
+package asr
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+const maxAlignSkip = 16
+
+type Word struct {
+	Text  string
+	Start time.Duration
+	End   time.Duration
+}
+
+type Segment struct {
+	Start        time.Duration
+	End          time.Duration
+	Text         string
+	Words        []Word
+	NoSpeechProb *float64
+	AvgLogprob   *float64
+	Language     string
+}
+
+type SegmentOptions struct {
+	MaxDuration time.Duration
+	MaxChars    int
+	PauseGap    time.Duration
+	MinDuration time.Duration
+}
+
+type cueToken struct {
+	text  string
+	start time.Duration
+	end   time.Duration
+}
+
+func DefaultSegmentOptions() SegmentOptions {
+	return SegmentOptions{MaxDuration: 6 * time.Second, MaxChars: 42, PauseGap: 600 * time.Millisecond, MinDuration: time.Second}
+}
+
+func (o SegmentOptions) Normalize() SegmentOptions {
+	defaults := DefaultSegmentOptions()
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = defaults.MaxDuration
+	}
+	if o.MaxChars <= 0 {
+		o.MaxChars = defaults.MaxChars
+	}
+	if o.PauseGap <= 0 {
+		o.PauseGap = defaults.PauseGap
+	}
+	if o.MinDuration <= 0 {
+		o.MinDuration = defaults.MinDuration
+	}
+	if o.MinDuration > o.MaxDuration {
+		o.MinDuration = o.MaxDuration
+	}
+	return o
+}
+
+func Resegment(segments []Segment, options SegmentOptions) ([]subtitle.Block, int) {
+	options = options.Normalize()
+	blocks := make([]subtitle.Block, 0, len(segments)*2)
+	fromWords := 0
+	for _, segment := range segments {
+		text := strings.TrimSpace(segment.Text)
+		var tokens []cueToken
+		if len(segment.Words) > 0 {
+			tokens = alignWords(text, segment.Words)
+			fromWords++
+		} else {
+			if text == "" {
+				continue
+			}
+			if textWidth(text) <= options.MaxChars && segment.End-segment.Start <= options.MaxDuration {
+				blocks = append(blocks, subtitle.Block{Start: segment.Start, End: segment.End, Lines: []string{text}})
+				continue
+			}
+			tokens = proportionalTokens(text, segment.Start, segment.End)
+		}
+		blocks = append(blocks, splitTokens(tokens, options)...)
+	}
+	for index := range blocks {
+		if index+1 < len(blocks) {
+			limit := blocks[index+1].Start
+			if blocks[index].End > limit && limit > blocks[index].Start {
+				blocks[index].End = limit
+			}
+		}
+		if minimum := blocks[index].Start + options.MinDuration; blocks[index].End < minimum {
+			blocks[index].End = minimum
+			if index+1 < len(blocks) && blocks[index+1].Start > blocks[index].Start && blocks[index].End > blocks[index+1].Start {
+				blocks[index].End = blocks[index+1].Start
+			}
+		}
+		blocks[index].Index = index + 1
+	}
+	return blocks, fromWords
+}
+
+func AssignWords(segments []Segment, words []Word) []Segment {
+	if len(segments) == 0 || len(words) == 0 {
+		return segments
+	}
+	current := 0
+	for _, word := range words {
+		middle := word.Start + (word.End-word.Start)/2
+		for current+1 < len(segments) && middle >= segments[current].End && middle >= segments[current+1].Start {
+			current++
+		}
+		segments[current].Words = append(segments[current].Words, word)
+	}
+	return segments
+}
+
+func splitTokens(tokens []cueToken, options SegmentOptions) []subtitle.Block {
+	blocks := make([]subtitle.Block, 0, 4)
+	current := make([]cueToken, 0, 16)
+	flush := func(count int) {
+		if count <= 0 {
+			return
+		}
+		text := joinTokens(current[:count])
+		if text != "" {
+			blocks = append(blocks, subtitle.Block{Start: current[0].start, End: current[count-1].end, Lines: []string{text}})
+		}
+		current = append(current[:0], current[count:]...)
+	}
+	for _, token := range tokens {
+		if len(current) > 0 && token.start-current[len(current)-1].end >= options.PauseGap {
+			flush(len(current))
+		}
+		if len(current) > 0 {
+			width := textWidth(joinTokens(append(current[:len(current):len(current)], token)))
+			if width > options.MaxChars || token.end-current[0].start > options.MaxDuration {
+				flush(breakPoint(current))
+			}
+		}
+		current = append(current, token)
+		if endsSentence(token.text) {
+			flush(len(current))
+		}
+	}
+	flush(len(current))
+	return blocks
+}
+
+func breakPoint(tokens []cueToken) int {
+	for index := len(tokens) - 1; index >= (len(tokens)+1)/2; index-- {
+		if endsClause(tokens[index-1].text) {
+			return index
+		}
+	}
+	return len(tokens)
+}
+
+func alignWords(text string, words []Word) []cueToken {
+	tokens := make([]cueToken, 0, len(words))
+	runes := []rune(text)
+	lower := lowerRunes(text)
+	position := 0
+	for _, word := range words {
+		needle := lowerRunes(strings.TrimSpace(word.Text))
+		if len(needle) == 0 {
+			continue
+		}
+		token := cueToken{start: word.Start, end: word.End}
+		found := indexRunes(lower, needle, position)
+		if found >= 0 && (found-position <= len(needle)+maxAlignSkip || onlySpaceOrPunct(runes[position:found])) {
+			end := found + len(needle)
+			for end < len(runes) && isTrailingPunct(runes[end]) {
+				end++
+			}
+			token.text = string(runes[position:end])
+			position = end
+		} else {
+			token.text = spacedWord(strings.TrimSpace(word.Text), tokens)
+		}
+		tokens = append(tokens, token)
+	}
+	if position < len(runes) && len(tokens) > 0 && onlySpaceOrPunct(runes[position:]) {
+		tokens[len(tokens)-1].text += strings.TrimRightFunc(string(runes[position:]), unicode.IsSpace)
+	}
+	return tokens
+}
+
+func lowerRunes(value string) []rune {
+	runes := []rune(value)
+	for index, char := range runes {
+		runes[index] = unicode.ToLower(char)
+	}
+	return runes
+}
+
+func proportionalTokens(text string, start time.Duration, end time.Duration) []cueToken {
+	pieces := make([]string, 0, 32)
+	var builder strings.Builder
+	var previous rune
+	for _, char := range text {
+		if builder.Len() > 0 && startsPiece(previous, char) {
+			pieces = append(pieces, builder.String())
+			builder.Reset()
+		}
+		builder.WriteRune(char)
+		previous = char
+	}
+	if builder.Len() > 0 {
+		pieces = append(pieces, builder.String())
+	}
+	total := 0
+	for _, piece := range pieces {
+		total += textWidth(piece)
+	}
+	if total == 0 {
+		return nil
+	}
+	tokens := make([]cueToken, 0, len(pieces))
+	span := float64(end - start)
+	consumed := 0
+	for _, piece := range pieces {
+		from := start + time.Duration(span*float64(consumed)/float64(total))
+		consumed += textWidth(piece)
+		to := start + time.Duration(span*float64(consumed)/float64(total))
+		tokens = append(tokens, cueToken{text: piece, start: from, end: to})
+	}
+	return tokens
+}
+
+func startsPiece(previous rune, char rune) bool {
+	switch {
+	case unicode.IsSpace(char):
+		return !unicode.IsSpace(previous)
+	case isTrailingPunct(char) || unicode.IsSpace(previous):
+		return false
+	case isWide(char):
+		return true
+	default:
+		return isWide(previous)
+	}
+}
+
+func joinTokens(tokens []cueToken) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteString(token.text)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func spacedWord(word string, previous []cueToken) string {
+	if len(previous) == 0 || word == "" {
+		return word
+	}
+	last := []rune(previous[len(previous)-1].text)
+	first := []rune(word)
+	if len(last) == 0 || isWide(last[len(last)-1]) || isWide(first[0]) || isTrailingPunct(first[0]) {
+		return word
+	}
+	return " " + word
+}
+
+func textWidth(value string) int {
+	width := 0
+	for _, char := range strings.TrimSpace(value) {
+		if isWide(char) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func isWide(char rune) bool {
+	return unicode.Is(unicode.Han, char) || unicode.Is(unicode.Hiragana, char) || unicode.Is(unicode.Katakana, char) ||
+		unicode.Is(unicode.Hangul, char) || (char >= 0x3000 && char <= 0x303f) || (char >= 0xff00 && char <= 0xffef)
+}
+
+func isTrailingPunct(char rune) bool {
+	return strings.ContainsRune(".,!?;:…，。！？；：、」』）)]\"'”’", char)
+}
+
+func endsSentence(text string) bool {
+	text = strings.TrimRight(strings.TrimSpace(text), "\"'”’」』）)]")
+	if text == "" {
+		return false
+	}
+	last := []rune(text)
+	return strings.ContainsRune(".!?…。！？", last[len(last)-1])
+}
+
+func endsClause(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return false
+	}
+	last := []rune(text)
+	return endsSentence(text) || strings.ContainsRune(",;:，；：、", last[len(last)-1])
+}
+
+func onlySpaceOrPunct(runes []rune) bool {
+	for _, char := range runes {
+		if !unicode.IsSpace(char) && !unicode.IsPunct(char) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexRunes(haystack []rune, needle []rune, from int) int {
+	for index := from; index+len(needle) <= len(haystack); index++ {
+		match := true
+		for offset, char := range needle {
+			if haystack[index+offset] != char {
+				match = false
+				break
+			}
+		}
+		if match {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,127 @@
+package asr
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResegment(t *testing.T) {
+	ms := func(value int) time.Duration {
+		return time.Duration(value) * time.Millisecond
+	}
+	words := func(spec ...any) []Word {
+		result := make([]Word, 0, len(spec)/3)
+		for index := 0; index+2 < len(spec); index += 3 {
+			result = append(result, Word{Text: spec[index].(string), Start: ms(spec[index+1].(int)), End: ms(spec[index+2].(int))})
+		}
+		return result
+	}
+	type cue struct {
+		text  string
+		start time.Duration
+		end   time.Duration
+	}
+	tests := []struct {
+		name      string
+		segments  []Segment
+		options   SegmentOptions
+		want      []cue
+		fromWords int
+	}{
+		{
+			name: "sentence end splits on word times",
+			segments: []Segment{{
+				Start: 0, End: ms(4000), Text: "Hello there. How are you?",
+				Words: words("Hello", 0, 400, "there", 450, 900, "How", 1000, 1200, "are", 1250, 1400, "you", 1450, 1900),
+			}},
+			want:      []cue{{"Hello there.", 0, ms(1000)}, {"How are you?", ms(1000), ms(2000)}},
+			fromWords: 1,
+		},
+		{
+			name: "pause splits a run-on sentence",
+			segments: []Segment{{
+				Start: 0, End: ms(5000), Text: "I think that we should go now",
+				Words: words("I", 0, 200, "think", 250, 600, "that", 650, 900, "we", 2000, 2200, "should", 2250, 2600, "go", 2650, 2800, "now", 2850, 3300),
+			}},
+			want:      []cue{{"I think that", 0, ms(1000)}, {"we should go now", ms(2000), ms(3300)}},
+			fromWords: 1,
+		},
+		{
+			name: "maximum characters break at a clause",
+			segments: []Segment{{
+				Start: 0, End: ms(4000), Text: "If you want it, then take it right now",
+				Words: words("If", 0, 300, "you", 350, 500, "want", 550, 800, "it", 850, 1200, "then", 1250, 1500, "take", 1550, 1800, "it", 1850, 2000, "right", 2050, 2300, "now", 2350, 2700),
+			}},
+			options:   SegmentOptions{MaxChars: 24},
+			want:      []cue{{"If you want it,", 0, ms(1200)}, {"then take it right now", ms(1250), ms(2700)}},
+			fromWords: 1,
+		},
+		{
+			name: "maximum duration splits a long unpunctuated run",
+			segments: []Segment{{
+				Start: 0, End: ms(5000), Text: "one two three four five six seven eight",
+				Words: words("one", 0, 400, "two", 500, 900, "three", 1000, 1400, "four", 1500, 1900, "five", 2000, 2400, "six", 2500, 2900, "seven", 3000, 3400, "eight", 3500, 3900),
+			}},
+			options:   SegmentOptions{MaxDuration: 2 * time.Second},
+			want:      []cue{{"one two three four", 0, ms(1900)}, {"five six seven eight", ms(2000), ms(3900)}},
+			fromWords: 1,
+		},
+		{
+			name: "chinese characters split on full stops",
+			segments: []Segment{{
+				Start: 0, End: ms(3000), Text: "你好。我很好。",
+				Words: words("你", 0, 200, "好", 200, 400, "我", 1000, 1200, "很", 1200, 1400, "好", 1400, 1600),
+			}},
+			want:      []cue{{"你好。", 0, ms(1000)}, {"我很好。", ms(1000), ms(2000)}},
+			fromWords: 1,
+		},
+		{
+			name:     "short segment without words is kept",
+			segments: []Segment{{Start: ms(500), End: ms(2500), Text: "Just this."}},
+			want:     []cue{{"Just this.", ms(500), ms(2500)}},
+		},
+		{
+			name:     "long segment without words is split proportionally",
+			segments: []Segment{{Start: 0, End: ms(8000), Text: "This is the first sentence. And this one is the second sentence."}},
+			want:     []cue{{"This is the first sentence.", 0, ms(3471)}, {"And this one is the second sentence.", ms(3471), ms(8000)}},
+		},
+		{
+			name:     "empty segments are skipped",
+			segments: []Segment{{Start: 0, End: ms(1000), Text: "  "}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks, fromWords := Resegment(test.segments, test.options)
+			if fromWords != test.fromWords {
+				t.Fatalf("fromWords %d, want %d", fromWords, test.fromWords)
+			}
+			if len(blocks) != len(test.want) {
+				t.Fatalf("got %d blocks, want %d: %+v", len(blocks), len(test.want), blocks)
+			}
+			for index, block := range blocks {
+				text := strings.Join(block.Lines, "\n")
+				if block.Index != index+1 || text != test.want[index].text || block.Start.Truncate(time.Millisecond) != test.want[index].start || block.End.Truncate(time.Millisecond) != test.want[index].end {
+					t.Fatalf("block %d is %d %q %s-%s, want %q %s-%s", index, block.Index, text, block.Start, block.End, test.want[index].text, test.want[index].start, test.want[index].end)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignWords(t *testing.T) {
+	segments := []Segment{
+		{Start: 0, End: 2 * time.Second, Text: "one two"},
+		{Start: 2 * time.Second, End: 4 * time.Second, Text: "three"},
+	}
+	words := []Word{
+		{Text: "one", Start: 0, End: 500 * time.Millisecond},
+		{Text: "two", Start: 1500 * time.Millisecond, End: 2100 * time.Millisecond},
+		{Text: "three", Start: 2200 * time.Millisecond, End: 3000 * time.Millisecond},
+	}
+	assigned := AssignWords(segments, words)
+	if len(assigned[0].Words) != 2 || len(assigned[1].Words) != 1 || assigned[1].Words[0].Text != "three" {
+		t.Fatalf("unexpected assignment: %+v", assigned)
+	}
+}
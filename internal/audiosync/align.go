@@ -0,0 +1,251 @@
+package audiosync
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+type Options struct {
+	MaxOffset     time.Duration
+	Scales        []float64
+	RefineWindows int
+	RefineRange   time.Duration
+}
+
+type Result struct {
+	Offset     time.Duration `json:"offset"`
+	Scale      float64       `json:"scale"`
+	Confidence float64       `json:"confidence"`
+	Coverage   float64       `json:"coverage"`
+}
+
+type cueSpan struct {
+	start time.Duration
+	end   time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxOffset:     60 * time.Second,
+		Scales:        []float64{1, 25 / 23.976, 23.976 / 25, 24 / 23.976, 23.976 / 24, 25.0 / 24, 24.0 / 25},
+		RefineWindows: 4,
+		RefineRange:   2 * time.Second,
+	}
+}
+
+func (r Result) Apply(blocks []subtitle.Block) []subtitle.Block {
+	return subtitle.Retime(blocks, r.Scale, r.Offset)
+}
+
+func (r Result) Drift() float64 {
+	return r.Scale - 1
+}
+
+func Estimate(activity Activity, blocks []subtitle.Block, options Options) (Result, error) {
+	defaults := DefaultOptions()
+	if options.MaxOffset <= 0 {
+		options.MaxOffset = defaults.MaxOffset
+	}
+	if len(options.Scales) == 0 {
+		options.Scales = defaults.Scales
+	}
+	if options.RefineRange <= 0 {
+		options.RefineRange = defaults.RefineRange
+	}
+	if len(activity.Speech) == 0 || activity.Frame <= 0 {
+		return Result{}, errors.New("没有可用的语音活动数据")
+	}
+	spans := make([]cueSpan, 0, len(blocks))
+	for _, block := range blocks {
+		if block.End > block.Start {
+			spans = append(spans, cueSpan{start: block.Start, end: block.End})
+		}
+	}
+	if len(spans) == 0 {
+		return Result{}, errors.New("字幕块为空，无法进行音频同步")
+	}
+
+	aligner := newAligner(activity)
+	maxLag := int(options.MaxOffset / activity.Frame)
+	best := Result{Scale: 1}
+	bestScore := math.Inf(-1)
+	var bestCurve []float64
+	bestLag := 0
+	for _, scale := range options.Scales {
+		curve := make([]float64, 2*maxLag+1)
+		for lag := -maxLag; lag <= maxLag; lag++ {
+			curve[lag+maxLag] = aligner.score(spans, scale, 0, lag)
+		}
+		for position, score := range curve {
+			if score > bestScore {
+				bestScore = score
+				bestCurve = curve
+				bestLag = position - maxLag
+				best = Result{Scale: scale, Offset: time.Duration(bestLag) * activity.Frame}
+			}
+		}
+	}
+	if bestScore <= 0 {
+		return Result{Scale: 1}, errors.New("字幕时间轴与语音活动没有明显相关性")
+	}
+
+	best = aligner.refine(spans, best, options)
+	best.Coverage = aligner.coverage(spans, best.Scale, best.Offset)
+	best.Confidence = confidence(best.Coverage, aligner.ratio, bestCurve, bestLag+maxLag, int(time.Second/activity.Frame))
+	return best, nil
+}
+
+type aligner struct {
+	frame  time.Duration
+	prefix []int
+	ratio  float64
+}
+
+func newAligner(activity Activity) aligner {
+	prefix := make([]int, len(activity.Speech)+1)
+	for index, speech := range activity.Speech {
+		prefix[index+1] = prefix[index]
+		if speech {
+			prefix[index+1]++
+		}
+	}
+	return aligner{frame: activity.Frame, prefix: prefix, ratio: activity.Ratio()}
+}
+
+func (a aligner) frameRange(span cueSpan, scale float64, offset time.Duration, lag int) (int, int) {
+	limit := len(a.prefix) - 1
+	start := int(math.Round((float64(span.start)*scale+float64(offset))/float64(a.frame))) + lag
+	end := int(math.Round((float64(span.end)*scale+float64(offset))/float64(a.frame))) + lag
+	if start < 0 {
+		start = 0
+	}
+	if end > limit {
+		end = limit
+	}
+	return start, end
+}
+
+func (a aligner) score(spans []cueSpan, scale float64, offset time.Duration, lag int) float64 {
+	total := 0.0
+	for _, span := range spans {
+		start, end := a.frameRange(span, scale, offset, lag)
+		if end <= start {
+			continue
+		}
+		total += float64(a.prefix[end]-a.prefix[start]) - a.ratio*float64(end-start)
+	}
+	return total
+}
+
+func (a aligner) coverage(spans []cueSpan, scale float64, offset time.Duration) float64 {
+	covered := 0
+	length := 0
+	for _, span := range spans {
+		start, end := a.frameRange(span, scale, offset, 0)
+		if end <= start {
+			continue
+		}
+		covered += a.prefix[end] - a.prefix[start]
+		length += end - start
+	}
+	if length == 0 {
+		return 0
+	}
+	return float64(covered) / float64(length)
+}
+
+func (a aligner) refine(spans []cueSpan, result Result, options Options) Result {
+	windows := options.RefineWindows
+	if windows < 2 || len(spans) < windows*8 {
+		return result
+	}
+	maxLag := int(options.RefineRange / a.frame)
+	centers := make([]float64, 0, windows)
+	residuals := make([]float64, 0, windows)
+	size := len(spans) / windows
+	for window := 0; window < windows; window++ {
+		group := spans[window*size : (window+1)*size]
+		if window == windows-1 {
+			group = spans[window*size:]
+		}
+		bestLag := 0
+		bestScore := math.Inf(-1)
+		for lag := -maxLag; lag <= maxLag; lag++ {
+			if score := a.score(group, result.Scale, result.Offset, lag); score > bestScore {
+				bestScore = score
+				bestLag = lag
+			}
+		}
+		if bestScore <= 0 {
+			continue
+		}
+		middle := group[len(group)/2]
+		centers = append(centers, float64(middle.start)*result.Scale+float64(result.Offset))
+		residuals = append(residuals, float64(time.Duration(bestLag)*a.frame))
+	}
+	if len(centers) < 2 {
+		return result
+	}
+	slope, intercept := linearFit(centers, residuals)
+	refined := result
+	refined.Scale = result.Scale * (1 + slope)
+	refined.Offset = time.Duration((1+slope)*float64(result.Offset) + intercept)
+	if a.score(spans, refined.Scale, refined.Offset, 0) < a.score(spans, result.Scale, result.Offset, 0) {
+		return result
+	}
+	return refined
+}
+
+func linearFit(xs []float64, ys []float64) (float64, float64) {
+	var meanX, meanY float64
+	for index := range xs {
+		meanX += xs[index]
+		meanY += ys[index]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+	var numerator, denominator float64
+	for index := range xs {
+		numerator += (xs[index] - meanX) * (ys[index] - meanY)
+		denominator += (xs[index] - meanX) * (xs[index] - meanX)
+	}
+	if denominator == 0 {
+		return 0, meanY
+	}
+	slope := numerator / denominator
+	return slope, meanY - slope*meanX
+}
+
+func confidence(coverage float64, ratio float64, curve []float64, peak int, exclusion int) float64 {
+	agreement := 0.0
+	if ratio < 1 {
+		agreement = (coverage - ratio) / (1 - ratio)
+	}
+	distinct := 0.0
+	if peak >= 0 && peak < len(curve) && curve[peak] > 0 {
+		second := 0.0
+		for position, score := range curve {
+			if position >= peak-exclusion && position <= peak+exclusion {
+				continue
+			}
+			if score > second {
+				second = score
+			}
+		}
+		distinct = 1 - second/curve[peak]
+	}
+	return clamp01((clamp01(agreement) + clamp01(distinct)) / 2)
+}
+
+func clamp01(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
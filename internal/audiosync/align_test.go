@@ -0,0 +1,89 @@
+package audiosync
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+func TestEstimate(t *testing.T) {
+	const frame = 50 * time.Millisecond
+	blocks := make([]subtitle.Block, 0, 48)
+	seed := uint32(7)
+	next := func(limit int) int {
+		seed = seed*1664525 + 1013904223
+		return int(seed>>16) % limit
+	}
+	at := 2 * time.Second
+	for index := 0; index < 48; index++ {
+		at += time.Duration(300+next(2500)) * time.Millisecond
+		length := time.Duration(800+next(2200)) * time.Millisecond
+		blocks = append(blocks, subtitle.Block{Index: index + 1, Start: at, End: at + length, Lines: []string{"line"}})
+		at += length
+	}
+	activityFor := func(scale float64, offset time.Duration) Activity {
+		speech := make([]bool, int((time.Duration(float64(at)*scale)+offset+10*time.Second)/frame))
+		for _, block := range blocks {
+			start := int((time.Duration(float64(block.Start)*scale) + offset) / frame)
+			end := int((time.Duration(float64(block.End)*scale) + offset) / frame)
+			for position := max(start, 0); position < end && position < len(speech); position++ {
+				speech[position] = true
+			}
+		}
+		return Activity{Frame: frame, Speech: speech}
+	}
+	tests := []struct {
+		name   string
+		scale  float64
+		offset time.Duration
+	}{
+		{name: "in sync", scale: 1, offset: 0},
+		{name: "late subtitles", scale: 1, offset: 3200 * time.Millisecond},
+		{name: "early subtitles", scale: 1, offset: -1500 * time.Millisecond},
+		{name: "frame rate drift", scale: 25 / 23.976, offset: 800 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Estimate(activityFor(test.scale, test.offset), blocks, Options{MaxOffset: 10 * time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(result.Scale-test.scale) > 0.001 {
+				t.Fatalf("scale %.4f, want %.4f", result.Scale, test.scale)
+			}
+			if diff := result.Offset - test.offset; diff > 2*frame || diff < -2*frame {
+				t.Fatalf("offset %s, want %s", result.Offset, test.offset)
+			}
+			if result.Confidence < 0.5 || result.Coverage < 0.9 {
+				t.Fatalf("low confidence %.2f coverage %.2f", result.Confidence, result.Coverage)
+			}
+			synced := result.Apply(blocks)
+			expected := time.Duration(float64(blocks[10].Start)*test.scale) + test.offset
+			if diff := synced[10].Start - expected; diff > 3*frame || diff < -3*frame {
+				t.Fatalf("retimed start %s, want %s", synced[10].Start, expected)
+			}
+		})
+	}
+}
+
+func TestEstimateRejectsUnusableInput(t *testing.T) {
+	blocks := []subtitle.Block{{Start: time.Second, End: 2 * time.Second, Lines: []string{"a"}}}
+	tests := []struct {
+		name     string
+		activity Activity
+		blocks   []subtitle.Block
+	}{
+		{name: "no activity", activity: Activity{Frame: 50 * time.Millisecond}, blocks: blocks},
+		{name: "silent audio", activity: Activity{Frame: 50 * time.Millisecond, Speech: make([]bool, 200)}, blocks: blocks},
+		{name: "no cues", activity: Activity{Frame: 50 * time.Millisecond, Speech: []bool{true, false}}, blocks: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Estimate(test.activity, test.blocks, Options{MaxOffset: time.Second}); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
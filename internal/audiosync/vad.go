@@ -0,0 +1,135 @@
+package audiosync
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+type Activity struct {
+	Frame  time.Duration
+	Speech []bool
+}
+
+func (a Activity) Ratio() float64 {
+	if len(a.Speech) == 0 {
+		return 0
+	}
+	active := 0
+	for _, value := range a.Speech {
+		if value {
+			active++
+		}
+	}
+	return float64(active) / float64(len(a.Speech))
+}
+
+func DetectSpeech(reader io.Reader, sampleRate int, frame time.Duration) (Activity, error) {
+	if sampleRate <= 0 {
+		return Activity{}, errors.New("采样率必须大于 0")
+	}
+	if frame <= 0 {
+		frame = 50 * time.Millisecond
+	}
+	samplesPerFrame := int(float64(sampleRate) * frame.Seconds())
+	if samplesPerFrame <= 0 {
+		samplesPerFrame = 1
+	}
+	energies := make([]float64, 0, 4096)
+	buffered := bufio.NewReaderSize(reader, 64*1024)
+	sample := make([]byte, 2)
+	var sum float64
+	count := 0
+	for {
+		if _, err := io.ReadFull(buffered, sample); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return Activity{}, err
+		}
+		value := float64(int16(binary.LittleEndian.Uint16(sample))) / 32768
+		sum += value * value
+		count++
+		if count == samplesPerFrame {
+			energies = append(energies, energyDB(sum, count))
+			sum = 0
+			count = 0
+		}
+	}
+	if count > 0 {
+		energies = append(energies, energyDB(sum, count))
+	}
+	if len(energies) == 0 {
+		return Activity{}, errors.New("音频为空，无法检测语音活动")
+	}
+	return Activity{Frame: frame, Speech: classifyEnergies(energies, frame)}, nil
+}
+
+func energyDB(sum float64, count int) float64 {
+	rms := math.Sqrt(sum / float64(count))
+	if rms < 1e-6 {
+		return -120
+	}
+	return 20 * math.Log10(rms)
+}
+
+func classifyEnergies(energies []float64, frame time.Duration) []bool {
+	sorted := append([]float64{}, energies...)
+	sort.Float64s(sorted)
+	noise := sorted[len(sorted)/10]
+	peak := sorted[len(sorted)*9/10]
+	threshold := noise + 0.35*(peak-noise)
+	if peak-noise < 6 {
+		threshold = noise + 6
+	}
+	speech := make([]bool, len(energies))
+	for index, value := range energies {
+		speech[index] = value >= threshold
+	}
+	hangover := int(200 * time.Millisecond / frame)
+	minRun := int(100 * time.Millisecond / frame)
+	removeShortRuns(speech, minRun)
+	extendRuns(speech, hangover)
+	return speech
+}
+
+func removeShortRuns(speech []bool, minRun int) {
+	if minRun <= 1 {
+		return
+	}
+	for start := 0; start < len(speech); {
+		if !speech[start] {
+			start++
+			continue
+		}
+		end := start
+		for end < len(speech) && speech[end] {
+			end++
+		}
+		if end-start < minRun {
+			for index := start; index < end; index++ {
+				speech[index] = false
+			}
+		}
+		start = end
+	}
+}
+
+func extendRuns(speech []bool, hangover int) {
+	original := append([]bool{}, speech...)
+	remaining := 0
+	for index := range speech {
+		if original[index] {
+			remaining = hangover
+			continue
+		}
+		if remaining > 0 {
+			speech[index] = true
+			remaining--
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package audiosync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDetectSpeech(t *testing.T) {
+	const sampleRate = 8000
+	type tone struct {
+		from      time.Duration
+		to        time.Duration
+		amplitude float64
+	}
+	render := func(length time.Duration, tones ...tone) []byte {
+		var buffer bytes.Buffer
+		count := int(length.Seconds() * sampleRate)
+		for index := 0; index < count; index++ {
+			at := time.Duration(float64(index) / sampleRate * float64(time.Second))
+			value := 0.001 * math.Sin(float64(index)*0.7)
+			for _, item := range tones {
+				if at >= item.from && at < item.to {
+					value += item.amplitude * math.Sin(2*math.Pi*220*float64(index)/sampleRate)
+				}
+			}
+			_ = binary.Write(&buffer, binary.LittleEndian, int16(value*32767))
+		}
+		return buffer.Bytes()
+	}
+	tests := []struct {
+		name    string
+		audio   []byte
+		speech  []time.Duration
+		silence []time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "tone between silences",
+			audio:   render(3*time.Second, tone{time.Second, 2 * time.Second, 0.5}),
+			speech:  []time.Duration{1050 * time.Millisecond, 1500 * time.Millisecond, 2100 * time.Millisecond},
+			silence: []time.Duration{500 * time.Millisecond, 2500 * time.Millisecond},
+		},
+		{
+			name:    "short click is ignored",
+			audio:   render(3*time.Second, tone{time.Second, 2 * time.Second, 0.5}, tone{2600 * time.Millisecond, 2650 * time.Millisecond, 0.5}),
+			speech:  []time.Duration{1500 * time.Millisecond},
+			silence: []time.Duration{2625 * time.Millisecond, 2800 * time.Millisecond},
+		},
+		{
+			name:    "empty audio",
+			audio:   nil,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			activity, err := DetectSpeech(bytes.NewReader(test.audio), sampleRate, 50*time.Millisecond)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := func(value time.Duration) bool {
+				return activity.Speech[int(value/activity.Frame)]
+			}
+			for _, value := range test.speech {
+				if !at(value) {
+					t.Fatalf("expected speech at %s", value)
+				}
+			}
+			for _, value := range test.silence {
+				if at(value) {
+					t.Fatalf("expected silence at %s", value)
+				}
+			}
+		})
+	}
+	if _, err := DetectSpeech(bytes.NewReader([]byte{0, 0}), 0, 0); err == nil {
+		t.Fatal("expected error for zero sample rate")
+	}
+}
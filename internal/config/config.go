@@ -2,12 +2,18 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+const (
+	OCRProviderVision  = "openai-compatible-vision"
+	OCRProviderCommand = "command"
+)
+
 type Config struct {
 	HTTPAddr             string
 	DBPath               string
@@ -18,6 +24,7 @@ type Config struct {
 	SubtitleOutputPath   string
 	MediaPaths           []string
 	FFmpegBin            string
+	FFprobeBin           string
 	TranslationProvider  string
 	DeepSeekBaseURL      string
 	DeepSeekAPIKey       string
@@ -26,13 +33,33 @@ type Config struct {
 	ASRBaseURL           string
 	ASRAPIKey            string
 	ASRModel             string
+	ASRChunkSeconds      int
+	ASRChunkOverlapSec   int
+	ASRConcurrency       int
+	ASRMaxRetries        int
+	ASRAudioCodec        string
+	ASRAudioBitrate      string
+	ASRWordTimestamps    bool
+	ASRMaxCueSeconds     float64
+	ASRMaxCueChars       int
+	DiarizeURL           string
+	DiarizeAPIKey        string
+	DiarizeMaxSpeakers   int
+	DiarizeTimeoutSec    int
 	OCRProvider          string
 	OCRBaseURL           string
 	OCRAPIKey            string
 	OCRModel             string
+	OCRSamplingMode      string
 	OCRFrameIntervalMS   int
 	OCRCropTopPercent    int
 	OCRCropHeightPercent int
+	OCRConcurrency       int
+	OCRRequestsPerSecond float64
+	OCRMaxRetries        int
+	OCRBatchSize         int
+	OCRCommand           string
+	OCRCommandTimeoutSec int
 	JobConcurrency       int
 	AppSecret            string
 }
@@ -47,6 +74,7 @@ func Load() (Config, error) {
 		SubtitleOutputPath:   envOrDefault("SUBTITLE_OUTPUT_PATH", "/app/subtitles"),
 		MediaPaths:           splitComma(envOrDefault("MEDIA_PATHS", "/media")),
 		FFmpegBin:            envOrDefault("FFMPEG_BIN", "ffmpeg"),
+		FFprobeBin:           envOrDefault("FFPROBE_BIN", "ffprobe"),
 		TranslationProvider:  envOrDefault("TRANSLATION_PROVIDER", "deepseek"),
 		DeepSeekBaseURL:      envOrDefault("DEEPSEEK_BASE_URL", "https://api.deepseek.com"),
 		DeepSeekAPIKey:       strings.TrimSpace(os.Getenv("DEEPSEEK_API_KEY")),
@@ -55,13 +83,33 @@ func Load() (Config, error) {
 		ASRBaseURL:           envOrDefault("ASR_BASE_URL", "https://api.openai.com/v1"),
 		ASRAPIKey:            strings.TrimSpace(os.Getenv("ASR_API_KEY")),
 		ASRModel:             envOrDefault("ASR_MODEL", "whisper-1"),
-		OCRProvider:          envOrDefault("OCR_PROVIDER", "openai-compatible-vision"),
+		ASRChunkSeconds:      intEnvOrDefault("ASR_CHUNK_SECONDS", 600),
+		ASRChunkOverlapSec:   intEnvOrDefault("ASR_CHUNK_OVERLAP_SECONDS", 2),
+		ASRConcurrency:       intEnvOrDefault("ASR_CONCURRENCY", 2),
+		ASRMaxRetries:        intEnvOrDefault("ASR_MAX_RETRIES", 3),
+		ASRAudioCodec:        strings.ToLower(envOrDefault("ASR_AUDIO_CODEC", "wav")),
+		ASRAudioBitrate:      strings.TrimSpace(os.Getenv("ASR_AUDIO_BITRATE")),
+		ASRWordTimestamps:    boolEnvOrDefault("ASR_WORD_TIMESTAMPS", true),
+		ASRMaxCueSeconds:     floatEnvOrDefault("ASR_MAX_CUE_SECONDS", 6),
+		ASRMaxCueChars:       intEnvOrDefault("ASR_MAX_CUE_CHARS", 42),
+		DiarizeURL:           strings.TrimSpace(os.Getenv("DIARIZATION_URL")),
+		DiarizeAPIKey:        strings.TrimSpace(os.Getenv("DIARIZATION_API_KEY")),
+		DiarizeMaxSpeakers:   intEnvOrDefault("DIARIZATION_MAX_SPEAKERS", 0),
+		DiarizeTimeoutSec:    intEnvOrDefault("DIARIZATION_TIMEOUT_SECONDS", 600),
+		OCRProvider:          strings.ToLower(envOrDefault("OCR_PROVIDER", OCRProviderVision)),
 		OCRBaseURL:           envOrDefault("OCR_BASE_URL", "https://api.openai.com/v1"),
 		OCRAPIKey:            strings.TrimSpace(os.Getenv("OCR_API_KEY")),
 		OCRModel:             envOrDefault("OCR_MODEL", "gpt-4.1-mini"),
+		OCRSamplingMode:      strings.ToLower(envOrDefault("OCR_SAMPLING_MODE", "interval")),
 		OCRFrameIntervalMS:   intEnvOrDefault("OCR_FRAME_INTERVAL_MS", 1000),
 		OCRCropTopPercent:    intEnvOrDefault("OCR_CROP_TOP_PERCENT", 72),
 		OCRCropHeightPercent: intEnvOrDefault("OCR_CROP_HEIGHT_PERCENT", 22),
+		OCRConcurrency:       intEnvOrDefault("OCR_CONCURRENCY", 4),
+		OCRRequestsPerSecond: floatEnvOrDefault("OCR_REQUESTS_PER_SECOND", -1),
+		OCRMaxRetries:        intEnvOrDefault("OCR_MAX_RETRIES", 3),
+		OCRBatchSize:         intEnvOrDefault("OCR_BATCH_SIZE", 1),
+		OCRCommand:           strings.TrimSpace(os.Getenv("OCR_COMMAND")),
+		OCRCommandTimeoutSec: intEnvOrDefault("OCR_COMMAND_TIMEOUT_SECONDS", 30),
 		JobConcurrency:       intEnvOrDefault("JOB_CONCURRENCY", 2),
 		AppSecret:            strings.TrimSpace(os.Getenv("APP_SECRET")),
 	}
@@ -73,6 +121,9 @@ func Load() (Config, error) {
 	if cfg.JobConcurrency <= 0 {
 		cfg.JobConcurrency = 1
 	}
+	if cfg.OCRSamplingMode != "interval" && cfg.OCRSamplingMode != "change" {
+		cfg.OCRSamplingMode = "interval"
+	}
 	if cfg.OCRFrameIntervalMS <= 0 {
 		cfg.OCRFrameIntervalMS = 1000
 	}
@@ -82,6 +133,18 @@ func Load() (Config, error) {
 	if cfg.OCRCropHeightPercent <= 0 || cfg.OCRCropHeightPercent > 100 {
 		cfg.OCRCropHeightPercent = 22
 	}
+	if cfg.OCRBatchSize > 16 {
+		cfg.OCRBatchSize = 16
+	}
+	if cfg.OCRProvider != OCRProviderVision && cfg.OCRProvider != OCRProviderCommand {
+		return Config{}, fmt.Errorf("不支持的 OCR_PROVIDER: %s，可选值为 %s 或 %s", cfg.OCRProvider, OCRProviderVision, OCRProviderCommand)
+	}
+	if cfg.OCRRequestsPerSecond < 0 {
+		cfg.OCRRequestsPerSecond = 4
+		if cfg.OCRProvider == OCRProviderCommand {
+			cfg.OCRRequestsPerSecond = 0
+		}
+	}
 	return cfg, nil
 }
 
@@ -128,3 +191,27 @@ func intEnvOrDefault(key string, fallback int) int {
 	}
 	return parsed
 }
+
+func boolEnvOrDefault(key string, fallback bool) bool {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func floatEnvOrDefault(key string, fallback float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOCRProvider(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{value: "", want: OCRProviderVision},
+		{value: "Command", want: OCRProviderCommand},
+		{value: OCRProviderVision, want: OCRProviderVision},
+		{value: "comand", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, key := range []string{"DATA_DIR", "WORK_DIR", "CONFIG_DIR", "SUBTITLE_OUTPUT_PATH"} {
+				t.Setenv(key, filepath.Join(dir, strings.ToLower(key)))
+			}
+			t.Setenv("OCR_PROVIDER", test.value)
+			cfg, err := Load()
+			if test.err {
+				if err == nil || !strings.Contains(err.Error(), "OCR_PROVIDER") {
+					t.Fatalf("error = %v, want an OCR_PROVIDER error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.OCRProvider != test.want {
+				t.Fatalf("OCRProvider = %q, want %q", cfg.OCRProvider, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE app_settings ADD COLUMN qa_json TEXT NOT NULL DEFAULT '{}';
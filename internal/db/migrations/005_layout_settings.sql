@@ -0,0 +1 @@
+ALTER TABLE app_settings ADD COLUMN layout_json TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE subtitle_jobs ADD COLUMN subtitle_stream_index INTEGER;
@@ -0,0 +1,8 @@
+ALTER TABLE subtitle_jobs ADD COLUMN ocr_regions_json TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE media_ocr_regions (
+    file_path TEXT PRIMARY KEY,
+    regions_json TEXT NOT NULL,
+    source TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
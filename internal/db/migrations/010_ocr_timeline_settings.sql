@@ -0,0 +1 @@
+ALTER TABLE app_settings ADD COLUMN ocr_timeline_json TEXT NOT NULL DEFAULT '{}';
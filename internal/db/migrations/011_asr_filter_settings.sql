@@ -0,0 +1 @@
+ALTER TABLE app_settings ADD COLUMN asr_filter_json TEXT NOT NULL DEFAULT '{}';
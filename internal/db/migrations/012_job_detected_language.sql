@@ -0,0 +1 @@
+ALTER TABLE subtitle_jobs ADD COLUMN detected_language TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE app_settings ADD COLUMN source_strategies_json TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE subtitle_jobs ADD COLUMN source_strategies_json TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE subtitle_jobs ADD COLUMN source_strategy TEXT NOT NULL DEFAULT '';
@@ -3,24 +3,34 @@ package db
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gayhub/4subs/internal/asr"
 	"github.com/gayhub/4subs/internal/config"
+	"github.com/gayhub/4subs/internal/fusion"
 	"github.com/gayhub/4subs/internal/model"
+	"github.com/gayhub/4subs/internal/ocr"
+	"github.com/gayhub/4subs/internal/pipeline"
+	"github.com/gayhub/4subs/internal/subtitle"
 	_ "modernc.org/sqlite"
 )
 
 //go:embed migrations/001_init.sql
 var initSQL string
 
-const schemaVersion = 3
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const baseSchemaVersion = 3
 
 type Repository struct {
 	db *sql.DB
@@ -34,6 +44,9 @@ type CreateJobInput struct {
 	TargetLanguage string
 	Provider       string
 	OutputFormats  []string
+	StreamIndex    *int
+	OCRRegions     []model.OCRRegion
+	Strategies     []string
 	Details        string
 }
 
@@ -65,14 +78,71 @@ func migrate(database *sql.DB) error {
 	if err := database.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
 		return err
 	}
-	if version == schemaVersion {
-		return nil
+	if version < baseSchemaVersion {
+		if err := applyMigration(database, initSQL, baseSchemaVersion); err != nil {
+			return err
+		}
+		version = baseSchemaVersion
 	}
-	if _, err := database.Exec(initSQL); err != nil {
+	steps, err := pendingMigrations(version)
+	if err != nil {
 		return err
 	}
-	_, err := database.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion))
-	return err
+	for _, step := range steps {
+		if err := applyMigration(database, step.sql, step.version); err != nil {
+			return fmt.Errorf("执行数据库迁移 %s 失败: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(database *sql.DB, statements string, version int) error {
+	tx, err := database.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(statements); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type migrationStep struct {
+	version int
+	name    string
+	sql     string
+}
+
+func pendingMigrations(current int) ([]migrationStep, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]migrationStep, 0, len(entries))
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			continue
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= baseSchemaVersion || version <= current {
+			continue
+		}
+		raw, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, migrationStep{version: version, name: entry.Name(), sql: string(raw)})
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].version < steps[j].version
+	})
+	return steps, nil
 }
 
 func NewRepository(database *sql.DB) *Repository {
@@ -100,6 +170,13 @@ func (r *Repository) EnsureDefaults(ctx context.Context, cfg config.Config) erro
 		CustomStylePrompt:   "",
 		Glossary:            "",
 		MaxSubtitlePerBatch: 20,
+		QA:                  defaultQASettings(),
+		Layout:              defaultLayoutSettings(),
+		Sync:                defaultSyncSettings(),
+		OCRTimeline:         defaultOCRTimelineSettings(),
+		ASRFilter:           defaultASRFilterSettings(),
+		SourceStrategies:    pipeline.DefaultSourceStrategies(),
+		Fusion:              defaultFusionSettings(),
 		UpdatedAt:           time.Now().UTC(),
 	}
 	return r.SaveSettings(ctx, settings)
@@ -109,13 +186,21 @@ func (r *Repository) GetSettings(ctx context.Context) (model.AppSettings, error)
 	var (
 		mediaPathsJSON    string
 		outputFormatsJSON string
+		qaJSON            string
+		layoutJSON        string
+		syncJSON          string
+		ocrTimelineJSON   string
+		asrFilterJSON     string
+		strategiesJSON    string
+		fusionJSON        string
 		updatedAtRaw      string
 		settings          model.AppSettings
 	)
 	row := r.db.QueryRowContext(ctx, `
 		SELECT media_paths_json, source_language, target_language, bilingual_layout,
 		       output_formats_json, translation_provider, translation_model,
-		       translation_prompt, max_subtitle_per_batch, updated_at
+		       translation_prompt, max_subtitle_per_batch, qa_json, layout_json, sync_json,
+		       ocr_timeline_json, asr_filter_json, source_strategies_json, fusion_json, updated_at
 		FROM app_settings WHERE id = 1`)
 	if err := row.Scan(
 		&mediaPathsJSON,
@@ -127,6 +212,13 @@ func (r *Repository) GetSettings(ctx context.Context) (model.AppSettings, error)
 		&settings.TranslationModel,
 		&settings.TranslationPrompt,
 		&settings.MaxSubtitlePerBatch,
+		&qaJSON,
+		&layoutJSON,
+		&syncJSON,
+		&ocrTimelineJSON,
+		&asrFilterJSON,
+		&strategiesJSON,
+		&fusionJSON,
 		&updatedAtRaw,
 	); err != nil {
 		return model.AppSettings{}, err
@@ -137,6 +229,40 @@ func (r *Repository) GetSettings(ctx context.Context) (model.AppSettings, error)
 	if err := json.Unmarshal([]byte(outputFormatsJSON), &settings.OutputFormats); err != nil {
 		return model.AppSettings{}, err
 	}
+	settings.QA = defaultQASettings()
+	if err := json.Unmarshal([]byte(qaJSON), &settings.QA); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.QA = normalizeQASettings(settings.QA)
+	settings.Layout = defaultLayoutSettings()
+	if err := json.Unmarshal([]byte(layoutJSON), &settings.Layout); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.Layout = normalizeLayoutSettings(settings.Layout)
+	settings.Sync = defaultSyncSettings()
+	if err := json.Unmarshal([]byte(syncJSON), &settings.Sync); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.Sync = normalizeSyncSettings(settings.Sync)
+	settings.OCRTimeline = defaultOCRTimelineSettings()
+	if err := json.Unmarshal([]byte(ocrTimelineJSON), &settings.OCRTimeline); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.OCRTimeline = normalizeOCRTimelineSettings(settings.OCRTimeline)
+	settings.ASRFilter = defaultASRFilterSettings()
+	if err := json.Unmarshal([]byte(asrFilterJSON), &settings.ASRFilter); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.ASRFilter = normalizeASRFilterSettings(settings.ASRFilter)
+	if err := json.Unmarshal([]byte(strategiesJSON), &settings.SourceStrategies); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.SourceStrategies = pipeline.NormalizeSourceStrategies(settings.SourceStrategies)
+	settings.Fusion = defaultFusionSettings()
+	if err := json.Unmarshal([]byte(fusionJSON), &settings.Fusion); err != nil {
+		return model.AppSettings{}, err
+	}
+	settings.Fusion = normalizeFusionSettings(settings.Fusion)
 	settings.UpdatedAt = parseTime(updatedAtRaw)
 	decodeTranslationPrompt(&settings)
 	return settings, nil
@@ -175,6 +301,13 @@ func (r *Repository) SaveSettings(ctx context.Context, settings model.AppSetting
 	if settings.MaxSubtitlePerBatch <= 0 {
 		settings.MaxSubtitlePerBatch = 20
 	}
+	settings.QA = normalizeQASettings(settings.QA)
+	settings.Layout = normalizeLayoutSettings(settings.Layout)
+	settings.Sync = normalizeSyncSettings(settings.Sync)
+	settings.OCRTimeline = normalizeOCRTimelineSettings(settings.OCRTimeline)
+	settings.ASRFilter = normalizeASRFilterSettings(settings.ASRFilter)
+	settings.SourceStrategies = pipeline.NormalizeSourceStrategies(settings.SourceStrategies)
+	settings.Fusion = normalizeFusionSettings(settings.Fusion)
 	settings.UpdatedAt = time.Now().UTC()
 	encodedPrompt, err := encodeTranslationPrompt(settings)
 	if err != nil {
@@ -189,13 +322,42 @@ func (r *Repository) SaveSettings(ctx context.Context, settings model.AppSetting
 	if err != nil {
 		return err
 	}
+	qaJSON, err := json.Marshal(settings.QA)
+	if err != nil {
+		return err
+	}
+	layoutJSON, err := json.Marshal(settings.Layout)
+	if err != nil {
+		return err
+	}
+	syncJSON, err := json.Marshal(settings.Sync)
+	if err != nil {
+		return err
+	}
+	ocrTimelineJSON, err := json.Marshal(settings.OCRTimeline)
+	if err != nil {
+		return err
+	}
+	asrFilterJSON, err := json.Marshal(settings.ASRFilter)
+	if err != nil {
+		return err
+	}
+	strategiesJSON, err := json.Marshal(settings.SourceStrategies)
+	if err != nil {
+		return err
+	}
+	fusionJSON, err := json.Marshal(settings.Fusion)
+	if err != nil {
+		return err
+	}
 
 	_, err = r.db.ExecContext(ctx, `
 		INSERT INTO app_settings (
 			id, media_paths_json, source_language, target_language, bilingual_layout,
 			output_formats_json, translation_provider, translation_model,
-			translation_prompt, max_subtitle_per_batch, updated_at
-		) VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			translation_prompt, max_subtitle_per_batch, qa_json, layout_json, sync_json,
+			ocr_timeline_json, asr_filter_json, source_strategies_json, fusion_json, updated_at
+		) VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			media_paths_json = excluded.media_paths_json,
 			source_language = excluded.source_language,
@@ -206,6 +368,13 @@ func (r *Repository) SaveSettings(ctx context.Context, settings model.AppSetting
 			translation_model = excluded.translation_model,
 			translation_prompt = excluded.translation_prompt,
 			max_subtitle_per_batch = excluded.max_subtitle_per_batch,
+			qa_json = excluded.qa_json,
+			layout_json = excluded.layout_json,
+			sync_json = excluded.sync_json,
+			ocr_timeline_json = excluded.ocr_timeline_json,
+			asr_filter_json = excluded.asr_filter_json,
+			source_strategies_json = excluded.source_strategies_json,
+			fusion_json = excluded.fusion_json,
 			updated_at = excluded.updated_at`,
 		string(mediaPathsJSON),
 		settings.SourceLanguage,
@@ -216,11 +385,178 @@ func (r *Repository) SaveSettings(ctx context.Context, settings model.AppSetting
 		settings.TranslationModel,
 		encodedPrompt,
 		settings.MaxSubtitlePerBatch,
+		string(qaJSON),
+		string(layoutJSON),
+		string(syncJSON),
+		string(ocrTimelineJSON),
+		string(asrFilterJSON),
+		string(strategiesJSON),
+		string(fusionJSON),
 		settings.UpdatedAt.Format(time.RFC3339),
 	)
 	return err
 }
 
+func defaultQASettings() model.QASettings {
+	defaults := subtitle.DefaultQAOptions()
+	return model.QASettings{
+		MaxCharsPerSecond: defaults.MaxCharsPerSecond,
+		MaxLineLength:     defaults.MaxLineLength,
+		MaxLinesPerSide:   defaults.MaxLinesPerSide,
+		MinOCRConfidence:  defaults.MinOCRConfidence,
+	}
+}
+
+func defaultLayoutSettings() model.LayoutSettings {
+	defaults := subtitle.DefaultLayoutOptions()
+	return model.LayoutSettings{
+		WrapEnabled:       defaults.WrapEnabled,
+		MaxLineWidth:      defaults.MaxLineWidth,
+		ExtendTiming:      defaults.ExtendTiming,
+		MaxCharsPerSecond: defaults.MaxCharsPerSecond,
+		MinGapMS:          int(defaults.MinGap / time.Millisecond),
+		MaxExtensionMS:    int(defaults.MaxExtension / time.Millisecond),
+	}
+}
+
+func normalizeLayoutSettings(settings model.LayoutSettings) model.LayoutSettings {
+	defaults := defaultLayoutSettings()
+	if settings.MaxLineWidth <= 0 {
+		settings.MaxLineWidth = defaults.MaxLineWidth
+	}
+	if settings.MaxCharsPerSecond <= 0 {
+		settings.MaxCharsPerSecond = defaults.MaxCharsPerSecond
+	}
+	if settings.MinGapMS < 0 {
+		settings.MinGapMS = defaults.MinGapMS
+	}
+	if settings.MaxExtensionMS < 0 {
+		settings.MaxExtensionMS = defaults.MaxExtensionMS
+	}
+	return settings
+}
+
+func defaultSyncSettings() model.SyncSettings {
+	return model.SyncSettings{
+		Enabled:       false,
+		MaxOffsetMS:   60000,
+		MinConfidence: 0.5,
+	}
+}
+
+func normalizeSyncSettings(settings model.SyncSettings) model.SyncSettings {
+	defaults := defaultSyncSettings()
+	if settings.MaxOffsetMS <= 0 {
+		settings.MaxOffsetMS = defaults.MaxOffsetMS
+	}
+	if settings.MinConfidence <= 0 || settings.MinConfidence > 1 {
+		settings.MinConfidence = defaults.MinConfidence
+	}
+	return settings
+}
+
+func defaultOCRTimelineSettings() model.OCRTimelineSettings {
+	defaults := ocr.DefaultTimelineOptions()
+	return model.OCRTimelineSettings{
+		MinDurationMS:       int(defaults.MinDuration / time.Millisecond),
+		MaxJoinGapMS:        int(defaults.MaxJoinGap / time.Millisecond),
+		MinTextLength:       defaults.MinTextLength,
+		StableFrames:        defaults.StableFrames,
+		ConfidenceFloor:     defaults.ConfidenceFloor,
+		SimilarityThreshold: defaults.SimilarityThreshold,
+		MaxEditDistance:     defaults.MaxEditDistance,
+	}
+}
+
+func normalizeOCRTimelineSettings(settings model.OCRTimelineSettings) model.OCRTimelineSettings {
+	defaults := defaultOCRTimelineSettings()
+	if settings.MinDurationMS <= 0 {
+		settings.MinDurationMS = defaults.MinDurationMS
+	}
+	if settings.MaxJoinGapMS <= 0 {
+		settings.MaxJoinGapMS = defaults.MaxJoinGapMS
+	}
+	if settings.MinTextLength < 0 {
+		settings.MinTextLength = defaults.MinTextLength
+	}
+	if settings.StableFrames <= 0 {
+		settings.StableFrames = defaults.StableFrames
+	}
+	if settings.ConfidenceFloor < 0 || settings.ConfidenceFloor > 1 {
+		settings.ConfidenceFloor = defaults.ConfidenceFloor
+	}
+	if settings.SimilarityThreshold <= 0 || settings.SimilarityThreshold > 1 {
+		settings.SimilarityThreshold = defaults.SimilarityThreshold
+	}
+	if settings.MaxEditDistance < 0 {
+		settings.MaxEditDistance = defaults.MaxEditDistance
+	}
+	return settings
+}
+
+func defaultASRFilterSettings() model.ASRFilterSettings {
+	defaults := asr.DefaultFilterOptions()
+	return model.ASRFilterSettings{
+		Enabled:         true,
+		MaxNoSpeechProb: defaults.MaxNoSpeechProb,
+		MinAvgLogprob:   defaults.MinAvgLogprob,
+		MaxRepeats:      defaults.MaxRepeats,
+		Blocklist:       defaultASRBlocklist,
+	}
+}
+
+func defaultFusionSettings() model.FusionSettings {
+	defaults := fusion.DefaultOptions()
+	return model.FusionSettings{
+		Enabled:       false,
+		MinSimilarity: defaults.MinSimilarity,
+		MaxShiftMS:    int(defaults.MaxShift / time.Millisecond),
+	}
+}
+
+func normalizeFusionSettings(settings model.FusionSettings) model.FusionSettings {
+	defaults := defaultFusionSettings()
+	if settings.MinSimilarity <= 0 || settings.MinSimilarity > 1 {
+		settings.MinSimilarity = defaults.MinSimilarity
+	}
+	if settings.MaxShiftMS <= 0 {
+		settings.MaxShiftMS = defaults.MaxShiftMS
+	}
+	return settings
+}
+
+func normalizeASRFilterSettings(settings model.ASRFilterSettings) model.ASRFilterSettings {
+	defaults := defaultASRFilterSettings()
+	if settings.MaxNoSpeechProb <= 0 || settings.MaxNoSpeechProb > 1 {
+		settings.MaxNoSpeechProb = defaults.MaxNoSpeechProb
+	}
+	if settings.MinAvgLogprob >= 0 {
+		settings.MinAvgLogprob = defaults.MinAvgLogprob
+	}
+	if settings.MaxRepeats <= 0 {
+		settings.MaxRepeats = defaults.MaxRepeats
+	}
+	settings.Blocklist = strings.TrimSpace(strings.ReplaceAll(settings.Blocklist, "\r\n", "\n"))
+	return settings
+}
+
+func normalizeQASettings(settings model.QASettings) model.QASettings {
+	defaults := defaultQASettings()
+	if settings.MaxCharsPerSecond <= 0 {
+		settings.MaxCharsPerSecond = defaults.MaxCharsPerSecond
+	}
+	if settings.MaxLineLength <= 0 {
+		settings.MaxLineLength = defaults.MaxLineLength
+	}
+	if settings.MaxLinesPerSide <= 0 {
+		settings.MaxLinesPerSide = defaults.MaxLinesPerSide
+	}
+	if settings.MinOCRConfidence < 0 || settings.MinOCRConfidence > 1 {
+		settings.MinOCRConfidence = defaults.MinOCRConfidence
+	}
+	return settings
+}
+
 type translationPromptMeta struct {
 	Style             string `json:"style,omitempty"`
 	CustomStylePrompt string `json:"custom_style_prompt,omitempty"`
@@ -229,6 +565,8 @@ type translationPromptMeta struct {
 
 const defaultTranslationPrompt = "???????????????????????????????????"
 
+const defaultASRBlocklist = "Thanks for watching\nThank you for watching\nPlease subscribe to my channel\nSubtitles by the Amara.org community\n字幕由Amara.org社区提供\n请不吝点赞 订阅 转发 打赏支持明镜与点点栏目\n明镜需要您的支持 欢迎订阅明镜\n谢谢观看\n感谢观看\nご視聴ありがとうございました\nチャンネル登録をお願いします\n시청해 주셔서 감사합니다"
+
 const translationPromptMetaMarker = "\n\n---4SUBS_META---\n"
 
 func encodeTranslationPrompt(settings model.AppSettings) (string, error) {
@@ -383,32 +721,47 @@ func (r *Repository) CreateJob(ctx context.Context, input CreateJobInput) (model
 	if err != nil {
 		return model.SubtitleJob{}, err
 	}
+	ocrRegionsJSON, err := encodeOCRRegions(input.OCRRegions)
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
+	input.Strategies, err = pipeline.NormalizeSourceStrategyKeys(input.Strategies)
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
+	strategiesJSON, err := json.Marshal(input.Strategies)
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
 	now := time.Now().UTC()
 	job := model.SubtitleJob{
-		ID:             fmt.Sprintf("job_%d", now.UnixNano()),
-		MediaAssetID:   input.MediaAssetID,
-		MediaPath:      input.MediaPath,
-		FileName:       input.FileName,
-		Status:         "queued",
-		CurrentStage:   "queued",
-		Progress:       0,
-		SourceLanguage: input.SourceLanguage,
-		TargetLanguage: input.TargetLanguage,
-		Provider:       input.Provider,
-		OutputFormats:  input.OutputFormats,
-		Details:        input.Details,
-		CreatedAt:      now,
-		UpdatedAt:      now,
+		ID:                  fmt.Sprintf("job_%d", now.UnixNano()),
+		MediaAssetID:        input.MediaAssetID,
+		MediaPath:           input.MediaPath,
+		FileName:            input.FileName,
+		Status:              "queued",
+		CurrentStage:        "queued",
+		Progress:            0,
+		SourceLanguage:      input.SourceLanguage,
+		TargetLanguage:      input.TargetLanguage,
+		Provider:            input.Provider,
+		OutputFormats:       input.OutputFormats,
+		SubtitleStreamIndex: input.StreamIndex,
+		OCRRegions:          input.OCRRegions,
+		SourceStrategies:    input.Strategies,
+		Details:             input.Details,
+		CreatedAt:           now,
+		UpdatedAt:           now,
 	}
 	_, err = r.db.ExecContext(ctx, `
 		INSERT INTO subtitle_jobs (
 			id, media_asset_id, media_path, file_name, status, current_stage, progress,
 			source_language, target_language, provider, output_formats_json,
 			source_subtitle_path, output_subtitle_path, output_srt_path, output_ass_path,
-			details, error_message, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, '', '', '', '', ?, '', ?, ?)`,
+			details, error_message, subtitle_stream_index, ocr_regions_json, source_strategies_json, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, '', '', '', '', ?, '', ?, ?, ?, ?, ?)`,
 		job.ID, nullableInt64(job.MediaAssetID), job.MediaPath, job.FileName, job.Status, job.CurrentStage, job.Progress,
-		job.SourceLanguage, job.TargetLanguage, job.Provider, string(outputFormatsJSON), job.Details,
+		job.SourceLanguage, job.TargetLanguage, job.Provider, string(outputFormatsJSON), job.Details, nullableInt(job.SubtitleStreamIndex), ocrRegionsJSON, string(strategiesJSON),
 		job.CreatedAt.Format(time.RFC3339), job.UpdatedAt.Format(time.RFC3339),
 	)
 	if err != nil {
@@ -422,6 +775,9 @@ func (r *Repository) GetJob(ctx context.Context, id string) (model.SubtitleJob,
 		job               model.SubtitleJob
 		outputFormatsJSON string
 		mediaAssetID      sql.NullInt64
+		streamIndex       sql.NullInt64
+		ocrRegionsJSON    string
+		strategiesJSON    string
 		createdAtRaw      string
 		updatedAtRaw      string
 	)
@@ -429,22 +785,30 @@ func (r *Repository) GetJob(ctx context.Context, id string) (model.SubtitleJob,
 		SELECT id, media_asset_id, media_path, file_name, status, current_stage, progress,
 		       source_language, target_language, provider, output_formats_json,
 		       source_subtitle_path, output_subtitle_path, output_srt_path, output_ass_path,
-		       details, error_message, created_at, updated_at
+		       details, error_message, subtitle_stream_index, ocr_regions_json, detected_language,
+		       source_strategies_json, source_strategy, created_at, updated_at
 		FROM subtitle_jobs WHERE id = ?`, id)
 	if err := row.Scan(
 		&job.ID, &mediaAssetID, &job.MediaPath, &job.FileName, &job.Status, &job.CurrentStage, &job.Progress,
 		&job.SourceLanguage, &job.TargetLanguage, &job.Provider, &outputFormatsJSON,
 		&job.SourceSubtitlePath, &job.OutputSubtitlePath, &job.OutputSRTPath, &job.OutputASSPath,
-		&job.Details, &job.ErrorMessage, &createdAtRaw, &updatedAtRaw,
+		&job.Details, &job.ErrorMessage, &streamIndex, &ocrRegionsJSON, &job.DetectedLanguage,
+		&strategiesJSON, &job.SourceStrategy, &createdAtRaw, &updatedAtRaw,
 	); err != nil {
 		return model.SubtitleJob{}, err
 	}
 	if mediaAssetID.Valid {
 		job.MediaAssetID = &mediaAssetID.Int64
 	}
+	if streamIndex.Valid {
+		value := int(streamIndex.Int64)
+		job.SubtitleStreamIndex = &value
+	}
 	if err := json.Unmarshal([]byte(outputFormatsJSON), &job.OutputFormats); err != nil {
 		return model.SubtitleJob{}, err
 	}
+	job.OCRRegions = decodeOCRRegions(ocrRegionsJSON)
+	_ = json.Unmarshal([]byte(strategiesJSON), &job.SourceStrategies)
 	job.CreatedAt = parseTime(createdAtRaw)
 	job.UpdatedAt = parseTime(updatedAtRaw)
 	return job, nil
@@ -458,7 +822,8 @@ func (r *Repository) ListJobs(ctx context.Context, limit int) ([]model.SubtitleJ
 		SELECT id, media_asset_id, media_path, file_name, status, current_stage, progress,
 		       source_language, target_language, provider, output_formats_json,
 		       source_subtitle_path, output_subtitle_path, output_srt_path, output_ass_path,
-		       details, error_message, created_at, updated_at
+		       details, error_message, subtitle_stream_index, ocr_regions_json, detected_language,
+		       source_strategies_json, source_strategy, created_at, updated_at
 		FROM subtitle_jobs
 		ORDER BY created_at DESC
 		LIMIT ?`, limit)
@@ -472,6 +837,9 @@ func (r *Repository) ListJobs(ctx context.Context, limit int) ([]model.SubtitleJ
 			job               model.SubtitleJob
 			outputFormatsJSON string
 			mediaAssetID      sql.NullInt64
+			streamIndex       sql.NullInt64
+			ocrRegionsJSON    string
+			strategiesJSON    string
 			createdAtRaw      string
 			updatedAtRaw      string
 		)
@@ -479,16 +847,23 @@ func (r *Repository) ListJobs(ctx context.Context, limit int) ([]model.SubtitleJ
 			&job.ID, &mediaAssetID, &job.MediaPath, &job.FileName, &job.Status, &job.CurrentStage, &job.Progress,
 			&job.SourceLanguage, &job.TargetLanguage, &job.Provider, &outputFormatsJSON,
 			&job.SourceSubtitlePath, &job.OutputSubtitlePath, &job.OutputSRTPath, &job.OutputASSPath,
-			&job.Details, &job.ErrorMessage, &createdAtRaw, &updatedAtRaw,
+			&job.Details, &job.ErrorMessage, &streamIndex, &ocrRegionsJSON, &job.DetectedLanguage,
+			&strategiesJSON, &job.SourceStrategy, &createdAtRaw, &updatedAtRaw,
 		); err != nil {
 			return nil, err
 		}
 		if mediaAssetID.Valid {
 			job.MediaAssetID = &mediaAssetID.Int64
 		}
+		if streamIndex.Valid {
+			value := int(streamIndex.Int64)
+			job.SubtitleStreamIndex = &value
+		}
 		if err := json.Unmarshal([]byte(outputFormatsJSON), &job.OutputFormats); err != nil {
 			return nil, err
 		}
+		job.OCRRegions = decodeOCRRegions(ocrRegionsJSON)
+		_ = json.Unmarshal([]byte(strategiesJSON), &job.SourceStrategies)
 		job.CreatedAt = parseTime(createdAtRaw)
 		job.UpdatedAt = parseTime(updatedAtRaw)
 		jobs = append(jobs, job)
@@ -540,6 +915,131 @@ func (r *Repository) UpdateJobProgress(ctx context.Context, id string, status st
 	return err
 }
 
+func (r *Repository) UpdateJobSourceStrategies(ctx context.Context, id string, strategies []string) error {
+	strategies, err := pipeline.NormalizeSourceStrategyKeys(strategies)
+	if err != nil {
+		return err
+	}
+	strategiesJSON, err := json.Marshal(strategies)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE subtitle_jobs SET source_strategies_json = ?, source_strategy = '', updated_at = ? WHERE id = ?`,
+		string(strategiesJSON), time.Now().UTC().Format(time.RFC3339), id,
+	)
+	return err
+}
+
+func (r *Repository) UpdateJobSourceStrategy(ctx context.Context, id string, strategy string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE subtitle_jobs SET source_strategy = ?, updated_at = ? WHERE id = ?`,
+		strategy, time.Now().UTC().Format(time.RFC3339), id,
+	)
+	return err
+}
+
+func (r *Repository) UpdateJobDetectedLanguage(ctx context.Context, id string, language string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE subtitle_jobs SET detected_language = ?, updated_at = ? WHERE id = ?`,
+		strings.TrimSpace(language), time.Now().UTC().Format(time.RFC3339), id,
+	)
+	return err
+}
+
+func (r *Repository) GetMediaOCRRegions(ctx context.Context, mediaPath string) (model.MediaOCRRegions, bool, error) {
+	var (
+		record       model.MediaOCRRegions
+		regionsJSON  string
+		updatedAtRaw string
+	)
+	row := r.db.QueryRowContext(ctx, `
+		SELECT file_path, regions_json, source, updated_at
+		FROM media_ocr_regions WHERE file_path = ?`, mediaPath)
+	if err := row.Scan(&record.MediaPath, &regionsJSON, &record.Source, &updatedAtRaw); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.MediaOCRRegions{}, false, nil
+		}
+		return model.MediaOCRRegions{}, false, err
+	}
+	record.Regions = decodeOCRRegions(regionsJSON)
+	record.UpdatedAt = parseTime(updatedAtRaw)
+	return record, len(record.Regions) > 0, nil
+}
+
+func (r *Repository) SaveMediaOCRRegions(ctx context.Context, mediaPath string, regions []model.OCRRegion, source string) (model.MediaOCRRegions, error) {
+	if strings.TrimSpace(mediaPath) == "" {
+		return model.MediaOCRRegions{}, errors.New("媒体路径不能为空")
+	}
+	regionsJSON, err := encodeOCRRegions(regions)
+	if err != nil {
+		return model.MediaOCRRegions{}, err
+	}
+	now := time.Now().UTC()
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO media_ocr_regions (file_path, regions_json, source, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(file_path) DO UPDATE SET
+			regions_json = excluded.regions_json,
+			source = excluded.source,
+			updated_at = excluded.updated_at`,
+		mediaPath, regionsJSON, source, now.Format(time.RFC3339),
+	); err != nil {
+		return model.MediaOCRRegions{}, err
+	}
+	return model.MediaOCRRegions{MediaPath: mediaPath, Regions: regions, Source: source, UpdatedAt: now}, nil
+}
+
+func (r *Repository) GetOCRCache(ctx context.Context, imageHash string, provider string, modelName string) (string, float64, bool, error) {
+	var text string
+	var confidence float64
+	row := r.db.QueryRowContext(ctx, `
+		SELECT text, confidence FROM ocr_cache
+		WHERE image_hash = ? AND provider = ? AND model = ?`, imageHash, provider, modelName)
+	if err := row.Scan(&text, &confidence); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, false, nil
+		}
+		return "", 0, false, err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ocr_cache SET hit_count = hit_count + 1, last_used_at = ?
+		WHERE image_hash = ? AND provider = ? AND model = ?`,
+		time.Now().UTC().Format(time.RFC3339), imageHash, provider, modelName)
+	return text, confidence, true, err
+}
+
+func (r *Repository) PutOCRCache(ctx context.Context, imageHash string, provider string, modelName string, text string, confidence float64) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO ocr_cache (image_hash, provider, model, text, confidence, hit_count, created_at, last_used_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?)
+		ON CONFLICT(image_hash, provider, model) DO UPDATE SET
+			text = excluded.text,
+			confidence = excluded.confidence,
+			last_used_at = excluded.last_used_at`,
+		imageHash, provider, modelName, text, confidence, now, now,
+	)
+	return err
+}
+
+func (r *Repository) GetOCRCacheStats(ctx context.Context) (model.OCRCacheStats, error) {
+	var stats model.OCRCacheStats
+	row := r.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(hit_count), 0) FROM ocr_cache`)
+	if err := row.Scan(&stats.Entries, &stats.Hits); err != nil {
+		return model.OCRCacheStats{}, err
+	}
+	return stats, nil
+}
+
+func (r *Repository) ClearOCRCache(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM ocr_cache`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *Repository) CountMediaAssets(ctx context.Context) (int, error) {
 	return r.countByQuery(ctx, `SELECT COUNT(*) FROM media_assets`)
 }
@@ -556,6 +1056,28 @@ func (r *Repository) countByQuery(ctx context.Context, query string) (int, error
 	return count, nil
 }
 
+func encodeOCRRegions(regions []model.OCRRegion) (string, error) {
+	if len(regions) == 0 {
+		return "", nil
+	}
+	raw, err := json.Marshal(regions)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func decodeOCRRegions(raw string) []model.OCRRegion {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var regions []model.OCRRegion
+	if err := json.Unmarshal([]byte(raw), &regions); err != nil {
+		return nil
+	}
+	return regions
+}
+
 func nullableInt64(value *int64) any {
 	if value == nil {
 		return nil
@@ -563,6 +1085,13 @@ func nullableInt64(value *int64) any {
 	return *value
 }
 
+func nullableInt(value *int) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
 func parseTime(raw string) time.Time {
 	parsed, err := time.Parse(time.RFC3339, raw)
 	if err != nil {
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateAppliesAllSteps(t *testing.T) {
+	database, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	steps, err := pendingMigrations(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var version int
+	if err := database.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if want := steps[len(steps)-1].version; version != want {
+		t.Fatalf("user_version = %d, want %d", version, want)
+	}
+}
+
+func TestApplyMigrationRollsBackOnFailure(t *testing.T) {
+	database, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	var before int
+	if err := database.QueryRow(`PRAGMA user_version`).Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	statements := "ALTER TABLE app_settings ADD COLUMN half_applied TEXT NOT NULL DEFAULT '';\nALTER TABLE missing_table ADD COLUMN broken TEXT;"
+	if err := applyMigration(database, statements, before+1); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	var after int
+	if err := database.QueryRow(`PRAGMA user_version`).Scan(&after); err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Fatalf("user_version moved from %d to %d after a failed migration", before, after)
+	}
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('app_settings') WHERE name = 'half_applied'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("partially applied column was not rolled back")
+	}
+	if err := applyMigration(database, "ALTER TABLE app_settings ADD COLUMN half_applied TEXT NOT NULL DEFAULT '';", before+1); err != nil {
+		t.Fatalf("retrying the fixed migration failed: %v", err)
+	}
+}
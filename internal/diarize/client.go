@@ -0,0 +1,169 @@
+package diarize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+type Turn struct {
+	Start   time.Duration
+	End     time.Duration
+	Speaker string
+}
+
+type Client struct {
+	URL         string
+	APIKey      string
+	MaxSpeakers int
+	HTTPClient  *http.Client
+}
+
+type turnResponse struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Speaker any     `json:"speaker"`
+}
+
+type diarizeResponse struct {
+	Segments []turnResponse `json:"segments"`
+}
+
+func (c Client) Name() string {
+	return "http-diarization"
+}
+
+func (c Client) Ready() bool {
+	return strings.TrimSpace(c.URL) != ""
+}
+
+func (c Client) Diarize(ctx context.Context, audioPath string) ([]Turn, error) {
+	if !c.Ready() {
+		return nil, errors.New("说话人分离服务尚未配置，请先填写 DIARIZATION_URL")
+	}
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+	if c.MaxSpeakers > 0 {
+		_ = writer.WriteField("max_speakers", fmt.Sprint(c.MaxSpeakers))
+	}
+	if _, err := writer.CreateFormFile("file", filepath.Base(audioPath)); err != nil {
+		return nil, err
+	}
+	prefix := append([]byte(nil), buffer.Bytes()...)
+	buffer.Reset()
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	body := io.MultiReader(bytes.NewReader(prefix), file, bytes.NewReader(buffer.Bytes()))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSpace(c.URL), body)
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = int64(len(prefix)) + stat.Size() + int64(buffer.Len())
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	if key := strings.TrimSpace(c.APIKey); key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Minute}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	raw, err := io.ReadAll(io.LimitReader(response.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("说话人分离请求失败: HTTP %d: %s", response.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return ParseTurns(raw)
+}
+
+func ParseTurns(raw []byte) ([]Turn, error) {
+	var items []turnResponse
+	trimmed := bytes.TrimSpace(raw)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("说话人分离响应解析失败: %w", err)
+		}
+	} else {
+		var payload diarizeResponse
+		if err := json.Unmarshal(trimmed, &payload); err != nil {
+			return nil, fmt.Errorf("说话人分离响应解析失败: %w", err)
+		}
+		items = payload.Segments
+	}
+	turns := make([]Turn, 0, len(items))
+	for _, item := range items {
+		speaker := strings.TrimSpace(fmt.Sprint(item.Speaker))
+		if item.Speaker == nil || speaker == "" || item.End <= item.Start {
+			continue
+		}
+		turns = append(turns, Turn{
+			Start:   time.Duration(item.Start * float64(time.Second)),
+			End:     time.Duration(item.End * float64(time.Second)),
+			Speaker: speaker,
+		})
+	}
+	sort.SliceStable(turns, func(i, j int) bool {
+		return turns[i].Start < turns[j].Start
+	})
+	return turns, nil
+}
+
+func Assign(blocks []subtitle.Block, turns []Turn) ([]subtitle.Block, int) {
+	assigned := make([]subtitle.Block, len(blocks))
+	count := 0
+	first := 0
+	for index, block := range blocks {
+		block.Lines = append([]string{}, block.Lines...)
+		block.Speaker = ""
+		for first < len(turns) && turns[first].End <= block.Start {
+			first++
+		}
+		overlaps := map[string]time.Duration{}
+		best := ""
+		for position := first; position < len(turns) && turns[position].Start < block.End; position++ {
+			turn := turns[position]
+			start, end := max(turn.Start, block.Start), min(turn.End, block.End)
+			if end <= start {
+				continue
+			}
+			overlaps[turn.Speaker] += end - start
+			if best == "" || overlaps[turn.Speaker] > overlaps[best] {
+				best = turn.Speaker
+			}
+		}
+		if best != "" {
+			block.Speaker = best
+			count++
+		}
+		assigned[index] = block
+	}
+	return assigned, count
+}
@@ -0,0 +1,152 @@
+package diarize
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+func TestParseTurns(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []Turn
+		wantErr bool
+	}{
+		{
+			name: "segments object",
+			raw:  `{"segments":[{"start":2.5,"end":4,"speaker":"SPEAKER_01"},{"start":0,"end":2,"speaker":"SPEAKER_00"}]}`,
+			want: []Turn{{0, 2 * time.Second, "SPEAKER_00"}, {2500 * time.Millisecond, 4 * time.Second, "SPEAKER_01"}},
+		},
+		{
+			name: "bare array with numeric speakers",
+			raw:  `[{"start":1,"end":3,"speaker":1}]`,
+			want: []Turn{{time.Second, 3 * time.Second, "1"}},
+		},
+		{
+			name: "empty speakers and ranges are dropped",
+			raw:  `[{"start":1,"end":1,"speaker":"A"},{"start":2,"end":3},{"start":3,"end":4,"speaker":" "},{"start":5,"end":6,"speaker":"B"}]`,
+			want: []Turn{{5 * time.Second, 6 * time.Second, "B"}},
+		},
+		{
+			name:    "invalid json",
+			raw:     `{"segments":`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			turns, err := ParseTurns([]byte(test.raw))
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(turns) != len(test.want) {
+				t.Fatalf("got %+v, want %+v", turns, test.want)
+			}
+			for index := range turns {
+				if turns[index] != test.want[index] {
+					t.Fatalf("got %+v, want %+v", turns, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	turns := []Turn{
+		{Start: 0, End: 2 * time.Second, Speaker: "A"},
+		{Start: 2 * time.Second, End: 5 * time.Second, Speaker: "B"},
+		{Start: 8 * time.Second, End: 9 * time.Second, Speaker: "A"},
+	}
+	blocks := []subtitle.Block{
+		{Index: 1, Start: 500 * time.Millisecond, End: 1500 * time.Millisecond, Lines: []string{"one"}},
+		{Index: 2, Start: 1500 * time.Millisecond, End: 4 * time.Second, Lines: []string{"two"}},
+		{Index: 3, Start: 6 * time.Second, End: 7 * time.Second, Lines: []string{"three"}, Speaker: "stale"},
+		{Index: 4, Start: 8500 * time.Millisecond, End: 10 * time.Second, Lines: []string{"four"}},
+	}
+	assigned, count := Assign(blocks, turns)
+	want := []string{"A", "B", "", "A"}
+	if count != 3 {
+		t.Fatalf("assigned %d blocks, want 3", count)
+	}
+	for index, block := range assigned {
+		if block.Speaker != want[index] {
+			t.Fatalf("block %d speaker %q, want %q", index, block.Speaker, want[index])
+		}
+	}
+	if blocks[2].Speaker != "stale" {
+		t.Fatal("input blocks were modified")
+	}
+}
+
+func TestDiarize(t *testing.T) {
+	audio := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(audio, []byte("RIFFdata"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		turns   int
+		wantErr string
+	}{
+		{name: "success", status: http.StatusOK, body: `{"segments":[{"start":0,"end":1,"speaker":"A"}]}`, turns: 1},
+		{name: "http error", status: http.StatusBadGateway, body: "upstream down", wantErr: "HTTP 502"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				if request.Header.Get("Authorization") != "Bearer secret" {
+					t.Errorf("missing authorization header")
+				}
+				if value := request.FormValue("max_speakers"); value != "3" {
+					t.Errorf("max_speakers = %q", value)
+				}
+				file, _, err := request.FormFile("file")
+				if err != nil {
+					t.Errorf("missing file: %v", err)
+				} else {
+					content, _ := io.ReadAll(file)
+					if string(content) != "RIFFdata" {
+						t.Errorf("file content %q", content)
+					}
+				}
+				writer.WriteHeader(test.status)
+				_, _ = writer.Write([]byte(test.body))
+			}))
+			defer server.Close()
+			client := Client{URL: server.URL, APIKey: "secret", MaxSpeakers: 3}
+			turns, err := client.Diarize(context.Background(), audio)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(turns) != test.turns {
+				t.Fatalf("got %d turns, want %d", len(turns), test.turns)
+			}
+		})
+	}
+	if _, err := (Client{}).Diarize(context.Background(), audio); err == nil {
+		t.Fatal("expected error for unconfigured client")
+	}
+}
@@ -0,0 +1,249 @@
+package fusion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+type Options struct {
+	MinSimilarity float64
+	MaxShift      time.Duration
+	MinDuration   time.Duration
+}
+
+type Flag struct {
+	Index      int
+	Start      time.Duration
+	End        time.Duration
+	OCRText    string
+	ASRText    string
+	Similarity float64
+	Reason     string
+}
+
+type Stats struct {
+	Cues         int
+	Matched      int
+	Retimed      int
+	Flagged      int
+	UncoveredASR int
+	MeanShift    time.Duration
+}
+
+type Result struct {
+	Blocks    []subtitle.Block
+	Flags     []Flag
+	Uncovered []subtitle.Block
+	Stats     Stats
+}
+
+type timedChar struct {
+	char  rune
+	start time.Duration
+	end   time.Duration
+	block int
+}
+
+func DefaultOptions() Options {
+	return Options{MinSimilarity: 0.6, MaxShift: 1500 * time.Millisecond, MinDuration: 700 * time.Millisecond}
+}
+
+func (o Options) Normalize() Options {
+	defaults := DefaultOptions()
+	if o.MinSimilarity <= 0 || o.MinSimilarity > 1 {
+		o.MinSimilarity = defaults.MinSimilarity
+	}
+	if o.MaxShift <= 0 {
+		o.MaxShift = defaults.MaxShift
+	}
+	if o.MinDuration <= 0 {
+		o.MinDuration = defaults.MinDuration
+	}
+	return o
+}
+
+func (f Flag) Describe() string {
+	if f.ASRText == "" {
+		return f.Reason
+	}
+	return fmt.Sprintf("%s：ASR 听到「%s」（相似度 %.0f%%）", f.Reason, f.ASRText, f.Similarity*100)
+}
+
+func Align(ocr []subtitle.Block, asr []subtitle.Block, options Options) Result {
+	options = options.Normalize()
+	chars := timedChars(asr)
+	covered := make([]bool, len(asr))
+	result := Result{Blocks: make([]subtitle.Block, len(ocr)), Stats: Stats{Cues: len(ocr)}}
+	cursor := 0
+	var shiftTotal time.Duration
+	for position, block := range ocr {
+		block.Lines = append([]string{}, block.Lines...)
+		result.Blocks[position] = block
+		text := flatText(block.Lines)
+		pattern := normalizedRunes(text)
+		if len(pattern) == 0 {
+			continue
+		}
+		flag := Flag{Index: position + 1, Start: block.Start, End: block.End, OCRText: text}
+		low := cursor + sort.Search(len(chars)-cursor, func(index int) bool {
+			return chars[cursor+index].end > block.Start-options.MaxShift
+		})
+		high := low + sort.Search(len(chars)-low, func(index int) bool {
+			return chars[low+index].start >= block.End+options.MaxShift
+		})
+		if low >= high {
+			flag.Reason = "附近没有 ASR 语音，可能是画面文字或 OCR 误识别"
+			result.Flags = append(result.Flags, flag)
+			continue
+		}
+		window := chars[low:high]
+		from, to, distance := fitAlignment(pattern, window, block.End)
+		flag.Similarity = 1 - float64(distance)/float64(max(len(pattern), to-from))
+		if flag.Similarity < options.MinSimilarity {
+			flag.Reason = "OCR 与 ASR 差异较大"
+			flag.ASRText = overlapText(asr, block.Start, block.End)
+			if flag.ASRText == "" {
+				flag.Reason = "该时段没有对应的 ASR 语音，可能是画面文字或 OCR 误识别"
+			}
+			result.Flags = append(result.Flags, flag)
+			continue
+		}
+		result.Stats.Matched++
+		for _, char := range window[from:to] {
+			covered[char.block] = true
+		}
+		cursor = low + to
+		start, end := window[from].start, window[to-1].end
+		if end-start < options.MinDuration {
+			end = start + options.MinDuration
+		}
+		if start != block.Start || end != block.End {
+			result.Stats.Retimed++
+			shiftTotal += absDuration(start - block.Start)
+			block.Start, block.End = start, end
+			result.Blocks[position] = block
+		}
+	}
+	for index := range result.Blocks {
+		if following := subtitle.NextInRegion(result.Blocks, index); following >= 0 {
+			next := result.Blocks[following].Start
+			if result.Blocks[index].End > next && next > result.Blocks[index].Start {
+				result.Blocks[index].End = next
+			}
+		}
+	}
+	for index, block := range asr {
+		if !covered[index] && len(normalizedRunes(flatText(block.Lines))) >= 4 {
+			result.Uncovered = append(result.Uncovered, block)
+		}
+	}
+	result.Stats.Flagged = len(result.Flags)
+	result.Stats.UncoveredASR = len(result.Uncovered)
+	if result.Stats.Retimed > 0 {
+		result.Stats.MeanShift = shiftTotal / time.Duration(result.Stats.Retimed)
+	}
+	return result
+}
+
+func fitAlignment(pattern []rune, window []timedChar, anchor time.Duration) (int, int, int) {
+	rows, columns := len(pattern)+1, len(window)+1
+	cost := make([]int, rows*columns)
+	for row := 1; row < rows; row++ {
+		cost[row*columns] = row
+	}
+	for row := 1; row < rows; row++ {
+		for column := 1; column < columns; column++ {
+			substitution := 1
+			if pattern[row-1] == window[column-1].char {
+				substitution = 0
+			}
+			cost[row*columns+column] = min(
+				cost[(row-1)*columns+column-1]+substitution,
+				cost[(row-1)*columns+column]+1,
+				cost[row*columns+column-1]+1,
+			)
+		}
+	}
+	last := (rows - 1) * columns
+	end := 1
+	for column := 2; column < columns; column++ {
+		current, best := cost[last+column], cost[last+end]
+		if current < best || (current == best && absDuration(window[column-1].end-anchor) < absDuration(window[end-1].end-anchor)) {
+			end = column
+		}
+	}
+	row, column := rows-1, end
+	for row > 0 && column > 0 {
+		value := cost[row*columns+column]
+		substitution := 1
+		if pattern[row-1] == window[column-1].char {
+			substitution = 0
+		}
+		switch {
+		case value == cost[(row-1)*columns+column-1]+substitution:
+			row--
+			column--
+		case value == cost[(row-1)*columns+column]+1:
+			row--
+		default:
+			column--
+		}
+	}
+	if column >= end {
+		column = end - 1
+	}
+	return column, end, cost[last+end]
+}
+
+func timedChars(blocks []subtitle.Block) []timedChar {
+	chars := make([]timedChar, 0, len(blocks)*24)
+	for index, block := range blocks {
+		runes := normalizedRunes(flatText(block.Lines))
+		span := block.End - block.Start
+		for position, char := range runes {
+			chars = append(chars, timedChar{
+				char:  char,
+				start: block.Start + span*time.Duration(position)/time.Duration(len(runes)),
+				end:   block.Start + span*time.Duration(position+1)/time.Duration(len(runes)),
+				block: index,
+			})
+		}
+	}
+	return chars
+}
+
+func overlapText(blocks []subtitle.Block, start time.Duration, end time.Duration) string {
+	parts := make([]string, 0, 2)
+	for _, block := range blocks {
+		if block.End > start && block.Start < end {
+			parts = append(parts, flatText(block.Lines))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func flatText(lines []string) string {
+	return strings.TrimSpace(strings.Join(lines, " "))
+}
+
+func normalizedRunes(text string) []rune {
+	runes := make([]rune, 0, len(text))
+	for _, char := range text {
+		if unicode.IsLetter(char) || unicode.IsNumber(char) {
+			runes = append(runes, unicode.ToLower(char))
+		}
+	}
+	return runes
+}
+
+func absDuration(value time.Duration) time.Duration {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
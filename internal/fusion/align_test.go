@@ -0,0 +1,133 @@
+package fusion
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+func TestAlign(t *testing.T) {
+	ms := func(value int) time.Duration {
+		return time.Duration(value) * time.Millisecond
+	}
+	block := func(start int, end int, text string) subtitle.Block {
+		return subtitle.Block{Start: ms(start), End: ms(end), Lines: []string{text}}
+	}
+	tests := []struct {
+		name      string
+		ocr       []subtitle.Block
+		asr       []subtitle.Block
+		want      [][2]time.Duration
+		reasons   []string
+		asrText   []string
+		uncovered int
+		retimed   int
+	}{
+		{
+			name:    "ocr text keeps asr timing",
+			ocr:     []subtitle.Block{block(1000, 3000, "Hello, world!")},
+			asr:     []subtitle.Block{block(1300, 2500, "hello world")},
+			want:    [][2]time.Duration{{ms(1300), ms(2500)}},
+			retimed: 1,
+		},
+		{
+			name:    "one misread character still aligns",
+			ocr:     []subtitle.Block{block(1000, 3000, "He1lo world")},
+			asr:     []subtitle.Block{block(1300, 2500, "hello world")},
+			want:    [][2]time.Duration{{ms(1300), ms(2500)}},
+			retimed: 1,
+		},
+		{
+			name:    "each cue takes its own part of a long asr segment",
+			ocr:     []subtitle.Block{block(900, 2200, "good morning"), block(2300, 3800, "see you later")},
+			asr:     []subtitle.Block{block(1000, 3300, "good morning see you later")},
+			want:    [][2]time.Duration{{ms(1000), ms(2150)}, {ms(2150), ms(3300)}},
+			retimed: 2,
+		},
+		{
+			name:    "short matches keep the minimum duration",
+			ocr:     []subtitle.Block{block(1000, 2000, "ok")},
+			asr:     []subtitle.Block{block(1200, 1400, "ok")},
+			want:    [][2]time.Duration{{ms(1200), ms(1900)}},
+			retimed: 1,
+		},
+		{
+			name:      "strong disagreement is flagged and keeps ocr timing",
+			ocr:       []subtitle.Block{block(1000, 3000, "The quick brown fox")},
+			asr:       []subtitle.Block{block(1200, 2800, "nothing alike here")},
+			want:      [][2]time.Duration{{ms(1000), ms(3000)}},
+			reasons:   []string{"OCR 与 ASR 差异较大"},
+			asrText:   []string{"nothing alike here"},
+			uncovered: 1,
+		},
+		{
+			name:      "cue without nearby speech is flagged",
+			ocr:       []subtitle.Block{block(10000, 12000, "Chapter One")},
+			asr:       []subtitle.Block{block(1000, 2000, "hello there")},
+			want:      [][2]time.Duration{{ms(10000), ms(12000)}},
+			reasons:   []string{"附近没有 ASR 语音，可能是画面文字或 OCR 误识别"},
+			uncovered: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Align(test.ocr, test.asr, DefaultOptions())
+			for index, block := range result.Blocks {
+				if block.Start != test.want[index][0] || block.End != test.want[index][1] {
+					t.Fatalf("block %d is %s-%s, want %s-%s", index, block.Start, block.End, test.want[index][0], test.want[index][1])
+				}
+				if strings.Join(block.Lines, "\n") != strings.Join(test.ocr[index].Lines, "\n") {
+					t.Fatalf("block %d text changed to %q", index, block.Lines)
+				}
+			}
+			if len(result.Flags) != len(test.reasons) {
+				t.Fatalf("got flags %+v, want reasons %v", result.Flags, test.reasons)
+			}
+			for index, flag := range result.Flags {
+				if flag.Reason != test.reasons[index] {
+					t.Fatalf("flag %d reason %q, want %q", index, flag.Reason, test.reasons[index])
+				}
+				if test.asrText != nil && flag.ASRText != test.asrText[index] {
+					t.Fatalf("flag %d asr text %q, want %q", index, flag.ASRText, test.asrText[index])
+				}
+			}
+			if result.Stats.UncoveredASR != test.uncovered || result.Stats.Retimed != test.retimed || result.Stats.Flagged != len(test.reasons) {
+				t.Fatalf("unexpected stats %+v", result.Stats)
+			}
+		})
+	}
+}
+
+func TestFitAlignment(t *testing.T) {
+	window := func(text string) []timedChar {
+		chars := make([]timedChar, 0, len(text))
+		for index, char := range text {
+			chars = append(chars, timedChar{char: char, start: time.Duration(index) * 100 * time.Millisecond, end: time.Duration(index+1) * 100 * time.Millisecond})
+		}
+		return chars
+	}
+	tests := []struct {
+		name     string
+		pattern  string
+		window   string
+		anchor   time.Duration
+		from     int
+		to       int
+		distance int
+	}{
+		{name: "exact substring", pattern: "hello", window: "xxhelloyy", anchor: 700 * time.Millisecond, from: 2, to: 7},
+		{name: "substitution", pattern: "hallo", window: "xxhelloyy", anchor: 700 * time.Millisecond, from: 2, to: 7, distance: 1},
+		{name: "repeated phrase picks the one nearest the anchor", pattern: "hi", window: "hiabcdefhi", anchor: time.Second, from: 8, to: 10},
+		{name: "repeated phrase near the start", pattern: "hi", window: "hiabcdefhi", anchor: 200 * time.Millisecond, from: 0, to: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to, distance := fitAlignment([]rune(test.pattern), window(test.window), test.anchor)
+			if from != test.from || to != test.to || distance != test.distance {
+				t.Fatalf("got %d-%d distance %d, want %d-%d distance %d", from, to, distance, test.from, test.to, test.distance)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package jobdata
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+type Record struct {
+	Blocks        []subtitle.Block        `json:"blocks,omitempty"`
+	Translations  []string                `json:"translations,omitempty"`
+	ParseWarnings []subtitle.ParseWarning `json:"parse_warnings"`
+	OCRConfidence []float64               `json:"ocr_confidence,omitempty"`
+	FusionFlags   []string                `json:"fusion_flags,omitempty"`
+	Speakers      map[string]string       `json:"speakers,omitempty"`
+	ReviewDrift   string                  `json:"review_drift,omitempty"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+}
+
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func New(baseDir string) *Store {
+	return &Store{dir: filepath.Join(baseDir, "job-data")}
+}
+
+func (s *Store) Load(jobID string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(jobID)
+}
+
+func (s *Store) Update(jobID string, apply func(record *Record)) error {
+	jobID = strings.TrimSpace(jobID)
+	if jobID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.load(jobID)
+	if err != nil {
+		return err
+	}
+	apply(&record)
+	record.UpdatedAt = time.Now().UTC()
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	tempPath := s.filePath(jobID) + ".tmp"
+	if err := os.WriteFile(tempPath, payload, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, s.filePath(jobID))
+}
+
+func (s *Store) load(jobID string) (Record, error) {
+	jobID = strings.TrimSpace(jobID)
+	record := Record{ParseWarnings: []subtitle.ParseWarning{}}
+	if jobID == "" {
+		return record, nil
+	}
+	raw, err := os.ReadFile(s.filePath(jobID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return record, nil
+		}
+		return Record{}, err
+	}
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return Record{}, err
+	}
+	if record.ParseWarnings == nil {
+		record.ParseWarnings = []subtitle.ParseWarning{}
+	}
+	return record, nil
+}
+
+func (s *Store) filePath(jobID string) string {
+	return filepath.Join(s.dir, jobID+".json")
+}
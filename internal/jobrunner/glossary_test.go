@@ -0,0 +1,20 @@
+package jobrunner
+
+import "testing"
+
+func TestGlossaryForLanguage(t *testing.T) {
+	glossary := "[ja] 先生 => 老师\n[en] Doctor => 医生\n[Dr.] Smith => 史密斯医生\n[BGM] => 背景音乐\nTokyo => 东京"
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{language: "ja", want: "先生 => 老师\n[Dr.] Smith => 史密斯医生\n[BGM] => 背景音乐\nTokyo => 东京"},
+		{language: "eng", want: "Doctor => 医生\n[Dr.] Smith => 史密斯医生\n[BGM] => 背景音乐\nTokyo => 东京"},
+		{language: "", want: "先生 => 老师\nDoctor => 医生\n[Dr.] Smith => 史密斯医生\n[BGM] => 背景音乐\nTokyo => 东京"},
+	}
+	for _, test := range tests {
+		if got := glossaryForLanguage(glossary, test.language); got != test.want {
+			t.Errorf("language %q:\n got %q\nwant %q", test.language, got, test.want)
+		}
+	}
+}
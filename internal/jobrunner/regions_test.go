@@ -0,0 +1,33 @@
+package jobrunner
+
+import (
+	"testing"
+
+	"github.com/gayhub/4subs/internal/model"
+	"github.com/gayhub/4subs/internal/subtitle"
+)
+
+func TestCropRegionsKeepPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []model.OCRRegion
+		want    []string
+	}{
+		{name: "single top band", regions: []model.OCRRegion{{TopPercent: 2, HeightPercent: 15}}, want: []string{subtitle.RegionTop}},
+		{name: "single bottom band", regions: []model.OCRRegion{{TopPercent: 72, HeightPercent: 22}}, want: []string{""}},
+		{name: "top and bottom", regions: []model.OCRRegion{{TopPercent: 5, HeightPercent: 12}, {TopPercent: 75, HeightPercent: 20}}, want: []string{subtitle.RegionTop, ""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			crops := cropRegions(test.regions)
+			for index, crop := range crops {
+				if got := blockRegion(crop); got != test.want[index] {
+					t.Fatalf("region %d = %q, want %q", index, got, test.want[index])
+				}
+				if crop.Name == "" {
+					t.Fatalf("region %d has no name", index)
+				}
+			}
+		})
+	}
+}
@@ -5,41 +5,120 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gayhub/4subs/internal/asr"
 	"github.com/gayhub/4subs/internal/asr/openai"
+	"github.com/gayhub/4subs/internal/audiosync"
 	"github.com/gayhub/4subs/internal/config"
 	"github.com/gayhub/4subs/internal/db"
+	"github.com/gayhub/4subs/internal/diarize"
+	"github.com/gayhub/4subs/internal/fusion"
+	"github.com/gayhub/4subs/internal/jobdata"
 	"github.com/gayhub/4subs/internal/joblog"
 	"github.com/gayhub/4subs/internal/media"
 	"github.com/gayhub/4subs/internal/model"
 	ocrprovider "github.com/gayhub/4subs/internal/ocr"
+	"github.com/gayhub/4subs/internal/pipeline"
 	"github.com/gayhub/4subs/internal/subtitle"
 	"github.com/gayhub/4subs/internal/translator/deepseek"
 )
 
+const (
+	maxLoggedParseWarnings = 50
+	maxLoggedLowConfidence = 50
+	maxLoggedFusionFlags   = 50
+	minLanguageConfidence  = 0.5
+	syncSampleRate         = 8000
+	syncMinCorrection      = 40 * time.Millisecond
+	syncMinDrift           = 0.0002
+)
+
+type sourceResult struct {
+	blocks   []subtitle.Block
+	path     string
+	language string
+	strategy string
+}
+
+type sourceSkipError struct {
+	reason string
+}
+
+func (e sourceSkipError) Error() string {
+	return e.reason
+}
+
+type streamProbe struct {
+	done    bool
+	streams []media.Stream
+	details string
+	err     error
+}
+
+func (p *streamProbe) subtitles(ctx context.Context, ffprobeBin string, mediaPath string) ([]media.Stream, string, error) {
+	if p.done {
+		return p.streams, p.details, p.err
+	}
+	p.done = true
+	streams, err := media.ProbeStreams(ctx, ffprobeBin, mediaPath)
+	if err != nil {
+		p.err = err
+		return nil, "", err
+	}
+	p.streams = media.SubtitleStreams(streams)
+	details := make([]string, 0, len(p.streams))
+	for _, stream := range p.streams {
+		details = append(details, stream.Describe())
+	}
+	p.details = strings.Join(details, "\n")
+	return p.streams, p.details, nil
+}
+
 type Runner struct {
 	cfg        config.Config
 	repo       *db.Repository
 	translator deepseek.Client
 	asr        openai.Client
 	ocr        ocrprovider.Provider
+	ocrPool    *ocrprovider.Pool
+	diarizer   diarize.Client
 	logger     *joblog.Store
+	data       *jobdata.Store
 	queue      chan string
 	active     sync.Map
 	cancels    sync.Map
 }
 
-func New(cfg config.Config, repo *db.Repository, translator deepseek.Client, asrClient openai.Client, ocrClient ocrprovider.Provider, logger *joblog.Store) *Runner {
+func New(cfg config.Config, repo *db.Repository, translator deepseek.Client, asrClient openai.Client, ocrClient ocrprovider.Provider, logger *joblog.Store, data *jobdata.Store) *Runner {
+	ocrPool := ocrprovider.NewPool(ocrprovider.PoolOptions{
+		Workers:           cfg.OCRConcurrency,
+		RequestsPerSecond: cfg.OCRRequestsPerSecond,
+		MaxRetries:        cfg.OCRMaxRetries,
+		BatchSize:         cfg.OCRBatchSize,
+	})
+	diarizer := diarize.Client{
+		URL:         cfg.DiarizeURL,
+		APIKey:      cfg.DiarizeAPIKey,
+		MaxSpeakers: cfg.DiarizeMaxSpeakers,
+		HTTPClient:  &http.Client{Timeout: time.Duration(cfg.DiarizeTimeoutSec) * time.Second},
+	}
 	runner := &Runner{
 		cfg:        cfg,
 		repo:       repo,
 		translator: translator,
 		asr:        asrClient,
 		ocr:        ocrClient,
+		ocrPool:    ocrPool,
+		diarizer:   diarizer,
 		logger:     logger,
+		data:       data,
 		queue:      make(chan string, 256),
 	}
 	workerCount := cfg.JobConcurrency
@@ -153,9 +232,16 @@ func (r *Runner) process(jobID string) error {
 	if err := r.updateProgress(ctx, jobID, "running", "extract_subtitle", 10, "正在尝试获取源字幕", paths, ""); err != nil {
 		return err
 	}
+	r.recordParseWarnings(jobID, nil)
+	r.recordOCRConfidence(jobID, nil)
+	r.saveJobData(jobID, func(record *jobdata.Record) {
+		record.FusionFlags = nil
+		record.ReviewDrift = ""
+	})
 
-	blocks, sourceSubtitlePath, err := r.resolveSourceBlocks(ctx, job)
-	paths.SourcePath = sourceSubtitlePath
+	source, err := r.resolveSourceBlocks(ocrprovider.WithLanguageHint(ctx, firstNonAuto(job.SourceLanguage, job.DetectedLanguage)), job, settings)
+	paths.SourcePath = source.path
+	blocks := source.blocks
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
 			return r.markCancelled(jobID, job, paths)
@@ -163,21 +249,41 @@ func (r *Runner) process(jobID string) error {
 		_ = r.updateProgress(context.Background(), jobID, "failed", "extract_subtitle", 10, "获取源字幕失败", paths, err.Error())
 		return err
 	}
-	if err := r.updateProgress(ctx, jobID, "running", "translate", 55, fmt.Sprintf("开始翻译，共 %d 条字幕", len(blocks)), paths, ""); err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
-			return r.markCancelled(jobID, job, paths)
-		}
-		return err
-	}
+	r.saveJobData(jobID, func(record *jobdata.Record) {
+		record.Blocks = blocks
+		record.Translations = nil
+	})
+	sourceLanguage, detectedLanguage := r.identifySourceLanguage(ctx, job, blocks, source.language)
+	job.DetectedLanguage = detectedLanguage
 
-	translations, err := r.translator.TranslateBlocks(ctx, buildTranslationPrompt(settings), job.SourceLanguage, job.TargetLanguage, blocks, settings.MaxSubtitlePerBatch)
-	if err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
-			return r.markCancelled(jobID, job, paths)
+	var translations []string
+	if media.SameLanguage(sourceLanguage, job.TargetLanguage) {
+		translations = sourceTexts(blocks)
+		if err := r.updateProgress(ctx, jobID, "running", "translate", 55, fmt.Sprintf("源语言 %s 与目标语言 %s 相同，跳过翻译", sourceLanguage, job.TargetLanguage), paths, ""); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+				return r.markCancelled(jobID, job, paths)
+			}
+			return err
+		}
+	} else {
+		if err := r.updateProgress(ctx, jobID, "running", "translate", 55, fmt.Sprintf("开始翻译，共 %d 条字幕", len(blocks)), paths, ""); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+				return r.markCancelled(jobID, job, paths)
+			}
+			return err
+		}
+		translations, err = r.translator.TranslateBlocks(ctx, buildTranslationPrompt(settings, sourceLanguage), sourceLanguage, job.TargetLanguage, subtitle.NameSpeakers(blocks, r.speakerNames(jobID)), settings.MaxSubtitlePerBatch)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+				return r.markCancelled(jobID, job, paths)
+			}
+			_ = r.updateProgress(context.Background(), jobID, "failed", "translate", 55, "字幕翻译失败", paths, err.Error())
+			return err
 		}
-		_ = r.updateProgress(context.Background(), jobID, "failed", "translate", 55, "字幕翻译失败", paths, err.Error())
-		return err
 	}
+	r.saveJobData(jobID, func(record *jobdata.Record) {
+		record.Translations = translations
+	})
 	if err := r.updateProgress(ctx, jobID, "running", "render", 85, "翻译完成，正在生成输出字幕", paths, ""); err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
 			return r.markCancelled(jobID, job, paths)
@@ -196,123 +302,1318 @@ func (r *Runner) process(jobID string) error {
 	paths.PrimaryPath = outputs.PrimaryPath
 	paths.SRTPath = outputs.SRTPath
 	paths.ASSPath = outputs.ASSPath
+	r.logQASummary(jobID, blocks, translations, settings, job)
 	return r.updateProgress(context.Background(), jobID, "completed", "completed", 100, "字幕输出已生成，可进入详情页校对", paths, "")
 }
 
-func (r *Runner) resolveSourceBlocks(ctx context.Context, job model.SubtitleJob) ([]subtitle.Block, string, error) {
-	path, err := media.ExtractSubtitleSource(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir)
-	if err == nil {
-		if parseErr := r.updateProgress(ctx, job.ID, "running", "parse_subtitle", 30, "已取得源字幕，正在解析 SRT", db.JobOutputPaths{SourcePath: path}, ""); parseErr != nil {
-			return nil, path, parseErr
-		}
-		blocks, parseErr := subtitle.ParseFile(path)
-		if parseErr != nil {
-			return nil, path, parseErr
-		}
-		return blocks, path, nil
-	}
-
-	var ocrErr error
-	if r.ocr != nil && r.ocr.Ready() {
-		if progressErr := r.updateProgress(ctx, job.ID, "running", "ocr_extract", 20, "未找到可用文本字幕，正在抽取硬字幕关键帧", db.JobOutputPaths{}, ""); progressErr != nil {
-			return nil, "", progressErr
-		}
-		frames, frameErr := media.ExtractSubtitleFrames(
-			ctx,
-			r.cfg.FFmpegBin,
-			job.MediaPath,
-			r.cfg.WorkDir,
-			time.Duration(r.cfg.OCRFrameIntervalMS)*time.Millisecond,
-			r.cfg.OCRCropTopPercent,
-			r.cfg.OCRCropHeightPercent,
-		)
-		if frameErr != nil {
-			ocrErr = frameErr
-		} else {
-			if progressErr := r.updateProgress(ctx, job.ID, "running", "ocr_recognize", 35, fmt.Sprintf("已抽取 %d 张关键帧，正在调用远程 OCR", len(frames)), db.JobOutputPaths{}, ""); progressErr != nil {
-				return nil, "", progressErr
+func (r *Runner) resolveSourceBlocks(ctx context.Context, job model.SubtitleJob, settings model.AppSettings) (sourceResult, error) {
+	strategies := job.SourceStrategies
+	origin := "任务指定"
+	if len(strategies) == 0 {
+		strategies = pipeline.EnabledSourceStrategies(settings.SourceStrategies)
+		origin = "默认"
+	}
+	if len(strategies) == 0 {
+		return sourceResult{}, errors.New("没有启用任何字幕来源策略，请在设置页至少启用一种")
+	}
+	titles := make([]string, 0, len(strategies))
+	for _, strategy := range strategies {
+		titles = append(titles, pipeline.SourceStrategyTitle(strategy))
+	}
+	r.logStreamSelection(job.ID, "info", fmt.Sprintf("按%s字幕来源策略依次尝试: %s", origin, strings.Join(titles, " → ")), "")
+	probe := &streamProbe{}
+	failures := make([]string, 0, len(strategies))
+	lastPath := ""
+	for _, strategy := range strategies {
+		result, err := r.acquireSource(ctx, job, settings, strategy, probe)
+		if err == nil {
+			result.strategy = strategy
+			if updateErr := r.repo.UpdateJobSourceStrategy(ctx, job.ID, strategy); updateErr != nil {
+				log.Printf("save source strategy for %s failed: %v", job.ID, updateErr)
 			}
-			observations := make([]ocrprovider.Observation, 0, len(frames))
-			var firstRecognizeErr error
-			for _, frame := range frames {
-				if err := ctx.Err(); err != nil {
-					return nil, "", err
-				}
-				text, confidence, recognizeErr := r.ocr.RecognizeImage(ctx, frame.Path)
-				if recognizeErr != nil {
-					if firstRecognizeErr == nil {
-						firstRecognizeErr = recognizeErr
-					}
-					continue
-				}
-				text = strings.TrimSpace(text)
-				if text == "" {
-					continue
-				}
-				observations = append(observations, ocrprovider.Observation{
-					At:         frame.At,
-					Text:       text,
-					Confidence: confidence,
-				})
+			r.logStreamSelection(job.ID, "info", fmt.Sprintf("源字幕来自「%s」，共 %d 条", pipeline.SourceStrategyTitle(strategy), len(result.blocks)), "")
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return sourceResult{path: result.path}, ctx.Err()
+		}
+		if result.path != "" {
+			lastPath = result.path
+		}
+		var skipped sourceSkipError
+		if !errors.As(err, &skipped) && job.SubtitleStreamIndex != nil && (strategy == pipeline.SourceEmbeddedText || strategy == pipeline.SourceBitmapOCR) {
+			return sourceResult{path: lastPath}, err
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", pipeline.SourceStrategyTitle(strategy), err))
+		level := "warn"
+		if errors.As(err, &skipped) {
+			level = "info"
+		}
+		r.logStreamSelection(job.ID, level, fmt.Sprintf("字幕来源「%s」未取得源字幕，尝试下一种", pipeline.SourceStrategyTitle(strategy)), err.Error())
+	}
+	return sourceResult{path: lastPath}, errors.New("所有字幕来源策略均未取得源字幕：" + strings.Join(failures, "；"))
+}
+
+func (r *Runner) acquireSource(ctx context.Context, job model.SubtitleJob, settings model.AppSettings, strategy string, probe *streamProbe) (sourceResult, error) {
+	switch strategy {
+	case pipeline.SourceSidecar:
+		return r.sidecarSource(ctx, job, settings)
+	case pipeline.SourceEmbeddedText:
+		return r.embeddedTextSource(ctx, job, probe)
+	case pipeline.SourceBitmapOCR:
+		return r.bitmapOCRSource(ctx, job, settings, probe)
+	case pipeline.SourceFrameOCR:
+		return r.frameOCRSource(ctx, job, settings)
+	case pipeline.SourceASR:
+		return r.asrSource(ctx, job, settings)
+	}
+	return sourceResult{}, sourceSkipError{reason: "未知的字幕来源策略 " + strategy}
+}
+
+func (r *Runner) sidecarSource(ctx context.Context, job model.SubtitleJob, settings model.AppSettings) (sourceResult, error) {
+	if job.SubtitleStreamIndex != nil {
+		return sourceResult{}, sourceSkipError{reason: "任务指定了字幕轨，不使用外挂字幕"}
+	}
+	candidates := media.DiscoverSidecars(job.MediaPath)
+	if len(candidates) == 0 {
+		return sourceResult{}, sourceSkipError{reason: "没有找到外挂字幕"}
+	}
+	selected, _ := media.SelectSidecar(candidates, job.SourceLanguage)
+	details := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		details = append(details, candidate.Describe())
+	}
+	r.logStreamSelection(job.ID, "info", "使用外挂字幕 "+selected.Describe(), strings.Join(details, "\n"))
+	source, err := media.ExtractSidecarSource(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir, selected)
+	if err != nil {
+		return sourceResult{}, err
+	}
+	blocks, err := r.parseSourceFile(ctx, job, source.Path)
+	if err != nil {
+		return sourceResult{path: source.Path}, err
+	}
+	if settings.Sync.Enabled {
+		blocks, err = r.syncToAudio(ctx, job, settings.Sync, blocks, source.Path)
+		if err != nil {
+			return sourceResult{path: source.Path}, err
+		}
+	}
+	return sourceResult{blocks: blocks, path: source.Path}, nil
+}
+
+func (r *Runner) embeddedTextSource(ctx context.Context, job model.SubtitleJob, probe *streamProbe) (sourceResult, error) {
+	subtitles, details, err := probe.subtitles(ctx, r.cfg.FFprobeBin, job.MediaPath)
+	var stream *media.Stream
+	switch {
+	case err != nil:
+		if job.SubtitleStreamIndex != nil || ctx.Err() != nil {
+			return sourceResult{}, err
+		}
+		r.logStreamSelection(job.ID, "warn", "媒体流探测失败，退回提取第一条字幕轨", err.Error())
+	case job.SubtitleStreamIndex != nil:
+		selected, ok := media.FindStream(subtitles, *job.SubtitleStreamIndex)
+		if !ok {
+			return sourceResult{}, fmt.Errorf("指定的字幕轨 #%d 不存在或不是字幕流", *job.SubtitleStreamIndex)
+		}
+		if !selected.TextBased {
+			return sourceResult{}, sourceSkipError{reason: fmt.Sprintf("指定的字幕轨 %s 不是文本字幕", selected.Describe())}
+		}
+		r.logStreamSelection(job.ID, "info", "使用任务指定的字幕轨 "+selected.Describe(), details)
+		stream = &selected
+	default:
+		selected, ok := media.SelectSubtitleStream(subtitles, job.SourceLanguage)
+		if !ok {
+			if len(subtitles) > 0 {
+				r.logStreamSelection(job.ID, "warn", fmt.Sprintf("发现 %d 条字幕轨，但都不是文本字幕", len(subtitles)), details)
 			}
-			blocks := ocrprovider.BuildBlocks(observations, ocrprovider.DefaultTimelineOptions())
-			if len(blocks) > 0 {
-				sourceContent := subtitle.RenderSRT(blocks)
-				sourcePath, writeErr := media.WriteOCRSRT(job.MediaPath, r.cfg.WorkDir, sourceContent)
-				if writeErr != nil {
-					return nil, "", writeErr
-				}
-				if progressErr := r.updateProgress(ctx, job.ID, "running", "parse_subtitle", 45, fmt.Sprintf("OCR 识别成功，已恢复 %d 条时间轴字幕", len(blocks)), db.JobOutputPaths{SourcePath: sourcePath}, ""); progressErr != nil {
-					return nil, sourcePath, progressErr
+			return sourceResult{}, errors.New("视频中没有可提取的文本字幕轨")
+		}
+		r.logStreamSelection(job.ID, "info", fmt.Sprintf("已按源语言 %s 选择字幕轨 %s", job.SourceLanguage, selected.Describe()), details)
+		stream = &selected
+	}
+	source, err := media.ExtractEmbeddedSource(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir, stream)
+	if err != nil {
+		return sourceResult{}, err
+	}
+	blocks, err := r.parseSourceFile(ctx, job, source.Path)
+	if err != nil {
+		return sourceResult{path: source.Path}, err
+	}
+	return sourceResult{blocks: blocks, path: source.Path}, nil
+}
+
+func (r *Runner) bitmapOCRSource(ctx context.Context, job model.SubtitleJob, settings model.AppSettings, probe *streamProbe) (sourceResult, error) {
+	if !r.ocrReady() && job.SubtitleStreamIndex == nil {
+		return sourceResult{}, sourceSkipError{reason: "OCR 未配置"}
+	}
+	subtitles, details, err := probe.subtitles(ctx, r.cfg.FFprobeBin, job.MediaPath)
+	if err != nil {
+		return sourceResult{}, err
+	}
+	var stream media.Stream
+	if job.SubtitleStreamIndex != nil {
+		selected, ok := media.FindStream(subtitles, *job.SubtitleStreamIndex)
+		if !ok {
+			return sourceResult{}, fmt.Errorf("指定的字幕轨 #%d 不存在或不是字幕流", *job.SubtitleStreamIndex)
+		}
+		if !selected.TextBased && !selected.BitmapBased {
+			return sourceResult{}, fmt.Errorf("指定的字幕轨 %s 既不是文本字幕也不是可识别的图形字幕", selected.Describe())
+		}
+		if !selected.BitmapBased {
+			return sourceResult{}, sourceSkipError{reason: fmt.Sprintf("指定的字幕轨 %s 不是图形字幕", selected.Describe())}
+		}
+		if !r.ocrReady() {
+			return sourceResult{}, fmt.Errorf("指定的字幕轨 %s 是图形字幕，需要先配置 OCR", selected.Describe())
+		}
+		r.logStreamSelection(job.ID, "info", "使用任务指定的图形字幕轨 "+selected.Describe(), details)
+		stream = selected
+	} else {
+		selected, ok := media.SelectBitmapSubtitleStream(subtitles, job.SourceLanguage)
+		if !ok {
+			return sourceResult{}, sourceSkipError{reason: "视频中没有图形字幕轨"}
+		}
+		r.logStreamSelection(job.ID, "info", fmt.Sprintf("已选择图形字幕轨 %s 进行 OCR", selected.Describe()), details)
+		stream = selected
+	}
+	blocks, sourcePath, err := r.recognizeBitmapStream(ctx, job, settings, stream)
+	if err != nil {
+		return sourceResult{}, err
+	}
+	return sourceResult{blocks: blocks, path: sourcePath}, nil
+}
+
+func (r *Runner) frameOCRSource(ctx context.Context, job model.SubtitleJob, settings model.AppSettings) (sourceResult, error) {
+	if !r.ocrReady() {
+		return sourceResult{}, sourceSkipError{reason: "OCR 未配置"}
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "ocr_extract", 20, "正在抽取硬字幕关键帧", db.JobOutputPaths{}, ""); err != nil {
+		return sourceResult{}, err
+	}
+	blocks, err := r.recognizeHardSubtitles(ctx, job, settings)
+	if len(blocks) == 0 {
+		if err == nil {
+			err = errors.New("OCR 未识别出有效字幕")
+		}
+		return sourceResult{}, err
+	}
+	sourcePath, err := media.WriteOCRSRT(job.MediaPath, r.cfg.WorkDir, subtitle.RenderSRT(blocks))
+	if err != nil {
+		return sourceResult{}, err
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "parse_subtitle", 45, fmt.Sprintf("OCR 识别成功，已恢复 %d 条时间轴字幕", len(blocks)), db.JobOutputPaths{SourcePath: sourcePath}, ""); err != nil {
+		return sourceResult{path: sourcePath}, err
+	}
+	source := sourceResult{blocks: blocks, path: sourcePath}
+	if settings.Fusion.Enabled {
+		return r.fuseWithASR(ctx, job, settings, source)
+	}
+	return source, nil
+}
+
+func (r *Runner) fuseWithASR(ctx context.Context, job model.SubtitleJob, settings model.AppSettings, source sourceResult) (sourceResult, error) {
+	if !r.asr.Ready() {
+		r.logFusion(job.ID, "warn", "已开启 OCR / ASR 融合，但 ASR 未配置，保留 OCR 时间轴", "")
+		return source, nil
+	}
+	skip := func(message string, err error) (sourceResult, error) {
+		if ctx.Err() != nil {
+			return source, ctx.Err()
+		}
+		r.logFusion(job.ID, "warn", message+"，跳过融合并保留 OCR 结果", err.Error())
+		return source, nil
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "extract_audio", 46, "OCR / ASR 融合：正在提取音频进行 ASR 转写", db.JobOutputPaths{SourcePath: source.path}, ""); err != nil {
+		return source, err
+	}
+	audioPath, err := media.ExtractAudio(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir, r.selectAudioTrack(ctx, job))
+	if err != nil {
+		return skip("融合所需的音频提取失败", err)
+	}
+	asrBlocks, language, err := r.transcribeAudio(ctx, job, settings, audioPath)
+	if err != nil {
+		return skip("融合所需的 ASR 转写失败", err)
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "fuse_sources", 50, fmt.Sprintf("正在对齐 %d 条 OCR 字幕与 %d 条 ASR 字幕", len(source.blocks), len(asrBlocks)), db.JobOutputPaths{SourcePath: source.path}, ""); err != nil {
+		return source, err
+	}
+	result := fusion.Align(source.blocks, asrBlocks, fusion.Options{
+		MinSimilarity: settings.Fusion.MinSimilarity,
+		MaxShift:      time.Duration(settings.Fusion.MaxShiftMS) * time.Millisecond,
+	})
+	flags := make([]string, len(result.Blocks))
+	details := make([]string, 0, min(len(result.Flags), maxLoggedFusionFlags)+1)
+	for _, flag := range result.Flags {
+		flags[flag.Index-1] = flag.Describe()
+		if len(details) < maxLoggedFusionFlags {
+			details = append(details, fmt.Sprintf("#%d %s - %s OCR「%s」：%s", flag.Index, formatClock(flag.Start), formatClock(flag.End), flag.OCRText, flag.Describe()))
+		}
+	}
+	if len(result.Flags) > maxLoggedFusionFlags {
+		details = append(details, fmt.Sprintf("……另有 %d 条未列出", len(result.Flags)-maxLoggedFusionFlags))
+	}
+	r.saveJobData(job.ID, func(record *jobdata.Record) {
+		record.FusionFlags = flags
+	})
+	level := "info"
+	if result.Stats.Flagged > 0 {
+		level = "warn"
+	}
+	r.logFusion(job.ID, level, fmt.Sprintf("OCR / ASR 融合完成：%d 条字幕中 %d 条与 ASR 对齐，%d 条按 ASR 校正时间轴（平均起点偏移 %d 毫秒），%d 条差异较大已标记待校对", result.Stats.Cues, result.Stats.Matched, result.Stats.Retimed, result.Stats.MeanShift.Milliseconds(), result.Stats.Flagged), strings.Join(details, "\n"))
+	if len(result.Uncovered) > 0 {
+		uncovered := make([]string, 0, min(len(result.Uncovered), maxLoggedFusionFlags))
+		for _, block := range result.Uncovered[:min(len(result.Uncovered), maxLoggedFusionFlags)] {
+			uncovered = append(uncovered, fmt.Sprintf("%s - %s %s", formatClock(block.Start), formatClock(block.End), strings.Join(block.Lines, " ")))
+		}
+		r.logFusion(job.ID, "warn", fmt.Sprintf("ASR 有 %d 段对白没有对应的 OCR 字幕，可能是硬字幕漏识别", len(result.Uncovered)), strings.Join(uncovered, "\n"))
+	}
+	sourcePath, err := media.WriteOCRSRT(job.MediaPath, r.cfg.WorkDir, subtitle.RenderSRT(result.Blocks))
+	if err != nil {
+		return source, err
+	}
+	return sourceResult{blocks: result.Blocks, path: sourcePath, language: language}, nil
+}
+
+func (r *Runner) logFusion(jobID string, level string, message string, details string) {
+	if r.logger == nil {
+		return
+	}
+	_ = r.logger.Append(jobID, level, "fuse_sources", message, details)
+}
+
+func (r *Runner) asrSource(ctx context.Context, job model.SubtitleJob, settings model.AppSettings) (sourceResult, error) {
+	if !r.asr.Ready() {
+		return sourceResult{}, sourceSkipError{reason: "ASR 未配置"}
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "extract_audio", 20, "正在提取音频进行 ASR 转写", db.JobOutputPaths{}, ""); err != nil {
+		return sourceResult{}, err
+	}
+	audioPath, err := media.ExtractAudio(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir, r.selectAudioTrack(ctx, job))
+	if err != nil {
+		return sourceResult{}, err
+	}
+	blocks, language, err := r.transcribeAudio(ctx, job, settings, audioPath)
+	if err != nil {
+		return sourceResult{}, err
+	}
+	blocks, err = r.diarizeBlocks(ctx, job, audioPath, blocks)
+	if err != nil {
+		return sourceResult{}, err
+	}
+	sourcePath, err := media.WriteSourceSRT(job.MediaPath, r.cfg.WorkDir, subtitle.RenderSRT(blocks))
+	if err != nil {
+		return sourceResult{}, err
+	}
+	return sourceResult{blocks: blocks, path: sourcePath, language: language}, nil
+}
+
+func (r *Runner) parseSourceFile(ctx context.Context, job model.SubtitleJob, path string) ([]subtitle.Block, error) {
+	if err := r.updateProgress(ctx, job.ID, "running", "parse_subtitle", 30, "已取得源字幕，正在解析 SRT", db.JobOutputPaths{SourcePath: path}, ""); err != nil {
+		return nil, err
+	}
+	blocks, warnings, err := subtitle.ParseFileWithWarnings(path)
+	r.recordParseWarnings(job.ID, warnings)
+	return blocks, err
+}
+
+func (r *Runner) identifySourceLanguage(ctx context.Context, job model.SubtitleJob, blocks []subtitle.Block, asrLanguage string) (string, string) {
+	textLanguage, confidence := subtitle.DetectLanguage(blocks)
+	detected, origin := media.NormalizeLanguage(asrLanguage), "ASR"
+	switch {
+	case detected == "zh" && textLanguage == "zh-Hant" && confidence >= minLanguageConfidence:
+		detected = textLanguage
+	case detected == "" && textLanguage != "" && confidence >= minLanguageConfidence:
+		detected, origin = textLanguage, fmt.Sprintf("文本识别，置信度 %.2f", confidence)
+	}
+	configured := ""
+	if media.NormalizeLanguage(job.SourceLanguage) != "" {
+		configured = job.SourceLanguage
+	}
+	if err := r.repo.UpdateJobDetectedLanguage(ctx, job.ID, detected); err != nil {
+		log.Printf("save detected language for %s failed: %v", job.ID, err)
+	}
+	if detected == "" {
+		if configured != "" {
+			return configured, ""
+		}
+		if r.logger != nil {
+			_ = r.logger.Append(job.ID, "warn", "detect_language", "未能可靠识别源语言，翻译时将由模型自行判断", "")
+		}
+		return "auto", ""
+	}
+	if r.logger != nil {
+		level, message := "info", fmt.Sprintf("已识别源语言: %s（%s）", detected, origin)
+		if configured != "" && !media.SameLanguage(configured, detected) {
+			level, message = "warn", fmt.Sprintf("任务指定源语言为 %s，但识别结果为 %s（%s），仍按指定语言处理", configured, detected, origin)
+		}
+		_ = r.logger.Append(job.ID, level, "detect_language", message, "")
+	}
+	return firstNonAuto(configured, detected), detected
+}
+
+func firstNonAuto(values ...string) string {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !strings.EqualFold(value, "auto") {
+			return value
+		}
+	}
+	return ""
+}
+
+func sourceTexts(blocks []subtitle.Block) []string {
+	texts := make([]string, len(blocks))
+	for index, block := range blocks {
+		texts[index] = strings.Join(block.Lines, "\n")
+	}
+	return texts
+}
+
+func (r *Runner) transcribeAudio(ctx context.Context, job model.SubtitleJob, settings model.AppSettings, audioPath string) ([]subtitle.Block, string, error) {
+	chunks, total, err := media.SplitAudio(audioPath, media.ChunkOptions{
+		ChunkDuration: time.Duration(r.cfg.ASRChunkSeconds) * time.Second,
+		Overlap:       time.Duration(r.cfg.ASRChunkOverlapSec) * time.Second,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	encoding := media.AudioEncoding{Codec: r.cfg.ASRAudioCodec, Bitrate: r.cfg.ASRAudioBitrate}.Normalize()
+	if encoding.Codec != media.AudioCodecWAV {
+		wavSize := media.AudioFileSize(chunkPaths(chunks)...)
+		chunks, err = media.EncodeAudioChunks(ctx, r.cfg.FFmpegBin, chunks, encoding)
+		if err != nil {
+			return nil, "", err
+		}
+		if r.logger != nil {
+			_ = r.logger.Append(job.ID, "info", "transcribe", fmt.Sprintf("ASR 音频已编码为 %s，上传体积 %s（WAV %s）", encoding.Describe(), formatBytes(media.AudioFileSize(chunkPaths(chunks)...)), formatBytes(wavSize)), "")
+		}
+	}
+	message := "音频提取完成，正在调用 ASR 转写"
+	if len(chunks) > 1 {
+		message = fmt.Sprintf("音频时长 %s，已在静音处切分为 %d 段，正在并发调用 ASR 转写", formatClock(total), len(chunks))
+		if r.logger != nil {
+			details := make([]string, 0, len(chunks))
+			for _, chunk := range chunks {
+				details = append(details, fmt.Sprintf("第 %d 段: %s - %s", chunk.Index, formatClock(chunk.Start), formatClock(chunk.End)))
+			}
+			_ = r.logger.Append(job.ID, "info", "transcribe", message, strings.Join(details, "\n"))
+		}
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "transcribe", 40, message, db.JobOutputPaths{}, ""); err != nil {
+		return nil, "", err
+	}
+	options := asr.ChunkedOptions{
+		Workers:    r.cfg.ASRConcurrency,
+		MaxRetries: r.cfg.ASRMaxRetries,
+		Segmentation: asr.SegmentOptions{
+			MaxDuration: time.Duration(r.cfg.ASRMaxCueSeconds * float64(time.Second)),
+			MaxChars:    r.cfg.ASRMaxCueChars,
+		},
+	}
+	if settings.ASRFilter.Enabled {
+		options.Filter = &asr.FilterOptions{
+			MaxNoSpeechProb: settings.ASRFilter.MaxNoSpeechProb,
+			MinAvgLogprob:   settings.ASRFilter.MinAvgLogprob,
+			MaxRepeats:      settings.ASRFilter.MaxRepeats,
+			Blocklist:       asr.ParseBlocklist(settings.ASRFilter.Blocklist),
+		}
+	}
+	blocks, stats, err := asr.TranscribeChunks(ctx, r.asr, chunks, job.SourceLanguage, options)
+	r.logASRFiltered(job.ID, stats.Filtered)
+	if r.logger != nil && (stats.Chunks > 1 || stats.Retries > 0) {
+		_ = r.logger.Append(job.ID, "info", "transcribe", fmt.Sprintf("ASR 共转写 %d 段，发起 %d 次请求（重试 %d 次），拼接时去除重叠区重复片段 %d 条", stats.Chunks, stats.Requests, stats.Retries, stats.Dropped), "")
+	}
+	return blocks, stats.Language, err
+}
+
+func (r *Runner) diarizeBlocks(ctx context.Context, job model.SubtitleJob, audioPath string, blocks []subtitle.Block) ([]subtitle.Block, error) {
+	if !r.diarizer.Ready() {
+		return blocks, nil
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "diarize", 50, "转写完成，正在进行说话人分离", db.JobOutputPaths{}, ""); err != nil {
+		return nil, err
+	}
+	turns, err := r.diarizer.Diarize(ctx, audioPath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if r.logger != nil {
+			_ = r.logger.Append(job.ID, "warn", "diarize", "说话人分离失败，字幕将不带说话人信息", err.Error())
+		}
+		return blocks, nil
+	}
+	assigned, count := diarize.Assign(blocks, turns)
+	if r.logger != nil {
+		speakers := subtitle.SummarizeSpeakers(assigned, nil)
+		details := make([]string, 0, len(speakers))
+		for _, speaker := range speakers {
+			details = append(details, fmt.Sprintf("%s（%s）: %d 条", speaker.Name, speaker.ID, speaker.Count))
+		}
+		_ = r.logger.Append(job.ID, "info", "diarize", fmt.Sprintf("说话人分离识别出 %d 位说话人，%d / %d 条字幕已标注", len(speakers), count, len(assigned)), strings.Join(details, "\n"))
+	}
+	return assigned, nil
+}
+
+func (r *Runner) speakerNames(jobID string) map[string]string {
+	if r.data == nil {
+		return nil
+	}
+	record, err := r.data.Load(jobID)
+	if err != nil {
+		return nil
+	}
+	return record.Speakers
+}
+
+func (r *Runner) logASRFiltered(jobID string, removed []asr.FilterRemoval) {
+	if r.logger == nil || len(removed) == 0 {
+		return
+	}
+	details := make([]string, 0, len(removed))
+	for _, removal := range removed {
+		details = append(details, fmt.Sprintf("%s - %s [%s] %s", formatClock(removal.Start), formatClock(removal.End), removal.Reason, removal.Text))
+	}
+	_ = r.logger.Append(jobID, "warn", "transcribe", fmt.Sprintf("ASR 幻觉过滤移除或合并了 %d 条疑似静音 / 音乐 / 重复片段", len(removed)), strings.Join(details, "\n"))
+}
+
+func (r *Runner) selectAudioTrack(ctx context.Context, job model.SubtitleJob) int {
+	streams, err := media.ProbeStreams(ctx, r.cfg.FFprobeBin, job.MediaPath)
+	if err != nil {
+		if r.logger != nil && ctx.Err() == nil {
+			_ = r.logger.Append(job.ID, "warn", "extract_audio", "媒体流探测失败，使用 ffmpeg 默认音轨", err.Error())
+		}
+		return -1
+	}
+	audios := media.AudioStreams(streams)
+	if len(audios) < 2 {
+		return -1
+	}
+	stream, ok := media.SelectAudioStream(audios, job.SourceLanguage)
+	if !ok {
+		return -1
+	}
+	if r.logger != nil {
+		details := make([]string, 0, len(audios))
+		for _, audio := range audios {
+			details = append(details, audio.Describe())
+		}
+		_ = r.logger.Append(job.ID, "info", "extract_audio", "ASR 使用音轨 "+stream.Describe(), strings.Join(details, "\n"))
+	}
+	return stream.TypeIndex
+}
+
+func chunkPaths(chunks []media.AudioChunk) []string {
+	paths := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		paths = append(paths, chunk.Path)
+	}
+	return paths
+}
+
+func formatBytes(size int64) string {
+	if size < 1<<20 {
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+}
+
+func formatClock(value time.Duration) string {
+	seconds := int(value.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+func (r *Runner) recognizeHardSubtitles(ctx context.Context, job model.SubtitleJob, settings model.AppSettings) ([]subtitle.Block, error) {
+	regions := r.resolveOCRRegions(ctx, job)
+	cues := make([]ocrprovider.Cue, 0, 64)
+	var firstErr error
+	for _, region := range regions {
+		frames, err := r.extractOCRFrames(ctx, job, region)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		message := fmt.Sprintf("已抽取 %d 张关键帧，正在调用远程 OCR", len(frames))
+		if len(regions) > 1 {
+			message = fmt.Sprintf("区域 %s 已抽取 %d 张关键帧，正在调用远程 OCR", describeCropRegion(region), len(frames))
+		}
+		if err := r.updateProgress(ctx, job.ID, "running", "ocr_recognize", 35, message, db.JobOutputPaths{}, ""); err != nil {
+			return nil, err
+		}
+		observations, err := r.recognizeFrames(ctx, job.ID, frames)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		built := ocrprovider.BuildCues(observations, TimelineOptions(settings))
+		if position := blockRegion(region); position != "" {
+			for index := range built {
+				built[index].Block.Region = position
+			}
+		}
+		cues = append(cues, built...)
+	}
+	if len(cues) == 0 {
+		return nil, firstErr
+	}
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].Block.Start < cues[j].Block.Start
+	})
+	blocks := make([]subtitle.Block, len(cues))
+	confidence := make([]float64, len(cues))
+	for index, cue := range cues {
+		blocks[index] = cue.Block
+		blocks[index].Index = index + 1
+		confidence[index] = cue.Confidence
+	}
+	r.recordOCRConfidence(job.ID, confidence)
+	r.logOCRConfidence(job.ID, cues, settings.QA.MinOCRConfidence)
+	return blocks, nil
+}
+
+func (r *Runner) recordOCRConfidence(jobID string, confidence []float64) {
+	r.saveJobData(jobID, func(record *jobdata.Record) {
+		record.OCRConfidence = append([]float64(nil), confidence...)
+	})
+}
+
+func (r *Runner) logOCRConfidence(jobID string, cues []ocrprovider.Cue, floor float64) {
+	if r.logger == nil || len(cues) == 0 {
+		return
+	}
+	rated := 0
+	total := 0.0
+	low := make([]string, 0, 8)
+	lowCount := 0
+	for index, cue := range cues {
+		if cue.Confidence <= 0 {
+			continue
+		}
+		rated++
+		total += cue.Confidence
+		if cue.Confidence < floor {
+			lowCount++
+			if len(low) < maxLoggedLowConfidence {
+				detail := fmt.Sprintf("%.2f", cue.Confidence)
+				if cue.Frames > 1 {
+					detail += fmt.Sprintf("，%d 帧一致率 %.0f%%", cue.Frames, cue.Agreement*100)
 				}
-				return blocks, sourcePath, nil
+				low = append(low, fmt.Sprintf("第 %d 条（%s）: %s", index+1, detail, subtitle.JoinText(cue.Block.Lines)))
+			}
+		}
+	}
+	if rated == 0 {
+		_ = r.logger.Append(jobID, "info", "ocr_recognize", fmt.Sprintf("OCR 共恢复 %d 条字幕，提供方未返回置信度", len(cues)), "")
+		return
+	}
+	message := fmt.Sprintf("OCR 共恢复 %d 条字幕，平均置信度 %.2f，%d 条低于 %.2f 需要复核", len(cues), total/float64(rated), lowCount, floor)
+	if floor <= 0 {
+		message = fmt.Sprintf("OCR 共恢复 %d 条字幕，平均置信度 %.2f，置信度检查已关闭", len(cues), total/float64(rated))
+	}
+	if lowCount == 0 {
+		_ = r.logger.Append(jobID, "info", "ocr_recognize", message, "")
+		return
+	}
+	_ = r.logger.Append(jobID, "warn", "ocr_recognize", message, strings.Join(low, "\n"))
+}
+
+func (r *Runner) resolveOCRRegions(ctx context.Context, job model.SubtitleJob) []media.CropRegion {
+	if len(job.OCRRegions) > 0 {
+		regions := cropRegions(job.OCRRegions)
+		r.logFrameSampling(job.ID, "info", "使用任务指定的 OCR 字幕区域 "+describeCropRegions(regions), "")
+		return regions
+	}
+	if r.repo != nil {
+		stored, found, err := r.repo.GetMediaOCRRegions(ctx, job.MediaPath)
+		if err == nil && found {
+			regions := cropRegions(stored.Regions)
+			r.logFrameSampling(job.ID, "info", fmt.Sprintf("使用已保存的 OCR 字幕区域 %s（%s）", describeCropRegions(regions), stored.Source), "")
+			return regions
+		}
+	}
+	fallback := []media.CropRegion{{TopPercent: float64(r.cfg.OCRCropTopPercent), HeightPercent: float64(r.cfg.OCRCropHeightPercent)}}
+	if err := r.updateProgress(ctx, job.ID, "running", "ocr_extract", 18, "正在检测硬字幕所在区域", db.JobOutputPaths{}, ""); err != nil {
+		return fallback
+	}
+	detected, err := media.DetectSubtitleRegions(ctx, r.cfg.FFmpegBin, r.cfg.FFprobeBin, job.MediaPath)
+	if err != nil {
+		r.logFrameSampling(job.ID, "warn", "字幕区域检测失败，使用默认裁剪区域 "+describeCropRegions(fallback), err.Error())
+		return fallback
+	}
+	regions := make([]model.OCRRegion, 0, len(detected))
+	for _, region := range detected {
+		regions = append(regions, model.OCRRegion{TopPercent: region.TopPercent, HeightPercent: region.HeightPercent})
+	}
+	if r.repo != nil {
+		if _, err := r.repo.SaveMediaOCRRegions(ctx, job.MediaPath, regions, "detected"); err != nil {
+			r.logFrameSampling(job.ID, "warn", "保存检测到的字幕区域失败", err.Error())
+		}
+	}
+	result := cropRegions(regions)
+	r.logFrameSampling(job.ID, "info", "已自动检测到 OCR 字幕区域 "+describeCropRegions(result), "")
+	return result
+}
+
+func cropRegions(regions []model.OCRRegion) []media.CropRegion {
+	result := make([]media.CropRegion, 0, len(regions))
+	for index, region := range regions {
+		crop := media.CropRegion{Name: fmt.Sprintf("r%d", index+1), TopPercent: region.TopPercent, HeightPercent: region.HeightPercent}
+		result = append(result, crop.Normalize())
+	}
+	return result
+}
+
+func blockRegion(region media.CropRegion) string {
+	if region.TopPercent+region.HeightPercent/2 < 50 {
+		return subtitle.RegionTop
+	}
+	return ""
+}
+
+func describeCropRegion(region media.CropRegion) string {
+	return fmt.Sprintf("%.1f%%~%.1f%%", region.TopPercent, region.TopPercent+region.HeightPercent)
+}
+
+func describeCropRegions(regions []media.CropRegion) string {
+	parts := make([]string, 0, len(regions))
+	for _, region := range regions {
+		parts = append(parts, describeCropRegion(region))
+	}
+	return strings.Join(parts, "、")
+}
+
+func (r *Runner) extractOCRFrames(ctx context.Context, job model.SubtitleJob, region media.CropRegion) ([]media.SubtitleFrame, error) {
+	if r.cfg.OCRSamplingMode == media.OCRSamplingChange {
+		frames, err := media.ExtractSubtitleChangeFrames(ctx, r.cfg.FFmpegBin, r.cfg.FFprobeBin, job.MediaPath, r.cfg.WorkDir, region)
+		if err == nil {
+			r.logFrameSampling(job.ID, "info", fmt.Sprintf("字幕区域变化检测得到 %d 个字幕片段，每段只识别一张代表帧", len(frames)), "")
+			return frames, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		r.logFrameSampling(job.ID, "warn", "变化检测抽帧失败，退回固定间隔抽帧", err.Error())
+	}
+	return media.ExtractSubtitleFrames(
+		ctx,
+		r.cfg.FFmpegBin,
+		job.MediaPath,
+		r.cfg.WorkDir,
+		time.Duration(r.cfg.OCRFrameIntervalMS)*time.Millisecond,
+		region,
+	)
+}
+
+func (r *Runner) recognizeBitmapStream(ctx context.Context, job model.SubtitleJob, settings model.AppSettings, stream media.Stream) ([]subtitle.Block, string, error) {
+	if err := r.updateProgress(ctx, job.ID, "running", "ocr_extract", 20, fmt.Sprintf("正在读取图形字幕轨 %s 的显示事件", stream.Describe()), db.JobOutputPaths{}, ""); err != nil {
+		return nil, "", err
+	}
+	events, err := media.ProbeBitmapEvents(ctx, r.cfg.FFprobeBin, job.MediaPath, stream)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(events) == 0 {
+		return nil, "", errors.New("图形字幕轨中没有可用的显示事件")
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "ocr_extract", 25, fmt.Sprintf("共 %d 条图形字幕事件，正在渲染字幕图片", len(events)), db.JobOutputPaths{}, ""); err != nil {
+		return nil, "", err
+	}
+	events, err = media.RenderBitmapEvents(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir, stream, events)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "ocr_recognize", 35, fmt.Sprintf("已渲染 %d 张图形字幕，正在调用 OCR", len(events)), db.JobOutputPaths{}, ""); err != nil {
+		return nil, "", err
+	}
+	paths := make([]string, len(events))
+	for index, event := range events {
+		paths[index] = event.Path
+	}
+	recognizer := r.cachedOCR()
+	results, poolStats, poolErr := r.ocrPool.RecognizeAll(ctx, recognizer, paths)
+	r.logOCRCache(job.ID, recognizer.Stats())
+	r.logOCRPool(job.ID, poolStats, results)
+	if poolErr != nil {
+		return nil, "", poolErr
+	}
+	blocks := make([]subtitle.Block, 0, len(events))
+	confidence := make([]float64, 0, len(events))
+	cues := make([]ocrprovider.Cue, 0, len(events))
+	var firstErr error
+	for index, event := range events {
+		if results[index].Err != nil {
+			if firstErr == nil {
+				firstErr = results[index].Err
 			}
-			if firstRecognizeErr != nil {
-				ocrErr = fmt.Errorf("OCR 未识别出有效字幕，首个错误: %w", firstRecognizeErr)
-			} else {
-				ocrErr = fmt.Errorf("OCR 未识别出有效字幕")
+			continue
+		}
+		lines := make([]string, 0, 2)
+		for _, line := range strings.Split(strings.TrimSpace(results[index].Text), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
 			}
 		}
+		if len(lines) == 0 {
+			continue
+		}
+		block := subtitle.Block{Index: len(blocks) + 1, Start: event.Start, End: event.End, Lines: lines}
+		blocks = append(blocks, block)
+		confidence = append(confidence, results[index].Confidence)
+		cues = append(cues, ocrprovider.Cue{Block: block, Confidence: results[index].Confidence, Frames: 1})
+	}
+	if len(blocks) == 0 {
+		if firstErr != nil {
+			return nil, "", fmt.Errorf("图形字幕 OCR 未识别出有效文本，首个错误: %w", firstErr)
+		}
+		return nil, "", errors.New("图形字幕 OCR 未识别出有效文本")
 	}
+	r.recordOCRConfidence(job.ID, confidence)
+	r.logOCRConfidence(job.ID, cues, settings.QA.MinOCRConfidence)
+	sourcePath, err := media.WriteOCRSRT(job.MediaPath, r.cfg.WorkDir, subtitle.RenderSRT(blocks))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := r.updateProgress(ctx, job.ID, "running", "parse_subtitle", 45, fmt.Sprintf("图形字幕识别完成，%d 个事件恢复出 %d 条字幕", len(events), len(blocks)), db.JobOutputPaths{SourcePath: sourcePath}, ""); err != nil {
+		return nil, sourcePath, err
+	}
+	return blocks, sourcePath, nil
+}
 
-	if !r.asr.Ready() {
-		reasons := []string{fmt.Sprintf("文本字幕提取失败: %v", err)}
-		if r.ocr != nil && r.ocr.Ready() {
-			if ocrErr != nil {
-				reasons = append(reasons, fmt.Sprintf("OCR 失败: %v", ocrErr))
-			} else {
-				reasons = append(reasons, "OCR 未产出有效字幕")
+func (r *Runner) recognizeFrames(ctx context.Context, jobID string, frames []media.SubtitleFrame) ([]ocrprovider.Observation, error) {
+	deduplicator := ocrprovider.NewDeduplicator(ocrprovider.DefaultDedupDistance)
+	stats := ocrprovider.FrameStats{Extracted: len(frames)}
+	sources := make([]int, len(frames))
+	paths := make([]string, 0, len(frames))
+	for index, frame := range frames {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		signature, analyzeErr := ocrprovider.AnalyzeFrame(frame.Path)
+		if analyzeErr == nil {
+			if !signature.HasText {
+				stats.Blank++
+				sources[index] = -1
+				continue
+			}
+			if source, ok := deduplicator.Lookup(signature); ok {
+				stats.Reused++
+				sources[index] = source
+				continue
 			}
-		} else {
-			reasons = append(reasons, "OCR 未配置")
+			deduplicator.Remember(signature, len(paths))
+		}
+		sources[index] = len(paths)
+		paths = append(paths, frame.Path)
+	}
+	stats.Sent = len(paths)
+	r.logFrameStats(jobID, stats)
+	recognizer := r.cachedOCR()
+	results, poolStats, poolErr := r.ocrPool.RecognizeAll(ctx, recognizer, paths)
+	r.logOCRCache(jobID, recognizer.Stats())
+	r.logOCRPool(jobID, poolStats, results)
+	if poolErr != nil {
+		return nil, poolErr
+	}
+	observations := make([]ocrprovider.Observation, 0, len(frames))
+	var firstErr error
+	for index, frame := range frames {
+		source := sources[index]
+		if source < 0 {
+			continue
+		}
+		result := results[source]
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			continue
+		}
+		text := strings.TrimSpace(result.Text)
+		if text == "" {
+			continue
+		}
+		observations = append(observations, ocrprovider.Observation{
+			At:         frame.At,
+			End:        frame.End,
+			Text:       text,
+			Confidence: result.Confidence,
+		})
+	}
+	if len(observations) == 0 && firstErr != nil {
+		return nil, fmt.Errorf("OCR 未识别出有效字幕，首个错误: %w", firstErr)
+	}
+	return observations, nil
+}
+
+func (r *Runner) logOCRPool(jobID string, stats ocrprovider.PoolStats, results []ocrprovider.Result) {
+	if r.logger == nil || stats.Requests == 0 {
+		return
+	}
+	message := fmt.Sprintf("OCR 共调用 %d 次（重试 %d 次），失败 %d 张", stats.Requests, stats.Retries, stats.Failed)
+	if r.cfg.OCRBatchSize > 1 {
+		message += fmt.Sprintf("，批量模式每次最多 %d 张", r.cfg.OCRBatchSize)
+	}
+	if stats.Failed == 0 {
+		_ = r.logger.Append(jobID, "info", "ocr_recognize", message, "")
+		return
+	}
+	counts := map[string]int{}
+	order := make([]string, 0, 4)
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		key := result.Err.Error()
+		if counts[key] == 0 {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+	details := make([]string, 0, len(order))
+	for index, key := range order {
+		if index == 5 {
+			details = append(details, fmt.Sprintf("……另有 %d 种错误", len(order)-index))
+			break
+		}
+		details = append(details, fmt.Sprintf("%d 次: %s", counts[key], key))
+	}
+	_ = r.logger.Append(jobID, "warn", "ocr_recognize", message, strings.Join(details, "\n"))
+}
+
+func (r *Runner) logFrameStats(jobID string, stats ocrprovider.FrameStats) {
+	if r.logger == nil {
+		return
+	}
+	message := fmt.Sprintf("OCR 抽取 %d 帧，实际发送 %d 帧（跳过空白 %d 帧，复用相似帧结果 %d 帧）", stats.Extracted, stats.Sent, stats.Blank, stats.Reused)
+	_ = r.logger.Append(jobID, "info", "ocr_recognize", message, "")
+}
+
+func (r *Runner) cachedOCR() *ocrprovider.CachedProvider {
+	var store ocrprovider.CacheStore
+	if r.repo != nil {
+		store = r.repo
+	}
+	modelName := r.cfg.OCRModel
+	if r.cfg.OCRProvider == config.OCRProviderCommand {
+		modelName = r.cfg.OCRCommand
+	}
+	return ocrprovider.NewCachedProvider(r.ocr, store, modelName)
+}
+
+func (r *Runner) logOCRCache(jobID string, stats ocrprovider.CacheStats) {
+	if r.logger == nil || stats.Hits+stats.Misses == 0 {
+		return
+	}
+	message := fmt.Sprintf("OCR 缓存命中 %d 次，未命中 %d 次", stats.Hits, stats.Misses)
+	level := "info"
+	details := ""
+	if stats.Errors > 0 {
+		level = "warn"
+		details = fmt.Sprintf("缓存读写失败 %d 次，已直接调用 OCR", stats.Errors)
+	}
+	_ = r.logger.Append(jobID, level, "ocr_recognize", message, details)
+}
+
+func (r *Runner) logFrameSampling(jobID string, level string, message string, details string) {
+	if r.logger == nil {
+		return
+	}
+	_ = r.logger.Append(jobID, level, "ocr_extract", message, details)
+}
+
+func (r *Runner) ocrReady() bool {
+	return r.ocr != nil && r.ocr.Ready()
+}
+
+func (r *Runner) logStreamSelection(jobID string, level string, message string, details string) {
+	if r.logger == nil {
+		return
+	}
+	_ = r.logger.Append(jobID, level, "extract_subtitle", message, details)
+}
+
+func (r *Runner) syncToAudio(ctx context.Context, job model.SubtitleJob, settings model.SyncSettings, blocks []subtitle.Block, sourcePath string) ([]subtitle.Block, error) {
+	if err := r.updateProgress(ctx, job.ID, "running", "sync_subtitle", 35, "正在根据音频校准外挂字幕时间轴", db.JobOutputPaths{SourcePath: sourcePath}, ""); err != nil {
+		return nil, err
+	}
+	pcmPath, err := media.ExtractPCM(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir, syncSampleRate)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		return nil, "", fmt.Errorf(strings.Join(reasons, "；") + "；且 ASR 未配置")
+		r.logSync(job.ID, "warn", "音频同步跳过：无法提取音频", err.Error())
+		return blocks, nil
+	}
+	defer os.Remove(pcmPath)
+	file, err := os.Open(pcmPath)
+	if err != nil {
+		r.logSync(job.ID, "warn", "音频同步跳过：无法读取音频", err.Error())
+		return blocks, nil
+	}
+	activity, err := audiosync.DetectSpeech(file, syncSampleRate, 20*time.Millisecond)
+	file.Close()
+	if err != nil {
+		r.logSync(job.ID, "warn", "音频同步跳过：语音活动检测失败", err.Error())
+		return blocks, nil
 	}
+	options := audiosync.DefaultOptions()
+	options.MaxOffset = time.Duration(settings.MaxOffsetMS) * time.Millisecond
+	result, err := audiosync.Estimate(activity, blocks, options)
+	if err != nil {
+		r.logSync(job.ID, "warn", "音频同步跳过："+err.Error(), "")
+		return blocks, nil
+	}
+	details := fmt.Sprintf("偏移 %+d 毫秒，漂移 %+.4f%%，置信度 %.2f（阈值 %.2f），字幕覆盖语音比例 %.0f%%，整体语音占比 %.0f%%",
+		result.Offset.Milliseconds(), result.Drift()*100, result.Confidence, settings.MinConfidence, result.Coverage*100, activity.Ratio()*100)
+	if result.Confidence < settings.MinConfidence {
+		r.logSync(job.ID, "warn", "音频同步置信度不足，保留原时间轴", details)
+		return blocks, nil
+	}
+	if result.Offset.Abs() < syncMinCorrection && math.Abs(result.Drift()) < syncMinDrift {
+		r.logSync(job.ID, "info", "外挂字幕与音频已对齐，无需校正", details)
+		return blocks, nil
+	}
+	synced := result.Apply(blocks)
+	if _, err := media.WriteTextFile(sourcePath, subtitle.RenderSRT(synced)); err != nil {
+		return nil, err
+	}
+	r.logSync(job.ID, "info", "已根据音频校正外挂字幕时间轴", details)
+	return synced, nil
+}
 
-	fallbackMessage := "未找到可用文本字幕，转为提取音频进行 ASR"
-	if ocrErr != nil {
-		fallbackMessage = "文本字幕不可用，且 OCR 未产出有效结果，转为提取音频进行 ASR"
+func (r *Runner) logSync(jobID string, level string, message string, details string) {
+	if r.logger == nil {
+		return
+	}
+	_ = r.logger.Append(jobID, level, "sync_subtitle", message, details)
+}
+
+func (r *Runner) AdjustTiming(ctx context.Context, jobID string, operation subtitle.TimingOperation) (model.SubtitleJob, error) {
+	job, err := r.repo.GetJob(ctx, jobID)
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
+	if job.Status != "completed" && job.Status != "failed" && job.Status != "cancelled" {
+		return model.SubtitleJob{}, errors.New("任务仍在执行中，暂不能调整时间轴")
+	}
+	if r.data == nil {
+		return model.SubtitleJob{}, errors.New("任务数据存储不可用")
+	}
+	record, err := r.data.Load(job.ID)
+	if err != nil {
+		return model.SubtitleJob{}, err
 	}
-	if progressErr := r.updateProgress(ctx, job.ID, "running", "extract_audio", 20, fallbackMessage, db.JobOutputPaths{}, ""); progressErr != nil {
-		return nil, "", progressErr
+	if len(record.Blocks) == 0 {
+		return model.SubtitleJob{}, errors.New("任务还没有可调整的源字幕")
 	}
-	audioPath, audioErr := media.ExtractAudio(ctx, r.cfg.FFmpegBin, job.MediaPath, r.cfg.WorkDir)
-	if audioErr != nil {
-		return nil, "", audioErr
+	if err := reviewDriftError(record); err != nil {
+		return model.SubtitleJob{}, err
 	}
-	if progressErr := r.updateProgress(ctx, job.ID, "running", "transcribe", 40, "音频提取完成，正在调用 ASR 转写", db.JobOutputPaths{}, ""); progressErr != nil {
-		return nil, "", progressErr
+	blocks, err := subtitle.ApplyTiming(record.Blocks, operation)
+	if err != nil {
+		return model.SubtitleJob{}, err
 	}
-	blocks, transcribeErr := r.asr.Transcribe(ctx, audioPath, job.SourceLanguage)
-	if transcribeErr != nil {
-		return nil, "", transcribeErr
+	paths := db.JobOutputPaths{
+		SourcePath:  job.SourceSubtitlePath,
+		PrimaryPath: job.OutputSubtitlePath,
+		SRTPath:     job.OutputSRTPath,
+		ASSPath:     job.OutputASSPath,
 	}
 	sourceContent := subtitle.RenderSRT(blocks)
-	sourcePath, writeErr := media.WriteSourceSRT(job.MediaPath, r.cfg.WorkDir, sourceContent)
-	if writeErr != nil {
-		return nil, "", writeErr
+	if strings.TrimSpace(paths.SourcePath) != "" {
+		_, err = media.WriteTextFile(paths.SourcePath, sourceContent)
+	} else {
+		paths.SourcePath, err = media.WriteSourceSRT(job.MediaPath, r.cfg.WorkDir, sourceContent)
 	}
-	return blocks, sourcePath, nil
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
+	if err := r.data.Update(job.ID, func(record *jobdata.Record) {
+		record.Blocks = blocks
+	}); err != nil {
+		return model.SubtitleJob{}, err
+	}
+
+	details := fmt.Sprintf("源字幕时间轴已调整：%s", operation.Describe())
+	regenerate := len(record.Translations) == len(blocks)
+	var settings model.AppSettings
+	if regenerate {
+		settings, err = r.repo.GetSettings(ctx)
+		if err != nil {
+			return model.SubtitleJob{}, err
+		}
+		outputs, err := r.renderOutputs(job, settings, blocks, record.Translations)
+		if err != nil {
+			return model.SubtitleJob{}, err
+		}
+		paths.PrimaryPath = outputs.PrimaryPath
+		paths.SRTPath = outputs.SRTPath
+		paths.ASSPath = outputs.ASSPath
+		details += "，双语字幕已重新生成"
+	}
+	if err := r.updateProgress(ctx, job.ID, job.Status, job.CurrentStage, job.Progress, details, paths, job.ErrorMessage); err != nil {
+		return model.SubtitleJob{}, err
+	}
+	if regenerate {
+		r.logQASummary(job.ID, blocks, record.Translations, settings, job)
+	}
+	return r.repo.GetJob(ctx, job.ID)
+}
+
+func (r *Runner) RenameSpeakers(ctx context.Context, jobID string, names map[string]string) (model.SubtitleJob, error) {
+	job, err := r.repo.GetJob(ctx, jobID)
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
+	if job.Status != "completed" && job.Status != "failed" && job.Status != "cancelled" {
+		return model.SubtitleJob{}, errors.New("任务仍在执行中，暂不能修改说话人名称")
+	}
+	if r.data == nil {
+		return model.SubtitleJob{}, errors.New("任务数据存储不可用")
+	}
+	record, err := r.data.Load(job.ID)
+	if err != nil {
+		return model.SubtitleJob{}, err
+	}
+	if err := reviewDriftError(record); err != nil {
+		return model.SubtitleJob{}, err
+	}
+	known := map[string]bool{}
+	for _, speaker := range subtitle.SummarizeSpeakers(record.Blocks, nil) {
+		known[speaker.ID] = true
+	}
+	if len(known) == 0 {
+		return model.SubtitleJob{}, errors.New("任务字幕没有说话人信息")
+	}
+	speakers := make(map[string]string, len(known))
+	for id, name := range record.Speakers {
+		if known[id] {
+			speakers[id] = name
+		}
+	}
+	for id, name := range names {
+		if !known[id] {
+			return model.SubtitleJob{}, fmt.Errorf("说话人 %s 不存在", id)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			delete(speakers, id)
+			continue
+		}
+		if len([]rune(name)) > 40 {
+			return model.SubtitleJob{}, fmt.Errorf("说话人名称过长: %s", name)
+		}
+		speakers[id] = name
+	}
+	if err := r.data.Update(job.ID, func(record *jobdata.Record) {
+		record.Speakers = speakers
+	}); err != nil {
+		return model.SubtitleJob{}, err
+	}
+	details := "说话人名称已更新"
+	paths := db.JobOutputPaths{
+		SourcePath:  job.SourceSubtitlePath,
+		PrimaryPath: job.OutputSubtitlePath,
+		SRTPath:     job.OutputSRTPath,
+		ASSPath:     job.OutputASSPath,
+	}
+	if len(record.Translations) == len(record.Blocks) {
+		settings, err := r.repo.GetSettings(ctx)
+		if err != nil {
+			return model.SubtitleJob{}, err
+		}
+		outputs, err := r.renderOutputs(job, settings, record.Blocks, record.Translations)
+		if err != nil {
+			return model.SubtitleJob{}, err
+		}
+		paths.PrimaryPath = outputs.PrimaryPath
+		paths.SRTPath = outputs.SRTPath
+		paths.ASSPath = outputs.ASSPath
+		details += "，双语字幕已重新生成"
+	}
+	if err := r.updateProgress(ctx, job.ID, job.Status, job.CurrentStage, job.Progress, details, paths, job.ErrorMessage); err != nil {
+		return model.SubtitleJob{}, err
+	}
+	if r.logger != nil {
+		lines := make([]string, 0, len(known))
+		for _, speaker := range subtitle.SummarizeSpeakers(record.Blocks, speakers) {
+			lines = append(lines, fmt.Sprintf("%s → %s", speaker.ID, speaker.Name))
+		}
+		_ = r.logger.Append(job.ID, "info", "review", details, strings.Join(lines, "\n"))
+	}
+	return r.repo.GetJob(ctx, job.ID)
+}
+
+func (r *Runner) SyncReview(ctx context.Context, job model.SubtitleJob, format string, content string) (string, error) {
+	if job.Status != "completed" && job.Status != "failed" && job.Status != "cancelled" {
+		return "任务仍在执行中，人工修改没有同步到任务数据，稍后会被新生成的字幕覆盖", nil
+	}
+	if r.data == nil {
+		return "", nil
+	}
+	record, err := r.data.Load(job.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(record.Blocks) == 0 || len(record.Translations) != len(record.Blocks) {
+		return "", nil
+	}
+	settings, err := r.repo.GetSettings(ctx)
+	if err != nil {
+		return "", err
+	}
+	merged, err := subtitle.MergeReview(subtitle.ReviewInput{
+		Format:       format,
+		Content:      content,
+		Blocks:       record.Blocks,
+		Translations: record.Translations,
+		Speakers:     record.Speakers,
+		Layout:       LayoutOptions(settings),
+		Order:        settings.BilingualLayout,
+	})
+	if err != nil {
+		drift := fmt.Sprintf("%s：%v", strings.ToUpper(format), err)
+		if err := r.data.Update(job.ID, func(record *jobdata.Record) {
+			record.ReviewDrift = drift
+		}); err != nil {
+			return "", err
+		}
+		warning := fmt.Sprintf("人工修改未能同步到任务数据（%s），调整时间轴等依赖任务数据的操作会被拒绝，直到字幕条数恢复一致或重新运行任务", drift)
+		if r.logger != nil {
+			_ = r.logger.Append(job.ID, "warn", "review", warning, "")
+		}
+		return warning, nil
+	}
+	if err := r.data.Update(job.ID, func(record *jobdata.Record) {
+		record.Blocks = merged.Blocks
+		record.Translations = merged.Translations
+		record.ReviewDrift = ""
+	}); err != nil {
+		return "", err
+	}
+	details := fmt.Sprintf("人工修改已同步到任务数据，共 %d 条字幕有改动", merged.Edited)
+	if merged.Edited > 0 {
+		if path := strings.TrimSpace(job.SourceSubtitlePath); path != "" {
+			if _, err := media.WriteTextFile(path, subtitle.RenderSRT(merged.Blocks)); err != nil {
+				return "", err
+			}
+		}
+		other, otherPath := "ass", strings.TrimSpace(job.OutputASSPath)
+		if format == "ass" {
+			other, otherPath = "srt", strings.TrimSpace(job.OutputSRTPath)
+		}
+		if otherPath != "" {
+			rendered, err := r.renderBilingual(job.ID, settings, merged.Blocks, merged.Translations, other)
+			if err != nil {
+				return "", err
+			}
+			if _, err := media.WriteTextFile(otherPath, rendered); err != nil {
+				return "", err
+			}
+			details += fmt.Sprintf("，%s 字幕已同步重新生成", strings.ToUpper(other))
+		}
+	}
+	if r.logger != nil {
+		_ = r.logger.Append(job.ID, "info", "review", details, "")
+	}
+	return "", nil
+}
+
+func reviewDriftError(record jobdata.Record) error {
+	if record.ReviewDrift == "" {
+		return nil
+	}
+	return fmt.Errorf("输出字幕的人工修改没有同步到任务数据（%s），请把字幕条数恢复一致后重新保存，或重新运行任务", record.ReviewDrift)
+}
+
+func (r *Runner) saveJobData(jobID string, apply func(record *jobdata.Record)) {
+	if r.data == nil {
+		return
+	}
+	if err := r.data.Update(jobID, apply); err != nil {
+		log.Printf("save job data for %s failed: %v", jobID, err)
+	}
+}
+
+func (r *Runner) logQASummary(jobID string, blocks []subtitle.Block, translations []string, settings model.AppSettings, job model.SubtitleJob) {
+	if r.logger == nil {
+		return
+	}
+	blocks, translations = subtitle.ApplyLayout(blocks, translations, LayoutOptions(settings))
+	options := QAOptions(settings, job)
+	if r.data != nil {
+		if record, err := r.data.Load(jobID); err == nil {
+			options.OCRConfidence = record.OCRConfidence
+			options.FusionFlags = record.FusionFlags
+		}
+	}
+	report := subtitle.CheckQA(blocks, translations, options)
+	if report.IssueCount == 0 {
+		_ = r.logger.Append(jobID, "info", "qa", "字幕质检通过，未发现问题", "")
+		return
+	}
+	_ = r.logger.Append(jobID, "warn", "qa", fmt.Sprintf("字幕质检发现 %d 个问题（其中 %d 个错误），涉及 %d 条字幕", report.IssueCount, report.ErrorCount, len(report.Issues)), "")
+}
+
+func QAOptions(settings model.AppSettings, job model.SubtitleJob) subtitle.QAOptions {
+	return subtitle.QAOptions{
+		MaxCharsPerSecond: settings.QA.MaxCharsPerSecond,
+		MaxLineLength:     settings.QA.MaxLineLength,
+		MaxLinesPerSide:   settings.QA.MaxLinesPerSide,
+		TargetLanguage:    job.TargetLanguage,
+		MinOCRConfidence:  settings.QA.MinOCRConfidence,
+		SourceIsTarget:    media.SameLanguage(firstNonAuto(job.SourceLanguage, job.DetectedLanguage), job.TargetLanguage),
+	}
+}
+
+func LayoutOptions(settings model.AppSettings) subtitle.LayoutOptions {
+	return subtitle.LayoutOptions{
+		WrapEnabled:       settings.Layout.WrapEnabled,
+		MaxLineWidth:      settings.Layout.MaxLineWidth,
+		ExtendTiming:      settings.Layout.ExtendTiming,
+		MaxCharsPerSecond: settings.Layout.MaxCharsPerSecond,
+		MinGap:            time.Duration(settings.Layout.MinGapMS) * time.Millisecond,
+		MaxExtension:      time.Duration(settings.Layout.MaxExtensionMS) * time.Millisecond,
+	}
+}
+
+func TimelineOptions(settings model.AppSettings) ocrprovider.TimelineOptions {
+	return ocrprovider.TimelineOptions{
+		MinDuration:         time.Duration(settings.OCRTimeline.MinDurationMS) * time.Millisecond,
+		MaxJoinGap:          time.Duration(settings.OCRTimeline.MaxJoinGapMS) * time.Millisecond,
+		MinTextLength:       settings.OCRTimeline.MinTextLength,
+		StableFrames:        settings.OCRTimeline.StableFrames,
+		ConfidenceFloor:     settings.OCRTimeline.ConfidenceFloor,
+		SimilarityThreshold: settings.OCRTimeline.SimilarityThreshold,
+		MaxEditDistance:     settings.OCRTimeline.MaxEditDistance,
+	}
+}
+
+func (r *Runner) recordParseWarnings(jobID string, warnings []subtitle.ParseWarning) {
+	r.saveJobData(jobID, func(record *jobdata.Record) {
+		record.ParseWarnings = append([]subtitle.ParseWarning{}, warnings...)
+	})
+	if r.logger == nil || len(warnings) == 0 {
+		return
+	}
+	details := make([]string, 0, len(warnings))
+	for index, warning := range warnings {
+		if index >= maxLoggedParseWarnings {
+			details = append(details, fmt.Sprintf("……其余 %d 条警告请通过 API 查看", len(warnings)-index))
+			break
+		}
+		details = append(details, fmt.Sprintf("第 %d 行: %s", warning.Line, warning.Reason))
+	}
+	_ = r.logger.Append(jobID, "warn", "parse_subtitle", fmt.Sprintf("SRT 解析产生 %d 条警告，部分内容已被修正或跳过", len(warnings)), strings.Join(details, "\n"))
 }
 
 func (r *Runner) renderOutputs(job model.SubtitleJob, settings model.AppSettings, blocks []subtitle.Block, translations []string) (db.JobOutputPaths, error) {
@@ -322,22 +1623,18 @@ func (r *Runner) renderOutputs(job model.SubtitleJob, settings model.AppSettings
 	}
 	paths := db.JobOutputPaths{}
 	for _, format := range requested {
+		content, err := r.renderBilingual(job.ID, settings, blocks, translations, format)
+		if err != nil {
+			return db.JobOutputPaths{}, err
+		}
 		switch format {
 		case "srt":
-			content, err := subtitle.RenderBilingualSRT(blocks, translations, settings.BilingualLayout)
-			if err != nil {
-				return db.JobOutputPaths{}, err
-			}
 			path, err := media.WriteBilingualSRT(job.MediaPath, settings.MediaPaths, r.cfg.SubtitleOutputPath, job.TargetLanguage, content)
 			if err != nil {
 				return db.JobOutputPaths{}, err
 			}
 			paths.SRTPath = path
 		case "ass":
-			content, err := subtitle.RenderBilingualASS(blocks, translations, settings.BilingualLayout)
-			if err != nil {
-				return db.JobOutputPaths{}, err
-			}
 			path, err := media.WriteBilingualASS(job.MediaPath, settings.MediaPaths, r.cfg.SubtitleOutputPath, job.TargetLanguage, content)
 			if err != nil {
 				return db.JobOutputPaths{}, err
@@ -349,6 +1646,14 @@ func (r *Runner) renderOutputs(job model.SubtitleJob, settings model.AppSettings
 	return paths, nil
 }
 
+func (r *Runner) renderBilingual(jobID string, settings model.AppSettings, blocks []subtitle.Block, translations []string, format string) (string, error) {
+	blocks, translations = subtitle.ApplyLayout(subtitle.NameSpeakers(blocks, r.speakerNames(jobID)), translations, LayoutOptions(settings))
+	if format == "ass" {
+		return subtitle.RenderBilingualASS(blocks, translations, settings.BilingualLayout)
+	}
+	return subtitle.RenderBilingualSRT(blocks, translations, settings.BilingualLayout)
+}
+
 func (r *Runner) markCancelled(jobID string, job model.SubtitleJob, paths db.JobOutputPaths) error {
 	if paths.SourcePath == "" {
 		paths.SourcePath = job.SourceSubtitlePath
@@ -382,7 +1687,7 @@ func (r *Runner) updateProgress(ctx context.Context, jobID string, status string
 	return nil
 }
 
-func buildTranslationPrompt(settings model.AppSettings) string {
+func buildTranslationPrompt(settings model.AppSettings, sourceLanguage string) string {
 	sections := []string{strings.TrimSpace(settings.TranslationPrompt)}
 	switch strings.TrimSpace(settings.TranslationStyle) {
 	case "faithful":
@@ -398,7 +1703,7 @@ func buildTranslationPrompt(settings model.AppSettings) string {
 			sections = append(sections, "自定义风格要求："+strings.TrimSpace(settings.CustomStylePrompt))
 		}
 	}
-	glossary := strings.TrimSpace(settings.Glossary)
+	glossary := glossaryForLanguage(settings.Glossary, sourceLanguage)
 	if glossary != "" {
 		sections = append(sections, "术语表要求（若命中请优先遵守）：\n"+glossary)
 	}
@@ -419,6 +1724,27 @@ func buildTranslationPrompt(settings model.AppSettings) string {
 	return strings.Join(result, "\n\n")
 }
 
+func glossaryForLanguage(glossary string, sourceLanguage string) string {
+	language := media.NormalizeLanguage(sourceLanguage)
+	lines := strings.Split(strings.TrimSpace(glossary), "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			if end := strings.Index(line, "]"); end > 1 && media.IsKnownLanguage(line[1:end]) {
+				if language != "" && media.NormalizeLanguage(line[1:end]) != language {
+					continue
+				}
+				line = strings.TrimSpace(line[end+1:])
+			}
+		}
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
 func normalizeFormats(values []string) []string {
 	result := make([]string, 0, len(values))
 	seen := map[string]struct{}{}
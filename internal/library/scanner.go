@@ -8,19 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gayhub/4subs/internal/media"
 	"github.com/gayhub/4subs/internal/model"
 )
 
-var supportedVideoExt = map[string]struct{}{
-	".mp4": {},
-	".mkv": {},
-	".avi": {},
-	".mov": {},
-	".wmv": {},
-	".m4v": {},
-	".ts":  {},
-}
-
 func ScanMediaPaths(paths []string) ([]model.MediaAsset, error) {
 	assets := make([]model.MediaAsset, 0)
 	for _, root := range paths {
@@ -38,8 +29,7 @@ func ScanMediaPaths(paths []string) ([]model.MediaAsset, error) {
 			if entry.IsDir() {
 				return nil
 			}
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			if _, ok := supportedVideoExt[ext]; !ok {
+			if !media.IsVideoFile(entry.Name()) {
 				return nil
 			}
 			info, err := entry.Info()
@@ -0,0 +1,239 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gayhub/4subs/internal/audiosync"
+)
+
+const (
+	chunkAnalysisFrame = 50 * time.Millisecond
+	chunkMaxSearch     = 60 * time.Second
+)
+
+type AudioChunk struct {
+	Index     int
+	Path      string
+	Start     time.Duration
+	End       time.Duration
+	KeepFrom  time.Duration
+	KeepUntil time.Duration
+}
+
+type ChunkOptions struct {
+	ChunkDuration time.Duration
+	Overlap       time.Duration
+}
+
+type wavInfo struct {
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	blockAlign    int
+	dataOffset    int64
+	dataSize      int64
+}
+
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{ChunkDuration: 10 * time.Minute, Overlap: 2 * time.Second}
+}
+
+func (c AudioChunk) Keeps(at time.Duration) bool {
+	return at >= c.KeepFrom && (c.KeepUntil <= 0 || at < c.KeepUntil)
+}
+
+func SplitAudio(wavPath string, options ChunkOptions) ([]AudioChunk, time.Duration, error) {
+	defaults := DefaultChunkOptions()
+	if options.ChunkDuration <= 0 {
+		options.ChunkDuration = defaults.ChunkDuration
+	}
+	if options.Overlap < 0 || options.Overlap >= options.ChunkDuration/4 {
+		options.Overlap = defaults.Overlap
+	}
+	file, err := os.Open(wavPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = file.Close() }()
+	info, err := readWAVInfo(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	bytesPerSecond := int64(info.sampleRate * info.blockAlign)
+	total := time.Duration(float64(info.dataSize) / float64(bytesPerSecond) * float64(time.Second))
+	if total <= options.ChunkDuration+options.Overlap {
+		return []AudioChunk{{Index: 1, Path: wavPath, Start: 0, End: total}}, total, nil
+	}
+	if info.channels != 1 || info.bitsPerSample != 16 {
+		return nil, total, errors.New("音频分段仅支持 16 位单声道 WAV")
+	}
+	activity, err := audiosync.DetectSpeech(io.NewSectionReader(file, info.dataOffset, info.dataSize), info.sampleRate, chunkAnalysisFrame)
+	if err != nil {
+		return nil, total, err
+	}
+	cuts := planChunkCuts(activity.Speech, chunkAnalysisFrame, total, options.ChunkDuration)
+	chunkDir := strings.TrimSuffix(wavPath, filepath.Ext(wavPath)) + ".chunks"
+	if err := os.RemoveAll(chunkDir); err != nil {
+		return nil, total, err
+	}
+	if err := os.MkdirAll(chunkDir, 0o755); err != nil {
+		return nil, total, err
+	}
+	chunks := make([]AudioChunk, 0, len(cuts)+1)
+	keepFrom := time.Duration(0)
+	for index := 0; index <= len(cuts); index++ {
+		chunk := AudioChunk{Index: index + 1, KeepFrom: keepFrom}
+		if index < len(cuts) {
+			chunk.KeepUntil = cuts[index]
+			chunk.End = cuts[index] + options.Overlap
+		} else {
+			chunk.End = total
+		}
+		chunk.Start = keepFrom - options.Overlap
+		if chunk.Start < 0 {
+			chunk.Start = 0
+		}
+		if chunk.End > total {
+			chunk.End = total
+		}
+		chunk.Path = filepath.Join(chunkDir, fmt.Sprintf("%04d.wav", chunk.Index))
+		startByte := alignedOffset(chunk.Start, bytesPerSecond, info.blockAlign)
+		endByte := alignedOffset(chunk.End, bytesPerSecond, info.blockAlign)
+		if endByte > info.dataSize {
+			endByte = info.dataSize
+		}
+		if err := writeWAVSlice(chunk.Path, file, info, startByte, endByte-startByte); err != nil {
+			return nil, total, err
+		}
+		chunks = append(chunks, chunk)
+		keepFrom = chunk.KeepUntil
+	}
+	return chunks, total, nil
+}
+
+func planChunkCuts(speech []bool, frame time.Duration, total time.Duration, chunkDuration time.Duration) []time.Duration {
+	chunkFrames := int(chunkDuration / frame)
+	search := chunkDuration / 4
+	if search > chunkMaxSearch {
+		search = chunkMaxSearch
+	}
+	searchFrames := int(search / frame)
+	totalFrames := int(total / frame)
+	cuts := make([]time.Duration, 0, totalFrames/chunkFrames+1)
+	for start := 0; totalFrames-start > chunkFrames; {
+		target := start + chunkFrames
+		if target > len(speech) {
+			target = len(speech)
+		}
+		cut := target
+		bestRun := 0
+		for index := target - searchFrames; index < target; {
+			if index < 0 || speech[index] {
+				index++
+				continue
+			}
+			end := index
+			for end < target && !speech[end] {
+				end++
+			}
+			if end-index >= bestRun {
+				bestRun = end - index
+				cut = index + (end-index)/2
+			}
+			index = end
+		}
+		if cut <= start {
+			cut = target
+		}
+		cuts = append(cuts, time.Duration(cut)*frame)
+		start = cut
+	}
+	return cuts
+}
+
+func alignedOffset(at time.Duration, bytesPerSecond int64, blockAlign int) int64 {
+	offset := int64(at.Seconds() * float64(bytesPerSecond))
+	return offset - offset%int64(blockAlign)
+}
+
+func readWAVInfo(file *os.File) (wavInfo, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return wavInfo{}, fmt.Errorf("WAV 文件头读取失败: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return wavInfo{}, errors.New("不是有效的 WAV 文件")
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return wavInfo{}, err
+	}
+	var info wavInfo
+	offset := int64(12)
+	chunkHeader := make([]byte, 8)
+	for {
+		if _, err := file.ReadAt(chunkHeader, offset); err != nil {
+			return wavInfo{}, errors.New("WAV 文件缺少音频数据")
+		}
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+		body := offset + 8
+		switch string(chunkHeader[0:4]) {
+		case "fmt ":
+			format := make([]byte, 16)
+			if _, err := file.ReadAt(format, body); err != nil {
+				return wavInfo{}, fmt.Errorf("WAV 格式信息读取失败: %w", err)
+			}
+			info.channels = int(binary.LittleEndian.Uint16(format[2:4]))
+			info.sampleRate = int(binary.LittleEndian.Uint32(format[4:8]))
+			info.blockAlign = int(binary.LittleEndian.Uint16(format[12:14]))
+			info.bitsPerSample = int(binary.LittleEndian.Uint16(format[14:16]))
+		case "data":
+			if info.sampleRate <= 0 || info.blockAlign <= 0 {
+				return wavInfo{}, errors.New("WAV 格式信息无效")
+			}
+			info.dataOffset = body
+			info.dataSize = size
+			if size == 0xFFFFFFFF || body+size > stat.Size() {
+				info.dataSize = stat.Size() - body
+			}
+			return info, nil
+		}
+		offset = body + size + size%2
+	}
+}
+
+func writeWAVSlice(path string, source io.ReaderAt, info wavInfo, start int64, length int64) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+length))
+	copy(header[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(info.channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(info.sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(info.sampleRate*info.blockAlign))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(info.blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(info.bitsPerSample))
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(length))
+	if _, err := output.Write(header); err != nil {
+		_ = output.Close()
+		return err
+	}
+	if _, err := io.Copy(output, io.NewSectionReader(source, info.dataOffset+start, length)); err != nil {
+		_ = output.Close()
+		return err
+	}
+	return output.Close()
+}
@@ -0,0 +1,125 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	AudioCodecWAV  = "wav"
+	AudioCodecFLAC = "flac"
+	AudioCodecOpus = "opus"
+	AudioCodecMP3  = "mp3"
+)
+
+type AudioEncoding struct {
+	Codec   string
+	Bitrate string
+}
+
+type audioCodecSpec struct {
+	extension      string
+	encoder        string
+	defaultBitrate string
+}
+
+var audioCodecs = map[string]audioCodecSpec{
+	AudioCodecWAV:  {extension: ".wav", encoder: "pcm_s16le"},
+	AudioCodecFLAC: {extension: ".flac", encoder: "flac"},
+	AudioCodecOpus: {extension: ".ogg", encoder: "libopus", defaultBitrate: "32k"},
+	AudioCodecMP3:  {extension: ".mp3", encoder: "libmp3lame", defaultBitrate: "64k"},
+}
+
+var audioContentTypes = map[string]string{
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".webm": "audio/webm",
+}
+
+func NormalizeAudioCodec(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "ogg", "libopus":
+		value = AudioCodecOpus
+	case "libmp3lame", "mpeg":
+		value = AudioCodecMP3
+	case "pcm", "pcm_s16le":
+		value = AudioCodecWAV
+	}
+	if _, ok := audioCodecs[value]; !ok {
+		return AudioCodecWAV
+	}
+	return value
+}
+
+func (e AudioEncoding) Normalize() AudioEncoding {
+	e.Codec = NormalizeAudioCodec(e.Codec)
+	spec := audioCodecs[e.Codec]
+	e.Bitrate = strings.TrimSpace(e.Bitrate)
+	if spec.defaultBitrate == "" {
+		e.Bitrate = ""
+	} else if e.Bitrate == "" {
+		e.Bitrate = spec.defaultBitrate
+	}
+	return e
+}
+
+func (e AudioEncoding) Describe() string {
+	e = e.Normalize()
+	if e.Bitrate == "" {
+		return strings.ToUpper(e.Codec)
+	}
+	return fmt.Sprintf("%s %sbps", strings.ToUpper(e.Codec), e.Bitrate)
+}
+
+func AudioContentType(path string) string {
+	if contentType, ok := audioContentTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func EncodeAudioChunks(ctx context.Context, ffmpegBin string, chunks []AudioChunk, encoding AudioEncoding) ([]AudioChunk, error) {
+	encoding = encoding.Normalize()
+	if encoding.Codec == AudioCodecWAV {
+		return chunks, nil
+	}
+	spec := audioCodecs[encoding.Codec]
+	encoded := make([]AudioChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		outputPath := strings.TrimSuffix(chunk.Path, filepath.Ext(chunk.Path)) + ".asr" + spec.extension
+		args := []string{"-y", "-v", "error", "-i", chunk.Path, "-vn", "-ac", "1", "-c:a", spec.encoder}
+		if encoding.Bitrate != "" {
+			args = append(args, "-b:a", encoding.Bitrate)
+		}
+		if encoding.Codec == AudioCodecOpus {
+			args = append(args, "-application", "voip")
+		}
+		args = append(args, outputPath)
+		output, err := exec.CommandContext(ctx, ffmpegBin, args...).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("音频编码为 %s 失败: %w: %s", encoding.Describe(), err, strings.TrimSpace(string(output)))
+		}
+		chunk.Path = outputPath
+		encoded = append(encoded, chunk)
+	}
+	return encoded, nil
+}
+
+func AudioFileSize(paths ...string) int64 {
+	var total int64
+	for _, path := range paths {
+		if stat, err := os.Stat(path); err == nil {
+			total += stat.Size()
+		}
+	}
+	return total
+}
@@ -0,0 +1,162 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minBitmapPacketSize   = 64
+	minBitmapEventLength  = 100 * time.Millisecond
+	defaultBitmapDuration = 4 * time.Second
+	bitmapSeekPreroll     = 3 * time.Second
+)
+
+var bitmapSubtitleCodecs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"pgssub":            true,
+	"dvd_subtitle":      true,
+	"dvdsub":            true,
+	"dvb_subtitle":      true,
+	"dvbsub":            true,
+	"xsub":              true,
+}
+
+type BitmapEvent struct {
+	Index int
+	Start time.Duration
+	End   time.Duration
+	Path  string
+}
+
+type packetProbeOutput struct {
+	Packets []struct {
+		PTSTime      string `json:"pts_time"`
+		DurationTime string `json:"duration_time"`
+		Size         string `json:"size"`
+	} `json:"packets"`
+}
+
+type bitmapPacket struct {
+	pts      time.Duration
+	duration time.Duration
+	size     int
+}
+
+func ProbeBitmapEvents(ctx context.Context, ffprobeBin string, videoPath string, stream Stream) ([]BitmapEvent, error) {
+	command := exec.CommandContext(ctx, ffprobeBin, "-v", "error", "-select_streams", strconv.Itoa(stream.Index),
+		"-show_entries", "packet=pts_time,duration_time,size", "-print_format", "json", videoPath)
+	output, err := command.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("图形字幕事件探测失败: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("图形字幕事件探测失败: %w", err)
+	}
+	var payload packetProbeOutput
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return nil, fmt.Errorf("图形字幕事件解析失败: %w", err)
+	}
+	packets := make([]bitmapPacket, 0, len(payload.Packets))
+	for _, raw := range payload.Packets {
+		pts, ok := parseSeconds(raw.PTSTime)
+		if !ok {
+			continue
+		}
+		duration, _ := parseSeconds(raw.DurationTime)
+		size, _ := strconv.Atoi(strings.TrimSpace(raw.Size))
+		packets = append(packets, bitmapPacket{pts: pts, duration: duration, size: size})
+	}
+	return buildBitmapEvents(packets), nil
+}
+
+func buildBitmapEvents(packets []bitmapPacket) []BitmapEvent {
+	sort.SliceStable(packets, func(i, j int) bool {
+		return packets[i].pts < packets[j].pts
+	})
+	events := make([]BitmapEvent, 0, len(packets))
+	for index, packet := range packets {
+		if packet.size < minBitmapPacketSize {
+			continue
+		}
+		end := packet.pts + defaultBitmapDuration
+		if packet.duration > 0 {
+			end = packet.pts + packet.duration
+		}
+		if index+1 < len(packets) {
+			next := packets[index+1].pts
+			if packet.duration <= 0 || next < end {
+				end = next
+			}
+		}
+		if end-packet.pts < minBitmapEventLength {
+			continue
+		}
+		events = append(events, BitmapEvent{Index: len(events) + 1, Start: packet.pts, End: end})
+	}
+	return events
+}
+
+func RenderBitmapEvents(ctx context.Context, ffmpegBin string, videoPath string, workDir string, stream Stream, events []BitmapEvent) ([]BitmapEvent, error) {
+	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	imageDir := filepath.Join(workDir, safeName(baseName)+".bitmap.frames")
+	if err := os.MkdirAll(imageDir, 0o755); err != nil {
+		return nil, err
+	}
+	filter := fmt.Sprintf("[0:v]drawbox=x=0:y=0:w=iw:h=ih:color=black:t=fill[canvas];[canvas][0:%d]overlay=eof_action=pass[out]", stream.Index)
+	rendered := make([]BitmapEvent, 0, len(events))
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		middle := event.Start + (event.End-event.Start)/2
+		seek := event.Start - bitmapSeekPreroll
+		if seek < 0 {
+			seek = 0
+		}
+		outputPath := filepath.Join(imageDir, fmt.Sprintf("%06d.png", event.Index))
+		command := exec.CommandContext(ctx, ffmpegBin, "-y",
+			"-ss", formatSeconds(seek), "-i", videoPath,
+			"-filter_complex", filter, "-map", "[out]",
+			"-ss", formatSeconds(middle-seek), "-frames:v", "1", outputPath)
+		if output, err := command.CombinedOutput(); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("图形字幕渲染失败: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		if _, err := os.Stat(outputPath); err != nil {
+			continue
+		}
+		event.Path = outputPath
+		rendered = append(rendered, event)
+	}
+	if len(rendered) == 0 {
+		return nil, fmt.Errorf("图形字幕渲染完成，但未生成可用图片")
+	}
+	return rendered, nil
+}
+
+func parseSeconds(raw string) (time.Duration, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "N/A" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(value * float64(time.Second)), true
+}
+
+func formatSeconds(value time.Duration) string {
+	return strconv.FormatFloat(value.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,256 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OCRSamplingInterval = "interval"
+	OCRSamplingChange   = "change"
+)
+
+const (
+	changeFrameWidth       = 960
+	changeAnalysisStep     = 4
+	changeMaxFrameRate     = 30.0
+	changeDefaultFrameRate = 25.0
+	changeBrightLuma       = 170
+	changeEdgeDelta        = 40
+	changeMinTextRatio     = 0.002
+	changeDiffRatio        = 0.25
+	changeMinDiffPixels    = 12
+	changeMinSegmentFrames = 3
+	changeSettleFrames     = 2
+)
+
+type videoProbeOutput struct {
+	Streams []struct {
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+	} `json:"streams"`
+}
+
+type changeSegment struct {
+	start          int
+	reference      []bool
+	referenceCount int
+	textFrames     int
+	frames         int
+	representative []byte
+}
+
+func ExtractSubtitleChangeFrames(ctx context.Context, ffmpegBin string, ffprobeBin string, videoPath string, workDir string, region CropRegion) ([]SubtitleFrame, error) {
+	region = region.Normalize()
+	width, height, rate, err := probeVideoGeometry(ctx, ffprobeBin, videoPath)
+	if err != nil {
+		return nil, err
+	}
+	cropHeight := float64(height) * region.HeightPercent / 100
+	frameHeight := int(cropHeight*changeFrameWidth/float64(width)) / 2 * 2
+	if frameHeight < changeAnalysisStep*2 {
+		frameHeight = changeAnalysisStep * 2
+	}
+	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	frameDir := filepath.Join(workDir, safeName(baseName)+region.dirSuffix()+".ocr.segments")
+	if err := os.MkdirAll(frameDir, 0o755); err != nil {
+		return nil, err
+	}
+	filter := fmt.Sprintf("fps=%.5f,%s,scale=%d:%d,format=gray", rate, region.cropFilter(), changeFrameWidth, frameHeight)
+	command := exec.CommandContext(ctx, ffmpegBin, "-v", "error", "-i", videoPath, "-map", "0:v:0", "-vf", filter, "-f", "rawvideo", "-pix_fmt", "gray", "pipe:1")
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr strings.Builder
+	command.Stderr = &stderr
+	if err := command.Start(); err != nil {
+		return nil, err
+	}
+	frames, scanErr := scanChangeSegments(bufio.NewReaderSize(stdout, 1<<20), changeFrameWidth, frameHeight, rate, frameDir)
+	if scanErr != nil {
+		_ = command.Process.Kill()
+		_ = command.Wait()
+		return nil, scanErr
+	}
+	if err := command.Wait(); err != nil {
+		return nil, fmt.Errorf("OCR 变化检测抽帧失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("OCR 变化检测完成，但未发现疑似字幕片段")
+	}
+	return frames, nil
+}
+
+func scanChangeSegments(reader io.Reader, width int, height int, rate float64, frameDir string) ([]SubtitleFrame, error) {
+	frameSize := width * height
+	buffer := make([]byte, frameSize)
+	frames := make([]SubtitleFrame, 0, 256)
+	var segment *changeSegment
+	frameTime := func(index int) time.Duration {
+		return time.Duration(float64(index) / rate * float64(time.Second))
+	}
+	flush := func(end int) error {
+		if segment == nil {
+			return nil
+		}
+		current := segment
+		segment = nil
+		if current.frames < changeMinSegmentFrames || current.textFrames*2 < current.frames {
+			return nil
+		}
+		path := filepath.Join(frameDir, fmt.Sprintf("%06d.png", len(frames)+1))
+		if err := writeGrayPNG(path, current.representative, width, height); err != nil {
+			return err
+		}
+		frames = append(frames, SubtitleFrame{
+			Index: len(frames) + 1,
+			At:    frameTime(current.start),
+			End:   frameTime(end),
+			Path:  path,
+		})
+		return nil
+	}
+	for index := 0; ; index++ {
+		if _, err := io.ReadFull(reader, buffer); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return frames, flush(index)
+			}
+			return nil, err
+		}
+		mask, count := textMask(buffer, width, height)
+		hasText := float64(count) >= changeMinTextRatio*float64(len(mask))
+		if segment != nil && maskChanged(segment.reference, segment.referenceCount, mask, count) {
+			if err := flush(index); err != nil {
+				return nil, err
+			}
+		}
+		if segment == nil {
+			segment = &changeSegment{start: index, reference: mask, referenceCount: count}
+		}
+		segment.frames++
+		if hasText {
+			segment.textFrames++
+		}
+		if segment.frames == 1 || segment.frames == changeSettleFrames+1 {
+			segment.representative = append(segment.representative[:0], buffer...)
+		}
+	}
+}
+
+func textMask(pixels []byte, width int, height int) ([]bool, int) {
+	columns := width / changeAnalysisStep
+	rows := height / changeAnalysisStep
+	mask := make([]bool, columns*rows)
+	count := 0
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			brightest, darkest := 0, 255
+			for y := row * changeAnalysisStep; y < (row+1)*changeAnalysisStep; y++ {
+				offset := y*width + column*changeAnalysisStep
+				for _, value := range pixels[offset : offset+changeAnalysisStep] {
+					if int(value) > brightest {
+						brightest = int(value)
+					}
+					if int(value) < darkest {
+						darkest = int(value)
+					}
+				}
+			}
+			if brightest >= changeBrightLuma && brightest-darkest >= changeEdgeDelta {
+				mask[row*columns+column] = true
+				count++
+			}
+		}
+	}
+	return mask, count
+}
+
+func maskChanged(reference []bool, referenceCount int, mask []bool, count int) bool {
+	if len(reference) != len(mask) {
+		return true
+	}
+	different := 0
+	for index := range mask {
+		if mask[index] != reference[index] {
+			different++
+		}
+	}
+	union := referenceCount
+	if count > union {
+		union = count
+	}
+	return different >= changeMinDiffPixels && float64(different) >= changeDiffRatio*float64(union)
+}
+
+func writeGrayPNG(path string, pixels []byte, width int, height int) error {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	copy(img.Pix, pixels)
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func probeVideoGeometry(ctx context.Context, ffprobeBin string, videoPath string) (int, int, float64, error) {
+	command := exec.CommandContext(ctx, ffprobeBin, "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height,avg_frame_rate,r_frame_rate", "-print_format", "json", videoPath)
+	output, err := command.Output()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("视频参数探测失败: %w", err)
+	}
+	var payload videoProbeOutput
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return 0, 0, 0, fmt.Errorf("视频参数解析失败: %w", err)
+	}
+	if len(payload.Streams) == 0 || payload.Streams[0].Width <= 0 || payload.Streams[0].Height <= 0 {
+		return 0, 0, 0, errors.New("未找到可用的视频流")
+	}
+	stream := payload.Streams[0]
+	rate, ok := parseFrameRate(stream.AvgFrameRate)
+	if !ok {
+		rate, ok = parseFrameRate(stream.RFrameRate)
+	}
+	if !ok {
+		rate = changeDefaultFrameRate
+	}
+	if rate > changeMaxFrameRate {
+		rate = changeMaxFrameRate
+	}
+	return stream.Width, stream.Height, rate, nil
+}
+
+func parseFrameRate(raw string) (float64, bool) {
+	numerator, denominator, found := strings.Cut(strings.TrimSpace(raw), "/")
+	value, err := strconv.ParseFloat(numerator, 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	if found {
+		divisor, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || divisor <= 0 {
+			return 0, false
+		}
+		value /= divisor
+	}
+	return value, value > 0
+}
@@ -0,0 +1,113 @@
+package media
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestScanChangeSegments(t *testing.T) {
+	const width, height = 64, 16
+	frame := func(from int, to int) []byte {
+		pixels := make([]byte, width*height)
+		for y := 0; y < height; y++ {
+			for x := from; x < to; x += 2 {
+				pixels[y*width+x] = 255
+			}
+		}
+		return pixels
+	}
+	blank := frame(0, 0)
+	left := frame(0, 32)
+	flicker := frame(0, 36)
+	right := frame(32, 64)
+	repeat := func(pixels []byte, count int) [][]byte {
+		frames := make([][]byte, count)
+		for index := range frames {
+			frames[index] = pixels
+		}
+		return frames
+	}
+	type span struct {
+		at  time.Duration
+		end time.Duration
+	}
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   []span
+	}{
+		{
+			name:   "blank video has no segments",
+			frames: repeat(blank, 10),
+		},
+		{
+			name:   "cue boundaries land on the changed frame",
+			frames: concatFrames(repeat(blank, 5), repeat(left, 10), repeat(right, 10), repeat(blank, 5)),
+			want:   []span{{500 * time.Millisecond, 1500 * time.Millisecond}, {1500 * time.Millisecond, 2500 * time.Millisecond}},
+		},
+		{
+			name:   "small flicker does not split a cue",
+			frames: concatFrames(repeat(left, 4), repeat(flicker, 2), repeat(left, 4), repeat(blank, 3)),
+			want:   []span{{0, time.Second}},
+		},
+		{
+			name:   "segments shorter than the minimum are dropped",
+			frames: concatFrames(repeat(blank, 3), repeat(left, 2), repeat(blank, 3), repeat(right, 4)),
+			want:   []span{{800 * time.Millisecond, 1200 * time.Millisecond}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stream bytes.Buffer
+			for _, pixels := range test.frames {
+				stream.Write(pixels)
+			}
+			frames, err := scanChangeSegments(&stream, width, height, 10, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(frames) != len(test.want) {
+				t.Fatalf("got %d segments, want %d: %+v", len(frames), len(test.want), frames)
+			}
+			for index, frame := range frames {
+				if frame.Index != index+1 || frame.At != test.want[index].at || frame.End != test.want[index].end {
+					t.Fatalf("segment %d is %+v, want %s-%s", index, frame, test.want[index].at, test.want[index].end)
+				}
+				if _, err := os.Stat(frame.Path); err != nil {
+					t.Fatalf("representative frame not written: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want float64
+		ok   bool
+	}{
+		{raw: "25/1", want: 25, ok: true},
+		{raw: "24000/1001", want: 24000.0 / 1001, ok: true},
+		{raw: "30", want: 30, ok: true},
+		{raw: "0/0"},
+		{raw: "25/0"},
+		{raw: ""},
+	}
+	for _, test := range tests {
+		value, ok := parseFrameRate(test.raw)
+		if ok != test.ok || value != test.want {
+			t.Fatalf("parseFrameRate(%q) = %v, %v, want %v, %v", test.raw, value, ok, test.want, test.ok)
+		}
+	}
+}
+
+func concatFrames(groups ...[][]byte) [][]byte {
+	var frames [][]byte
+	for _, group := range groups {
+		frames = append(frames, group...)
+	}
+	return frames
+}
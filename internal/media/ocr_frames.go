@@ -15,21 +15,17 @@ import (
 type SubtitleFrame struct {
 	Index int
 	At    time.Duration
+	End   time.Duration
 	Path  string
 }
 
-func ExtractSubtitleFrames(ctx context.Context, ffmpegBin string, videoPath string, workDir string, interval time.Duration, cropTopPercent int, cropHeightPercent int) ([]SubtitleFrame, error) {
+func ExtractSubtitleFrames(ctx context.Context, ffmpegBin string, videoPath string, workDir string, interval time.Duration, region CropRegion) ([]SubtitleFrame, error) {
 	if interval <= 0 {
 		interval = time.Second
 	}
-	if cropTopPercent < 0 {
-		cropTopPercent = 72
-	}
-	if cropHeightPercent <= 0 || cropHeightPercent > 100 {
-		cropHeightPercent = 22
-	}
+	region = region.Normalize()
 	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
-	frameDir := filepath.Join(workDir, safeName(baseName)+".ocr.frames")
+	frameDir := filepath.Join(workDir, safeName(baseName)+region.dirSuffix()+".ocr.frames")
 	if err := os.MkdirAll(frameDir, 0o755); err != nil {
 		return nil, err
 	}
@@ -37,9 +33,7 @@ func ExtractSubtitleFrames(ctx context.Context, ffmpegBin string, videoPath stri
 	if fps <= 0 {
 		fps = 1
 	}
-	cropTop := fmt.Sprintf("ih*%.4f", float64(cropTopPercent)/100)
-	cropHeight := fmt.Sprintf("ih*%.4f", float64(cropHeightPercent)/100)
-	filter := fmt.Sprintf("fps=%.5f,crop=iw:%s:0:%s", fps, cropHeight, cropTop)
+	filter := fmt.Sprintf("fps=%.5f,%s", fps, region.cropFilter())
 	outputPattern := filepath.Join(frameDir, "%06d.png")
 	command := exec.CommandContext(ctx, ffmpegBin, "-y", "-i", videoPath, "-vf", filter, outputPattern)
 	output, err := command.CombinedOutput()
@@ -0,0 +1,289 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+type Stream struct {
+	Index           int    `json:"index"`
+	TypeIndex       int    `json:"type_index"`
+	CodecType       string `json:"codec_type"`
+	CodecName       string `json:"codec_name"`
+	Language        string `json:"language,omitempty"`
+	Title           string `json:"title,omitempty"`
+	Default         bool   `json:"default"`
+	Forced          bool   `json:"forced"`
+	HearingImpaired bool   `json:"hearing_impaired"`
+	TextBased       bool   `json:"text_based"`
+	BitmapBased     bool   `json:"bitmap_based"`
+}
+
+type probeOutput struct {
+	Streams []struct {
+		Index       int               `json:"index"`
+		CodecType   string            `json:"codec_type"`
+		CodecName   string            `json:"codec_name"`
+		Tags        map[string]string `json:"tags"`
+		Disposition map[string]int    `json:"disposition"`
+	} `json:"streams"`
+}
+
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+var signsTitleKeywords = []string{"sign", "song", "forced", "karaoke", "commentary", "字幕组信息", "特效"}
+
+var sdhTitleKeywords = []string{"sdh", "hearing", "cc", "closed caption"}
+
+var languageAliases = map[string]string{
+	"eng": "en", "spa": "es", "jpn": "ja", "kor": "ko", "chi": "zh", "zho": "zh",
+	"fre": "fr", "fra": "fr", "ger": "de", "deu": "de", "ita": "it", "por": "pt",
+	"rus": "ru", "ara": "ar", "tha": "th", "vie": "vi", "hin": "hi", "ind": "id",
+	"dut": "nl", "nld": "nl", "pol": "pl", "tur": "tr", "swe": "sv", "nor": "no",
+	"dan": "da", "fin": "fi", "gre": "el", "ell": "el", "heb": "he", "hun": "hu",
+	"cze": "cs", "ces": "cs", "ukr": "uk", "may": "ms", "msa": "ms", "fil": "tl", "tgl": "tl",
+	"english": "en", "spanish": "es", "japanese": "ja", "korean": "ko", "chinese": "zh", "mandarin": "zh",
+	"cantonese": "yue", "french": "fr", "german": "de", "italian": "it", "portuguese": "pt", "russian": "ru",
+	"arabic": "ar", "thai": "th", "vietnamese": "vi", "hindi": "hi", "indonesian": "id", "dutch": "nl",
+	"polish": "pl", "turkish": "tr", "swedish": "sv", "norwegian": "no", "danish": "da", "finnish": "fi",
+	"greek": "el", "hebrew": "he", "hungarian": "hu", "czech": "cs", "ukrainian": "uk", "malay": "ms",
+	"tagalog": "tl",
+}
+
+func ProbeStreams(ctx context.Context, ffprobeBin string, mediaPath string) ([]Stream, error) {
+	command := exec.CommandContext(ctx, ffprobeBin, "-v", "error", "-print_format", "json", "-show_streams", mediaPath)
+	output, err := command.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("媒体流探测失败: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("媒体流探测失败: %w", err)
+	}
+	var payload probeOutput
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return nil, fmt.Errorf("媒体流探测结果解析失败: %w", err)
+	}
+	typeCounts := map[string]int{}
+	streams := make([]Stream, 0, len(payload.Streams))
+	for _, raw := range payload.Streams {
+		stream := Stream{
+			Index:           raw.Index,
+			TypeIndex:       typeCounts[raw.CodecType],
+			CodecType:       raw.CodecType,
+			CodecName:       strings.ToLower(raw.CodecName),
+			Language:        strings.TrimSpace(tagValue(raw.Tags, "language")),
+			Title:           strings.TrimSpace(tagValue(raw.Tags, "title")),
+			Default:         raw.Disposition["default"] == 1,
+			Forced:          raw.Disposition["forced"] == 1,
+			HearingImpaired: raw.Disposition["hearing_impaired"] == 1,
+		}
+		stream.TextBased = stream.CodecType == "subtitle" && textSubtitleCodecs[stream.CodecName]
+		stream.BitmapBased = stream.CodecType == "subtitle" && bitmapSubtitleCodecs[stream.CodecName]
+		typeCounts[raw.CodecType]++
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+func SubtitleStreams(streams []Stream) []Stream {
+	result := make([]Stream, 0, len(streams))
+	for _, stream := range streams {
+		if stream.CodecType == "subtitle" {
+			result = append(result, stream)
+		}
+	}
+	return result
+}
+
+func AudioStreams(streams []Stream) []Stream {
+	result := make([]Stream, 0, len(streams))
+	for _, stream := range streams {
+		if stream.CodecType == "audio" {
+			result = append(result, stream)
+		}
+	}
+	return result
+}
+
+func FindStream(streams []Stream, index int) (Stream, bool) {
+	for _, stream := range streams {
+		if stream.Index == index {
+			return stream, true
+		}
+	}
+	return Stream{}, false
+}
+
+func SelectSubtitleStream(streams []Stream, sourceLanguage string) (Stream, bool) {
+	return selectStream(streams, sourceLanguage, func(stream Stream) bool { return stream.TextBased })
+}
+
+func SelectBitmapSubtitleStream(streams []Stream, sourceLanguage string) (Stream, bool) {
+	return selectStream(streams, sourceLanguage, func(stream Stream) bool { return stream.BitmapBased })
+}
+
+func SelectAudioStream(streams []Stream, sourceLanguage string) (Stream, bool) {
+	return selectStream(streams, sourceLanguage, func(stream Stream) bool { return stream.CodecType == "audio" })
+}
+
+func selectStream(streams []Stream, sourceLanguage string, accept func(Stream) bool) (Stream, bool) {
+	candidates := make([]Stream, 0, len(streams))
+	for _, stream := range streams {
+		if accept(stream) {
+			candidates = append(candidates, stream)
+		}
+	}
+	if len(candidates) == 0 {
+		return Stream{}, false
+	}
+	language := NormalizeLanguage(sourceLanguage)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		left := streamRank(candidates[i], language)
+		right := streamRank(candidates[j], language)
+		for position := range left {
+			if left[position] != right[position] {
+				return left[position] > right[position]
+			}
+		}
+		return candidates[i].Index < candidates[j].Index
+	})
+	return candidates[0], true
+}
+
+func (s Stream) IsSignsOnly() bool {
+	if s.Forced {
+		return true
+	}
+	return containsAny(strings.ToLower(s.Title), signsTitleKeywords)
+}
+
+func (s Stream) IsSDH() bool {
+	if s.HearingImpaired {
+		return true
+	}
+	title := " " + strings.ToLower(s.Title) + " "
+	for _, keyword := range sdhTitleKeywords {
+		if keyword == "cc" {
+			if strings.Contains(title, " cc ") || strings.Contains(title, "(cc)") || strings.Contains(title, "[cc]") {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(title, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Stream) Describe() string {
+	parts := []string{fmt.Sprintf("#%d", s.Index), s.CodecName}
+	if s.Language != "" {
+		parts = append(parts, s.Language)
+	}
+	if s.Title != "" {
+		parts = append(parts, fmt.Sprintf("「%s」", s.Title))
+	}
+	if s.Default {
+		parts = append(parts, "default")
+	}
+	if s.Forced {
+		parts = append(parts, "forced")
+	}
+	if s.IsSDH() {
+		parts = append(parts, "SDH")
+	}
+	return strings.Join(parts, " ")
+}
+
+func NormalizeLanguage(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "auto" || value == "und" {
+		return ""
+	}
+	if index := strings.IndexAny(value, "-_"); index > 0 {
+		value = value[:index]
+	}
+	if alias, ok := languageAliases[value]; ok {
+		return alias
+	}
+	return value
+}
+
+func IsKnownLanguage(value string) bool {
+	return isKnownLanguageCode(NormalizeLanguage(value))
+}
+
+func SameLanguage(left string, right string) bool {
+	base := NormalizeLanguage(left)
+	if base == "" || base != NormalizeLanguage(right) {
+		return false
+	}
+	return base != "zh" || traditionalChinese(left) == traditionalChinese(right)
+}
+
+func traditionalChinese(value string) bool {
+	value = strings.ToLower(value)
+	for _, marker := range []string{"hant", "tw", "hk", "mo", "cht"} {
+		if strings.Contains(value, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func streamRank(stream Stream, language string) [4]int {
+	languageScore := 1
+	streamLanguage := NormalizeLanguage(stream.Language)
+	if language != "" {
+		switch {
+		case streamLanguage == language:
+			languageScore = 2
+		case streamLanguage != "":
+			languageScore = 0
+		}
+	}
+	fullDialogue := 1
+	if stream.IsSignsOnly() {
+		fullDialogue = 0
+	}
+	plain := 1
+	if stream.IsSDH() {
+		plain = 0
+	}
+	preferred := 0
+	if stream.Default {
+		preferred = 1
+	}
+	return [4]int{languageScore, fullDialogue, plain, preferred}
+}
+
+func tagValue(tags map[string]string, key string) string {
+	for name, value := range tags {
+		if strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+func containsAny(value string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(value, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	regionSampleWidth    = 480
+	regionSampleCount    = 24
+	regionEdgeDelta      = 80
+	regionBrightLuma     = 190
+	regionMinRowHits     = 0.12
+	regionStaticRatio    = 0.8
+	regionMinBandPercent = 1.5
+	regionGapPercent     = 1.0
+	regionMaxRegions     = 3
+)
+
+type CropRegion struct {
+	Name          string
+	TopPercent    float64
+	HeightPercent float64
+}
+
+type DetectedRegion struct {
+	TopPercent    float64
+	HeightPercent float64
+	Score         float64
+}
+
+type formatProbeOutput struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func (r CropRegion) Normalize() CropRegion {
+	if r.TopPercent < 0 || r.TopPercent >= 100 {
+		r.TopPercent = 72
+	}
+	if r.HeightPercent <= 0 || r.HeightPercent > 100 {
+		r.HeightPercent = 22
+	}
+	if r.TopPercent+r.HeightPercent > 100 {
+		r.HeightPercent = 100 - r.TopPercent
+	}
+	return r
+}
+
+func (r CropRegion) cropFilter() string {
+	return fmt.Sprintf("crop=iw:ih*%.4f:0:ih*%.4f", r.HeightPercent/100, r.TopPercent/100)
+}
+
+func (r CropRegion) dirSuffix() string {
+	if strings.TrimSpace(r.Name) == "" {
+		return ""
+	}
+	return "." + safeName(r.Name)
+}
+
+func DetectSubtitleRegions(ctx context.Context, ffmpegBin string, ffprobeBin string, videoPath string) ([]DetectedRegion, error) {
+	width, height, _, err := probeVideoGeometry(ctx, ffprobeBin, videoPath)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := probeDuration(ctx, ffprobeBin, videoPath)
+	if err != nil {
+		return nil, err
+	}
+	sampleHeight := int(float64(height)*regionSampleWidth/float64(width)) / 2 * 2
+	if sampleHeight < 2 {
+		return nil, errors.New("视频分辨率异常，无法检测字幕区域")
+	}
+	frames := make([][]byte, 0, regionSampleCount)
+	for index := 0; index < regionSampleCount; index++ {
+		at := time.Duration((0.05 + 0.9*float64(index)/float64(regionSampleCount-1)) * float64(duration))
+		frame, err := grabGrayFrame(ctx, ffmpegBin, videoPath, at, regionSampleWidth, sampleHeight)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) < regionSampleCount/2 {
+		return nil, fmt.Errorf("字幕区域检测只取到 %d 张采样帧", len(frames))
+	}
+	regions := detectRegions(frames, regionSampleWidth, sampleHeight)
+	if len(regions) == 0 {
+		return nil, errors.New("未检测到稳定的字幕区域")
+	}
+	return regions, nil
+}
+
+func detectRegions(frames [][]byte, width int, height int) []DetectedRegion {
+	if len(frames) == 0 || width < 2 || height < 2 {
+		return nil
+	}
+	minTransitions := width / 60
+	if minTransitions < 6 {
+		minTransitions = 6
+	}
+	transition := func(row []byte, x int) bool {
+		left, right := int(row[x]), int(row[x+1])
+		delta := left - right
+		if delta < 0 {
+			delta = -delta
+		}
+		return delta >= regionEdgeDelta && (left >= regionBrightLuma || right >= regionBrightLuma)
+	}
+	persistence := make([]int, width*height)
+	for _, frame := range frames {
+		for y := 0; y < height; y++ {
+			row := frame[y*width : (y+1)*width]
+			for x := 0; x+1 < width; x++ {
+				if transition(row, x) {
+					persistence[y*width+x]++
+				}
+			}
+		}
+	}
+	staticLimit := int(math.Ceil(float64(len(frames)) * regionStaticRatio))
+	hits := make([]float64, height)
+	for _, frame := range frames {
+		for y := 0; y < height; y++ {
+			row := frame[y*width : (y+1)*width]
+			transitions := 0
+			for x := 0; x+1 < width; x++ {
+				if persistence[y*width+x] < staticLimit && transition(row, x) {
+					transitions++
+				}
+			}
+			if transitions >= minTransitions {
+				hits[y]++
+			}
+		}
+	}
+	for y := range hits {
+		hits[y] /= float64(len(frames))
+	}
+	gap := int(math.Ceil(float64(height) * regionGapPercent / 100))
+	minRows := int(math.Ceil(float64(height) * regionMinBandPercent / 100))
+	type band struct {
+		start, end int
+		score      float64
+	}
+	bands := make([]band, 0, 4)
+	current := band{start: -1}
+	lastHit := -gap - 1
+	for y := 0; y < height; y++ {
+		if hits[y] < regionMinRowHits {
+			continue
+		}
+		if current.start >= 0 && y-lastHit > gap {
+			bands = append(bands, current)
+			current = band{start: -1}
+		}
+		if current.start < 0 {
+			current = band{start: y}
+		}
+		current.end = y + 1
+		current.score += hits[y]
+		lastHit = y
+	}
+	if current.start >= 0 {
+		bands = append(bands, current)
+	}
+	regions := make([]DetectedRegion, 0, len(bands))
+	for _, item := range bands {
+		if item.end-item.start < minRows {
+			continue
+		}
+		bandHeight := item.end - item.start
+		padTop := bandHeight
+		if minimum := int(float64(height) * 0.03); padTop < minimum {
+			padTop = minimum
+		}
+		padBottom := int(math.Ceil(float64(height) * 0.02))
+		top := item.start - padTop
+		if top < 0 {
+			top = 0
+		}
+		bottom := item.end + padBottom
+		if bottom > height {
+			bottom = height
+		}
+		regions = append(regions, DetectedRegion{
+			TopPercent:    roundPercent(float64(top) * 100 / float64(height)),
+			HeightPercent: roundPercent(float64(bottom-top) * 100 / float64(height)),
+			Score:         item.score,
+		})
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].Score > regions[j].Score
+	})
+	if len(regions) > regionMaxRegions {
+		regions = regions[:regionMaxRegions]
+	}
+	return mergeOverlappingRegions(regions)
+}
+
+func mergeOverlappingRegions(regions []DetectedRegion) []DetectedRegion {
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].TopPercent < regions[j].TopPercent
+	})
+	merged := make([]DetectedRegion, 0, len(regions))
+	for _, region := range regions {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if region.TopPercent <= last.TopPercent+last.HeightPercent {
+				bottom := math.Max(last.TopPercent+last.HeightPercent, region.TopPercent+region.HeightPercent)
+				last.HeightPercent = roundPercent(bottom - last.TopPercent)
+				last.Score += region.Score
+				continue
+			}
+		}
+		merged = append(merged, region)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	return merged
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+func grabGrayFrame(ctx context.Context, ffmpegBin string, videoPath string, at time.Duration, width int, height int) ([]byte, error) {
+	command := exec.CommandContext(ctx, ffmpegBin, "-v", "error", "-ss", formatSeconds(at), "-i", videoPath,
+		"-map", "0:v:0", "-frames:v", "1", "-vf", fmt.Sprintf("scale=%d:%d,format=gray", width, height),
+		"-f", "rawvideo", "-pix_fmt", "gray", "pipe:1")
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("字幕区域采样失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	frame := make([]byte, width*height)
+	if _, err := io.ReadFull(&stdout, frame); err != nil {
+		return nil, fmt.Errorf("字幕区域采样帧不完整: %w", err)
+	}
+	return frame, nil
+}
+
+func probeDuration(ctx context.Context, ffprobeBin string, videoPath string) (time.Duration, error) {
+	command := exec.CommandContext(ctx, ffprobeBin, "-v", "error", "-show_entries", "format=duration", "-print_format", "json", videoPath)
+	output, err := command.Output()
+	if err != nil {
+		return 0, fmt.Errorf("视频时长探测失败: %w", err)
+	}
+	var payload formatProbeOutput
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return 0, fmt.Errorf("视频时长解析失败: %w", err)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(payload.Format.Duration), 64)
+	if err != nil || seconds <= 0 {
+		return 0, errors.New("无法获取视频时长")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,331 @@
+package media
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type SidecarCandidate struct {
+	Path            string `json:"path"`
+	Language        string `json:"language,omitempty"`
+	Forced          bool   `json:"forced"`
+	HearingImpaired bool   `json:"hearing_impaired"`
+	InSubsDir       bool   `json:"in_subs_dir"`
+	ExactMatch      bool   `json:"exact_match"`
+}
+
+var subsDirNames = []string{"subs", "sub", "subtitles", "subtitle"}
+
+var sidecarLanguageNames = map[string]string{
+	"english": "en", "chinese": "zh", "chs": "zh", "cht": "zh", "sc": "zh", "tc": "zh",
+	"gb": "zh", "big5": "zh", "简体": "zh", "繁体": "zh", "简中": "zh", "繁中": "zh", "中文": "zh",
+	"japanese": "ja", "jp": "ja", "korean": "ko", "kr": "ko", "spanish": "es", "french": "fr",
+	"german": "de", "italian": "it", "portuguese": "pt", "brazilian": "pt", "russian": "ru",
+	"arabic": "ar", "thai": "th", "vietnamese": "vi", "hindi": "hi", "indonesian": "id",
+	"dutch": "nl", "polish": "pl", "turkish": "tr", "swedish": "sv", "norwegian": "no",
+	"danish": "da", "finnish": "fi", "greek": "el", "hebrew": "he", "hungarian": "hu",
+	"czech": "cs", "ukrainian": "uk", "malay": "ms", "filipino": "tl",
+}
+
+var sidecarForcedTokens = map[string]bool{"forced": true, "foreign": true, "signs": true}
+
+var sidecarSDHTokens = map[string]bool{"sdh": true, "cc": true, "hi": true}
+
+var sidecarIgnoredTokens = map[string]bool{"default": true, "full": true}
+
+var sidecarRegionTokens = map[string]bool{"cn": true, "tw": true, "hk": true, "sg": true, "us": true, "br": true, "mx": true, "hans": true, "hant": true}
+
+var videoExtensions = []string{".mp4", ".mkv", ".avi", ".mov", ".wmv", ".m4v", ".ts"}
+
+func DiscoverSidecars(videoPath string) []SidecarCandidate {
+	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	videoDir := filepath.Dir(videoPath)
+	otherVideos := otherVideoStems(videoPath)
+	candidates := make([]SidecarCandidate, 0, 4)
+	for _, name := range readDirFiles(videoDir) {
+		stem, ok := sidecarStem(name)
+		if !ok || otherVideos[strings.ToLower(stem)] {
+			continue
+		}
+		var tags string
+		switch {
+		case strings.EqualFold(stem, baseName):
+			tags = ""
+		case len(stem) > len(baseName) && strings.EqualFold(stem[:len(baseName)], baseName) && stem[len(baseName)] == '.':
+			tags = stem[len(baseName)+1:]
+		default:
+			continue
+		}
+		if candidate, ok := parseSidecarTags(filepath.Join(videoDir, name), tags, false); ok {
+			candidate.ExactMatch = tags == ""
+			candidates = append(candidates, candidate)
+		}
+	}
+	for _, subsDir := range findSubsDirs(videoDir) {
+		episodeDir := findChildDir(subsDir, baseName)
+		if episodeDir != "" {
+			for _, name := range readDirFiles(episodeDir) {
+				if stem, ok := sidecarStem(name); ok {
+					if candidate, ok := parseSidecarTags(filepath.Join(episodeDir, name), stem, true); ok {
+						candidates = append(candidates, candidate)
+					}
+				}
+			}
+			continue
+		}
+		for _, name := range readDirFiles(subsDir) {
+			stem, ok := sidecarStem(name)
+			if !ok || otherVideos[strings.ToLower(stem)] {
+				continue
+			}
+			tags := stem
+			switch {
+			case strings.EqualFold(stem, baseName):
+				tags = ""
+			case len(stem) > len(baseName) && strings.EqualFold(stem[:len(baseName)], baseName) && strings.ContainsRune("._-", rune(stem[len(baseName)])):
+				tags = stem[len(baseName)+1:]
+			case !singleVideoInDir(videoPath):
+				continue
+			}
+			if candidate, ok := parseSidecarTags(filepath.Join(subsDir, name), tags, true); ok {
+				candidate.ExactMatch = tags == ""
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+func SelectSidecar(candidates []SidecarCandidate, sourceLanguage string) (SidecarCandidate, bool) {
+	if len(candidates) == 0 {
+		return SidecarCandidate{}, false
+	}
+	language := NormalizeLanguage(sourceLanguage)
+	ranked := append([]SidecarCandidate{}, candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		left := sidecarRank(ranked[i], language)
+		right := sidecarRank(ranked[j], language)
+		for position := range left {
+			if left[position] != right[position] {
+				return left[position] > right[position]
+			}
+		}
+		return ranked[i].Path < ranked[j].Path
+	})
+	return ranked[0], true
+}
+
+func (c SidecarCandidate) Describe() string {
+	parts := []string{filepath.Base(c.Path)}
+	if c.Language != "" {
+		parts = append(parts, c.Language)
+	}
+	if c.Forced {
+		parts = append(parts, "forced")
+	}
+	if c.HearingImpaired {
+		parts = append(parts, "SDH")
+	}
+	if c.InSubsDir {
+		parts = append(parts, fmt.Sprintf("目录 %s", filepath.Base(filepath.Dir(c.Path))))
+	}
+	return strings.Join(parts, " ")
+}
+
+func sidecarRank(candidate SidecarCandidate, language string) [6]int {
+	languageScore := 1
+	if language != "" {
+		switch {
+		case candidate.Language == language:
+			languageScore = 2
+		case candidate.Language != "":
+			languageScore = 0
+		}
+	}
+	exact := 0
+	if candidate.ExactMatch {
+		exact = 1
+	}
+	fullDialogue := 1
+	if candidate.Forced {
+		fullDialogue = 0
+	}
+	plain := 1
+	if candidate.HearingImpaired {
+		plain = 0
+	}
+	besideVideo := 1
+	if candidate.InSubsDir {
+		besideVideo = 0
+	}
+	extensionScore := len(sidecarExtensions)
+	for index, ext := range sidecarExtensions {
+		if strings.EqualFold(filepath.Ext(candidate.Path), ext) {
+			extensionScore = len(sidecarExtensions) - index
+			break
+		}
+	}
+	return [6]int{languageScore, exact, fullDialogue, plain, besideVideo, extensionScore}
+}
+
+func parseSidecarTags(path string, tags string, inSubsDir bool) (SidecarCandidate, bool) {
+	candidate := SidecarCandidate{Path: path, InSubsDir: inSubsDir}
+	tokens := strings.FieldsFunc(strings.ToLower(tags), func(char rune) bool {
+		return char == '.' || char == '_' || char == ' ' || char == '[' || char == ']' || char == '(' || char == ')'
+	})
+	for _, token := range tokens {
+		if token == "bilingual" {
+			return SidecarCandidate{}, false
+		}
+		if sidecarIgnoredTokens[token] || isNumericToken(token) {
+			continue
+		}
+		if sidecarForcedTokens[token] {
+			candidate.Forced = true
+			continue
+		}
+		if sidecarSDHTokens[token] && (token != "hi" || candidate.Language != "") {
+			candidate.HearingImpaired = true
+			continue
+		}
+		if sidecarRegionTokens[token] && candidate.Language != "" {
+			continue
+		}
+		language := sidecarLanguage(token)
+		if language == "" {
+			return SidecarCandidate{}, false
+		}
+		if candidate.Language == "" {
+			candidate.Language = language
+		}
+	}
+	return candidate, true
+}
+
+func sidecarLanguage(token string) string {
+	if code, ok := sidecarLanguageNames[token]; ok {
+		return code
+	}
+	base := token
+	if index := strings.IndexAny(base, "-"); index > 0 {
+		base = base[:index]
+	}
+	if code, ok := languageAliases[base]; ok {
+		return code
+	}
+	if len(base) == 2 && isKnownLanguageCode(base) {
+		return base
+	}
+	return ""
+}
+
+func isKnownLanguageCode(code string) bool {
+	for _, value := range languageAliases {
+		if value == code {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumericToken(token string) bool {
+	for _, char := range token {
+		if !unicode.IsDigit(char) {
+			return false
+		}
+	}
+	return token != ""
+}
+
+func sidecarStem(name string) (string, bool) {
+	ext := filepath.Ext(name)
+	for _, candidate := range sidecarExtensions {
+		if strings.EqualFold(ext, candidate) {
+			return strings.TrimSuffix(name, ext), true
+		}
+	}
+	return "", false
+}
+
+func IsVideoFile(name string) bool {
+	ext := filepath.Ext(name)
+	for _, candidate := range videoExtensions {
+		if strings.EqualFold(ext, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func otherVideoStems(videoPath string) map[string]bool {
+	stems := map[string]bool{}
+	self := filepath.Base(videoPath)
+	for _, name := range readDirFiles(filepath.Dir(videoPath)) {
+		if name != self && IsVideoFile(name) {
+			stems[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))] = true
+		}
+	}
+	return stems
+}
+
+func findSubsDirs(videoDir string) []string {
+	entries, err := os.ReadDir(videoDir)
+	if err != nil {
+		return nil
+	}
+	dirs := make([]string, 0, 1)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, name := range subsDirNames {
+			if strings.EqualFold(entry.Name(), name) {
+				dirs = append(dirs, filepath.Join(videoDir, entry.Name()))
+				break
+			}
+		}
+	}
+	return dirs
+}
+
+func singleVideoInDir(videoPath string) bool {
+	ext := filepath.Ext(videoPath)
+	count := 0
+	for _, name := range readDirFiles(filepath.Dir(videoPath)) {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			count++
+		}
+	}
+	return count <= 1
+}
+
+func findChildDir(parent string, name string) string {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+			return filepath.Join(parent, entry.Name())
+		}
+	}
+	return ""
+}
+
+func readDirFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
@@ -0,0 +1,114 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestDiscoverAndSelectSidecar(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		language string
+		found    []string
+		selected string
+	}{
+		{
+			name:     "exact stem beats unrelated tags",
+			files:    []string{"Movie.mkv", "Movie.srt", "Movie.Part2.srt", "Movie.Extended.srt"},
+			found:    []string{"Movie.srt"},
+			selected: "Movie.srt",
+		},
+		{
+			name:     "exact stem beats numbered tag",
+			files:    []string{"Movie.mkv", "Movie.srt", "Movie.2.srt"},
+			found:    []string{"Movie.2.srt", "Movie.srt"},
+			selected: "Movie.srt",
+		},
+		{
+			name:     "language tag wins when it matches the source language",
+			files:    []string{"Movie.mkv", "Movie.srt", "Movie.en.srt", "Movie.ja.srt"},
+			language: "ja",
+			found:    []string{"Movie.en.srt", "Movie.ja.srt", "Movie.srt"},
+			selected: "Movie.ja.srt",
+		},
+		{
+			name:     "region suffix after language is accepted",
+			files:    []string{"Movie.mkv", "Movie.zh_CN.srt"},
+			language: "zh",
+			found:    []string{"Movie.zh_CN.srt"},
+			selected: "Movie.zh_CN.srt",
+		},
+		{
+			name:     "forced and sdh rank below full dialogue",
+			files:    []string{"Movie.mkv", "Movie.en.forced.srt", "Movie.en.sdh.srt", "Movie.en.srt"},
+			language: "en",
+			found:    []string{"Movie.en.forced.srt", "Movie.en.sdh.srt", "Movie.en.srt"},
+			selected: "Movie.en.srt",
+		},
+		{
+			name:  "bilingual output is never a source",
+			files: []string{"Movie.mkv", "Movie.zh.bilingual.srt"},
+		},
+		{
+			name:     "another video's subtitle is rejected",
+			files:    []string{"Movie.mkv", "Movie.Part2.mkv", "Movie.Part2.srt", "Movie.en.srt"},
+			found:    []string{"Movie.en.srt"},
+			selected: "Movie.en.srt",
+		},
+		{
+			name:     "subs dir does not match a longer video name",
+			files:    []string{"Movie.mkv", "Movie 2.mkv", "Subs/Movie 2.srt", "Subs/Movie.en.srt"},
+			found:    []string{"Subs/Movie.en.srt"},
+			selected: "Subs/Movie.en.srt",
+		},
+		{
+			name:     "subs dir of a single video accepts bare language files",
+			files:    []string{"Movie.mkv", "Subs/English.srt", "Subs/Notes.srt"},
+			found:    []string{"Subs/English.srt"},
+			selected: "Subs/English.srt",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range test.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			candidates := DiscoverSidecars(filepath.Join(dir, "Movie.mkv"))
+			found := make([]string, 0, len(candidates))
+			for _, candidate := range candidates {
+				relative, _ := filepath.Rel(dir, candidate.Path)
+				found = append(found, filepath.ToSlash(relative))
+			}
+			sort.Strings(found)
+			if len(found) != len(test.found) {
+				t.Fatalf("found %v, want %v", found, test.found)
+			}
+			for index := range found {
+				if found[index] != test.found[index] {
+					t.Fatalf("found %v, want %v", found, test.found)
+				}
+			}
+			selected, ok := SelectSidecar(candidates, test.language)
+			if test.selected == "" {
+				if ok {
+					t.Fatalf("selected %s, want none", selected.Path)
+				}
+				return
+			}
+			relative, _ := filepath.Rel(dir, selected.Path)
+			if !ok || filepath.ToSlash(relative) != test.selected {
+				t.Fatalf("selected %s, want %s", relative, test.selected)
+			}
+		})
+	}
+}
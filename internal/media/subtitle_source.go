@@ -7,31 +7,45 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 var sidecarExtensions = []string{".srt", ".ass", ".ssa", ".vtt"}
 
-func ExtractSubtitleSource(ctx context.Context, ffmpegBin string, videoPath string, workDir string) (string, error) {
+type SubtitleSource struct {
+	Path string
+}
+
+func ExtractSidecarSource(ctx context.Context, ffmpegBin string, videoPath string, workDir string, candidate SidecarCandidate) (SubtitleSource, error) {
 	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
-	videoDir := filepath.Dir(videoPath)
-	for _, ext := range sidecarExtensions {
-		candidate := filepath.Join(videoDir, baseName+ext)
-		if _, err := os.Stat(candidate); err == nil {
-			return ensureSRT(ctx, ffmpegBin, candidate, filepath.Join(workDir, safeName(baseName)+".source.srt"))
-		}
+	path, err := ensureSRT(ctx, ffmpegBin, candidate.Path, filepath.Join(workDir, safeName(baseName)+".source.srt"))
+	return SubtitleSource{Path: path}, err
+}
+
+func ExtractEmbeddedSource(ctx context.Context, ffmpegBin string, videoPath string, workDir string, stream *Stream) (SubtitleSource, error) {
+	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	streamMap := "0:s:0"
+	if stream != nil {
+		streamMap = fmt.Sprintf("0:%d", stream.Index)
 	}
-	return extractEmbeddedSubtitle(ctx, ffmpegBin, videoPath, filepath.Join(workDir, safeName(baseName)+".embedded.srt"))
+	path, err := extractEmbeddedSubtitle(ctx, ffmpegBin, videoPath, streamMap, filepath.Join(workDir, safeName(baseName)+".embedded.srt"))
+	return SubtitleSource{Path: path}, err
 }
 
-func ExtractAudio(ctx context.Context, ffmpegBin string, videoPath string, workDir string) (string, error) {
+func ExtractAudio(ctx context.Context, ffmpegBin string, videoPath string, workDir string, audioTypeIndex int) (string, error) {
 	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
 	outputPath := filepath.Join(workDir, safeName(baseName)+".wav")
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
 		return "", err
 	}
-	command := exec.CommandContext(ctx, ffmpegBin, "-y", "-i", videoPath, "-vn", "-ac", "1", "-ar", "16000", "-acodec", "pcm_s16le", outputPath)
+	args := []string{"-y", "-i", videoPath}
+	if audioTypeIndex >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:a:%d", audioTypeIndex))
+	}
+	args = append(args, "-vn", "-ac", "1", "-ar", "16000", "-acodec", "pcm_s16le", outputPath)
+	command := exec.CommandContext(ctx, ffmpegBin, args...)
 	output, err := command.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("音频提取失败: %w: %s", err, strings.TrimSpace(string(output)))
@@ -39,6 +53,20 @@ func ExtractAudio(ctx context.Context, ffmpegBin string, videoPath string, workD
 	return outputPath, nil
 }
 
+func ExtractPCM(ctx context.Context, ffmpegBin string, videoPath string, workDir string, sampleRate int) (string, error) {
+	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	outputPath := filepath.Join(workDir, safeName(baseName)+".sync.pcm")
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return "", err
+	}
+	command := exec.CommandContext(ctx, ffmpegBin, "-y", "-i", videoPath, "-vn", "-ac", "1", "-ar", strconv.Itoa(sampleRate), "-f", "s16le", "-acodec", "pcm_s16le", outputPath)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("同步音频提取失败: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return outputPath, nil
+}
+
 func ensureSRT(ctx context.Context, ffmpegBin string, inputPath string, outputPath string) (string, error) {
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
 		return "", err
@@ -61,11 +89,11 @@ func ensureSRT(ctx context.Context, ffmpegBin string, inputPath string, outputPa
 	return outputPath, nil
 }
 
-func extractEmbeddedSubtitle(ctx context.Context, ffmpegBin string, videoPath string, outputPath string) (string, error) {
+func extractEmbeddedSubtitle(ctx context.Context, ffmpegBin string, videoPath string, streamMap string, outputPath string) (string, error) {
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
 		return "", err
 	}
-	command := exec.CommandContext(ctx, ffmpegBin, "-y", "-i", videoPath, "-map", "0:s:0", outputPath)
+	command := exec.CommandContext(ctx, ffmpegBin, "-y", "-i", videoPath, "-map", streamMap, outputPath)
 	output, err := command.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("未找到可提取的外挂或内嵌文本字幕；将尝试 ASR。ffmpeg 输出: %s", strings.TrimSpace(string(output)))
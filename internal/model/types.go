@@ -3,19 +3,77 @@ package model
 import "time"
 
 type AppSettings struct {
-	MediaPaths          []string  `json:"media_paths"`
-	SourceLanguage      string    `json:"source_language"`
-	TargetLanguage      string    `json:"target_language"`
-	BilingualLayout     string    `json:"bilingual_layout"`
-	OutputFormats       []string  `json:"output_formats"`
-	TranslationProvider string    `json:"translation_provider"`
-	TranslationModel    string    `json:"translation_model"`
-	TranslationPrompt   string    `json:"translation_prompt"`
-	TranslationStyle    string    `json:"translation_style"`
-	CustomStylePrompt   string    `json:"custom_style_prompt"`
-	Glossary            string    `json:"glossary"`
-	MaxSubtitlePerBatch int       `json:"max_subtitle_per_batch"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	MediaPaths          []string            `json:"media_paths"`
+	SourceLanguage      string              `json:"source_language"`
+	TargetLanguage      string              `json:"target_language"`
+	BilingualLayout     string              `json:"bilingual_layout"`
+	OutputFormats       []string            `json:"output_formats"`
+	TranslationProvider string              `json:"translation_provider"`
+	TranslationModel    string              `json:"translation_model"`
+	TranslationPrompt   string              `json:"translation_prompt"`
+	TranslationStyle    string              `json:"translation_style"`
+	CustomStylePrompt   string              `json:"custom_style_prompt"`
+	Glossary            string              `json:"glossary"`
+	MaxSubtitlePerBatch int                 `json:"max_subtitle_per_batch"`
+	QA                  QASettings          `json:"qa"`
+	Layout              LayoutSettings      `json:"layout"`
+	Sync                SyncSettings        `json:"sync"`
+	OCRTimeline         OCRTimelineSettings `json:"ocr_timeline"`
+	ASRFilter           ASRFilterSettings   `json:"asr_filter"`
+	SourceStrategies    []SourceStrategy    `json:"source_strategies"`
+	Fusion              FusionSettings      `json:"fusion"`
+	UpdatedAt           time.Time           `json:"updated_at"`
+}
+
+type QASettings struct {
+	MaxCharsPerSecond float64 `json:"max_chars_per_second"`
+	MaxLineLength     int     `json:"max_line_length"`
+	MaxLinesPerSide   int     `json:"max_lines_per_side"`
+	MinOCRConfidence  float64 `json:"min_ocr_confidence"`
+}
+
+type LayoutSettings struct {
+	WrapEnabled       bool    `json:"wrap_enabled"`
+	MaxLineWidth      int     `json:"max_line_width"`
+	ExtendTiming      bool    `json:"extend_timing"`
+	MaxCharsPerSecond float64 `json:"max_chars_per_second"`
+	MinGapMS          int     `json:"min_gap_ms"`
+	MaxExtensionMS    int     `json:"max_extension_ms"`
+}
+
+type SyncSettings struct {
+	Enabled       bool    `json:"enabled"`
+	MaxOffsetMS   int     `json:"max_offset_ms"`
+	MinConfidence float64 `json:"min_confidence"`
+}
+
+type OCRTimelineSettings struct {
+	MinDurationMS       int     `json:"min_duration_ms"`
+	MaxJoinGapMS        int     `json:"max_join_gap_ms"`
+	MinTextLength       int     `json:"min_text_length"`
+	StableFrames        int     `json:"stable_frames"`
+	ConfidenceFloor     float64 `json:"confidence_floor"`
+	SimilarityThreshold float64 `json:"similarity_threshold"`
+	MaxEditDistance     int     `json:"max_edit_distance"`
+}
+
+type ASRFilterSettings struct {
+	Enabled         bool    `json:"enabled"`
+	MaxNoSpeechProb float64 `json:"max_no_speech_prob"`
+	MinAvgLogprob   float64 `json:"min_avg_logprob"`
+	MaxRepeats      int     `json:"max_repeats"`
+	Blocklist       string  `json:"blocklist"`
+}
+
+type FusionSettings struct {
+	Enabled       bool    `json:"enabled"`
+	MinSimilarity float64 `json:"min_similarity"`
+	MaxShiftMS    int     `json:"max_shift_ms"`
+}
+
+type SourceStrategy struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
 }
 
 type MediaAsset struct {
@@ -30,25 +88,42 @@ type MediaAsset struct {
 }
 
 type SubtitleJob struct {
-	ID                 string    `json:"id"`
-	MediaAssetID       *int64    `json:"media_asset_id,omitempty"`
-	MediaPath          string    `json:"media_path"`
-	FileName           string    `json:"file_name"`
-	Status             string    `json:"status"`
-	CurrentStage       string    `json:"current_stage"`
-	Progress           int       `json:"progress"`
-	SourceLanguage     string    `json:"source_language"`
-	TargetLanguage     string    `json:"target_language"`
-	Provider           string    `json:"provider"`
-	OutputFormats      []string  `json:"output_formats"`
-	SourceSubtitlePath string    `json:"source_subtitle_path,omitempty"`
-	OutputSubtitlePath string    `json:"output_subtitle_path,omitempty"`
-	OutputSRTPath      string    `json:"output_srt_path,omitempty"`
-	OutputASSPath      string    `json:"output_ass_path,omitempty"`
-	Details            string    `json:"details,omitempty"`
-	ErrorMessage       string    `json:"error_message,omitempty"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                  string      `json:"id"`
+	MediaAssetID        *int64      `json:"media_asset_id,omitempty"`
+	MediaPath           string      `json:"media_path"`
+	FileName            string      `json:"file_name"`
+	Status              string      `json:"status"`
+	CurrentStage        string      `json:"current_stage"`
+	Progress            int         `json:"progress"`
+	SourceLanguage      string      `json:"source_language"`
+	DetectedLanguage    string      `json:"detected_language,omitempty"`
+	TargetLanguage      string      `json:"target_language"`
+	Provider            string      `json:"provider"`
+	OutputFormats       []string    `json:"output_formats"`
+	SubtitleStreamIndex *int        `json:"subtitle_stream_index,omitempty"`
+	OCRRegions          []OCRRegion `json:"ocr_regions,omitempty"`
+	SourceStrategies    []string    `json:"source_strategies,omitempty"`
+	SourceStrategy      string      `json:"source_strategy,omitempty"`
+	SourceSubtitlePath  string      `json:"source_subtitle_path,omitempty"`
+	OutputSubtitlePath  string      `json:"output_subtitle_path,omitempty"`
+	OutputSRTPath       string      `json:"output_srt_path,omitempty"`
+	OutputASSPath       string      `json:"output_ass_path,omitempty"`
+	Details             string      `json:"details,omitempty"`
+	ErrorMessage        string      `json:"error_message,omitempty"`
+	CreatedAt           time.Time   `json:"created_at"`
+	UpdatedAt           time.Time   `json:"updated_at"`
+}
+
+type OCRRegion struct {
+	TopPercent    float64 `json:"top_percent"`
+	HeightPercent float64 `json:"height_percent"`
+}
+
+type MediaOCRRegions struct {
+	MediaPath string      `json:"media_path"`
+	Regions   []OCRRegion `json:"regions"`
+	Source    string      `json:"source"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
 
 type JobLogEntry struct {
@@ -59,6 +134,11 @@ type JobLogEntry struct {
 	Detail    string    `json:"detail,omitempty"`
 }
 
+type OCRCacheStats struct {
+	Entries int `json:"entries"`
+	Hits    int `json:"hits"`
+}
+
 type PipelineStep struct {
 	Key         string `json:"key"`
 	Title       string `json:"title"`
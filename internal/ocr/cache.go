@@ -0,0 +1,167 @@
+package ocr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type CacheStore interface {
+	GetOCRCache(ctx context.Context, imageHash string, provider string, modelName string) (string, float64, bool, error)
+	PutOCRCache(ctx context.Context, imageHash string, provider string, modelName string, text string, confidence float64) error
+}
+
+type CacheStats struct {
+	Hits   int
+	Misses int
+	Errors int
+}
+
+type CachedProvider struct {
+	provider Provider
+	store    CacheStore
+	model    string
+	mu       sync.Mutex
+	stats    CacheStats
+}
+
+func NewCachedProvider(provider Provider, store CacheStore, modelName string) *CachedProvider {
+	return &CachedProvider{provider: provider, store: store, model: strings.TrimSpace(modelName)}
+}
+
+func (c *CachedProvider) Name() string {
+	return c.provider.Name()
+}
+
+func (c *CachedProvider) Ready() bool {
+	return c.provider.Ready()
+}
+
+func (c *CachedProvider) RecognizeImage(ctx context.Context, imagePath string) (string, float64, error) {
+	if c.store == nil {
+		return c.provider.RecognizeImage(ctx, imagePath)
+	}
+	imageHash, err := HashImage(imagePath)
+	if err != nil {
+		c.record(func(stats *CacheStats) { stats.Errors++ })
+		return c.provider.RecognizeImage(ctx, imagePath)
+	}
+	text, confidence, found, err := c.store.GetOCRCache(ctx, imageHash, c.provider.Name(), c.cacheModel(ctx))
+	if err == nil && found {
+		c.record(func(stats *CacheStats) { stats.Hits++ })
+		return text, confidence, nil
+	}
+	c.record(func(stats *CacheStats) {
+		stats.Misses++
+		if err != nil {
+			stats.Errors++
+		}
+	})
+	text, confidence, err = c.provider.RecognizeImage(ctx, imagePath)
+	if err != nil {
+		return "", 0, err
+	}
+	if putErr := c.store.PutOCRCache(ctx, imageHash, c.provider.Name(), c.cacheModel(ctx), text, confidence); putErr != nil {
+		c.record(func(stats *CacheStats) { stats.Errors++ })
+	}
+	return text, confidence, nil
+}
+
+func (c *CachedProvider) BatchSupported() bool {
+	batcher, ok := c.provider.(BatchProvider)
+	return ok && batcher.BatchSupported()
+}
+
+func (c *CachedProvider) RecognizeBatch(ctx context.Context, imagePaths []string) ([]Result, error) {
+	batcher, ok := c.provider.(BatchProvider)
+	if !ok {
+		return nil, errors.New("当前 OCR 提供方不支持批量识别")
+	}
+	if c.store == nil {
+		return batcher.RecognizeBatch(ctx, imagePaths)
+	}
+	results := make([]Result, len(imagePaths))
+	hashes := make([]string, len(imagePaths))
+	pending := make([]int, 0, len(imagePaths))
+	for index, imagePath := range imagePaths {
+		imageHash, err := HashImage(imagePath)
+		if err != nil {
+			c.record(func(stats *CacheStats) { stats.Errors++ })
+			pending = append(pending, index)
+			continue
+		}
+		hashes[index] = imageHash
+		text, confidence, found, err := c.store.GetOCRCache(ctx, imageHash, c.provider.Name(), c.cacheModel(ctx))
+		if err == nil && found {
+			c.record(func(stats *CacheStats) { stats.Hits++ })
+			results[index] = Result{Text: text, Confidence: confidence}
+			continue
+		}
+		c.record(func(stats *CacheStats) {
+			stats.Misses++
+			if err != nil {
+				stats.Errors++
+			}
+		})
+		pending = append(pending, index)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+	paths := make([]string, len(pending))
+	for position, index := range pending {
+		paths[position] = imagePaths[index]
+	}
+	recognized, err := batcher.RecognizeBatch(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+	for position, index := range pending {
+		result := recognized[position]
+		results[index] = result
+		if result.Err != nil || hashes[index] == "" {
+			continue
+		}
+		if putErr := c.store.PutOCRCache(ctx, hashes[index], c.provider.Name(), c.cacheModel(ctx), result.Text, result.Confidence); putErr != nil {
+			c.record(func(stats *CacheStats) { stats.Errors++ })
+		}
+	}
+	return results, nil
+}
+
+func (c *CachedProvider) cacheModel(ctx context.Context) string {
+	if hint := LanguageHint(ctx); hint != "" {
+		return c.model + "|" + hint
+	}
+	return c.model
+}
+
+func (c *CachedProvider) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *CachedProvider) record(update func(*CacheStats)) {
+	c.mu.Lock()
+	update(&c.stats)
+	c.mu.Unlock()
+}
+
+func HashImage(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,158 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const ImagePlaceholder = "{image}"
+
+type Client struct {
+	CommandLine string
+	Timeout     time.Duration
+}
+
+type commandOutput struct {
+	Text       string   `json:"text"`
+	Confidence *float64 `json:"confidence"`
+}
+
+func (c Client) Name() string {
+	return "local-command"
+}
+
+func (c Client) Ready() bool {
+	args, err := SplitCommandLine(c.CommandLine)
+	if err != nil || len(args) == 0 {
+		return false
+	}
+	_, err = exec.LookPath(args[0])
+	return err == nil
+}
+
+func (c Client) RecognizeImage(ctx context.Context, imagePath string) (string, float64, error) {
+	args, err := SplitCommandLine(c.CommandLine)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(args) == 0 {
+		return "", 0, errors.New("本地 OCR 命令尚未配置，请先填写 OCR_COMMAND")
+	}
+	replaced := false
+	for index, arg := range args[1:] {
+		if strings.Contains(arg, ImagePlaceholder) {
+			args[index+1] = strings.ReplaceAll(arg, ImagePlaceholder, imagePath)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, imagePath)
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	command := exec.CommandContext(runCtx, args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	command.WaitDelay = 2 * time.Second
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return "", 0, fmt.Errorf("本地 OCR 命令超时（%s）", timeout)
+		}
+		return "", 0, fmt.Errorf("本地 OCR 命令执行失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	text, confidence := ParseOutput(stdout.String())
+	return text, confidence, nil
+}
+
+func ParseOutput(raw string) (string, float64) {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff"))
+	if strings.HasPrefix(trimmed, "{") {
+		var payload commandOutput
+		if err := json.Unmarshal([]byte(trimmed), &payload); err == nil {
+			return cleanText(payload.Text), normalizeConfidence(payload.Confidence)
+		}
+	}
+	return cleanText(trimmed), 0
+}
+
+func SplitCommandLine(raw string) ([]string, error) {
+	args := make([]string, 0, 4)
+	var current strings.Builder
+	var quote rune
+	started := false
+	escaped := false
+	for _, char := range strings.TrimSpace(raw) {
+		switch {
+		case escaped:
+			current.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			started = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				current.WriteRune(char)
+			}
+		case char == '"' || char == '\'':
+			quote = char
+			started = true
+		case char == ' ' || char == '\t':
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(char)
+			started = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("OCR_COMMAND 引号或转义不完整")
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func normalizeConfidence(value *float64) float64 {
+	if value == nil || *value <= 0 {
+		return 0
+	}
+	if *value > 1 {
+		if *value <= 100 {
+			return *value / 100
+		}
+		return 1
+	}
+	return *value
+}
+
+func cleanText(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	lines := strings.Split(value, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(strings.ReplaceAll(line, "\f", "")); line != "" {
+			result = append(result, line)
+		}
+	}
+	return strings.Join(result, "\n")
+}
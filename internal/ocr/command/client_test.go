@@ -0,0 +1,122 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeStub(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ocr-stub.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecognizeImageWithStub(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		args       string
+		text       string
+		confidence float64
+		err        string
+	}{
+		{
+			name:       "json with confidence",
+			script:     `printf '{"text":"你好\\n世界","confidence":0.87}'`,
+			text:       "你好\n世界",
+			confidence: 0.87,
+		},
+		{
+			name:       "percentage confidence",
+			script:     `printf '{"text":"Hello","confidence":92}'`,
+			text:       "Hello",
+			confidence: 0.92,
+		},
+		{
+			name:   "plain text without confidence",
+			script: `printf '  first line \n\n second line \f\n'`,
+			text:   "first line\nsecond line",
+		},
+		{
+			name:   "image path placeholder",
+			script: `printf '%s' "$1"`,
+			args:   "--image={image}",
+			text:   "--image=/tmp/frame.png",
+		},
+		{
+			name:   "image path appended",
+			script: `printf '%s' "$1"`,
+			text:   "/tmp/frame.png",
+		},
+		{
+			name:   "non-zero exit",
+			script: `echo "model not found" >&2; exit 3`,
+			err:    "model not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := Client{CommandLine: strings.TrimSpace(writeStub(t, test.script) + " " + test.args), Timeout: 5 * time.Second}
+			if !client.Ready() {
+				t.Fatal("stub command should be ready")
+			}
+			text, confidence, err := client.RecognizeImage(context.Background(), "/tmp/frame.png")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to mention %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != test.text || confidence != test.confidence {
+				t.Fatalf("got (%q, %v), want (%q, %v)", text, confidence, test.text, test.confidence)
+			}
+		})
+	}
+}
+
+func TestRecognizeImageTimeout(t *testing.T) {
+	client := Client{CommandLine: writeStub(t, "sleep 5"), Timeout: 200 * time.Millisecond}
+	started := time.Now()
+	_, _, err := client.RecognizeImage(context.Background(), "/tmp/frame.png")
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Fatalf("error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(started); elapsed > 4*time.Second {
+		t.Fatalf("timeout took %s", elapsed)
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+		err  bool
+	}{
+		{raw: `tesseract {image} stdout -l chi_sim`, want: []string{"tesseract", "{image}", "stdout", "-l", "chi_sim"}},
+		{raw: `"/opt/my ocr/run" --lang 'ja jp'`, want: []string{"/opt/my ocr/run", "--lang", "ja jp"}},
+		{raw: `run path\ with\ space`, want: []string{"run", "path with space"}},
+		{raw: `run "unterminated`, err: true},
+	}
+	for _, test := range tests {
+		got, err := SplitCommandLine(test.raw)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.raw)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%q: got %q (%v), want %q", test.raw, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package ocr
+
+import (
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"os"
+)
+
+const (
+	DefaultDedupDistance = 8
+	analysisWidth        = 256
+	hashColumns          = 64
+	hashRows             = 8
+	edgeThreshold        = 48
+	hashDeadZone         = 6
+	minEdgeDensity       = 0.006
+	minEdgeRows          = 2
+	minEdgesPerRow       = 4
+	maxChangedCells      = 6
+)
+
+type FrameSignature struct {
+	Hash        [hashColumns * hashRows / 64]uint64
+	EdgeDensity float64
+	HasText     bool
+	luma        []float64
+}
+
+type FrameStats struct {
+	Extracted int
+	Blank     int
+	Reused    int
+	Sent      int
+}
+
+type Deduplicator struct {
+	maxDistance int
+	reference   FrameSignature
+	source      int
+	ready       bool
+}
+
+func NewDeduplicator(maxDistance int) *Deduplicator {
+	if maxDistance < 0 {
+		maxDistance = DefaultDedupDistance
+	}
+	return &Deduplicator{maxDistance: maxDistance}
+}
+
+func (d *Deduplicator) Lookup(signature FrameSignature) (int, bool) {
+	if !d.ready || signature.Distance(d.reference) > d.maxDistance || signature.changedCells(d.reference) > maxChangedCells {
+		return 0, false
+	}
+	return d.source, true
+}
+
+func (d *Deduplicator) Remember(signature FrameSignature, source int) {
+	d.reference = signature
+	d.source = source
+	d.ready = true
+}
+
+func (s FrameSignature) Distance(other FrameSignature) int {
+	distance := 0
+	for index := range s.Hash {
+		distance += bits.OnesCount64(s.Hash[index] ^ other.Hash[index])
+	}
+	return distance
+}
+
+func (s FrameSignature) changedCells(other FrameSignature) int {
+	if len(s.luma) != len(other.luma) {
+		return len(s.luma) + len(other.luma)
+	}
+	changed := 0
+	for index := range s.luma {
+		delta := s.luma[index] - other.luma[index]
+		if delta >= edgeThreshold || delta <= -edgeThreshold {
+			changed++
+		}
+	}
+	return changed
+}
+
+func AnalyzeFrame(path string) (FrameSignature, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FrameSignature{}, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return FrameSignature{}, err
+	}
+	return AnalyzeImage(img), nil
+}
+
+func AnalyzeImage(img image.Image) FrameSignature {
+	bounds := img.Bounds()
+	if bounds.Dx() <= 0 || bounds.Dy() <= 0 {
+		return FrameSignature{}
+	}
+	width := analysisWidth
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < hashRows {
+		height = hashRows
+	}
+	if height > bounds.Dy() {
+		height = bounds.Dy()
+	}
+	luma := downsampleLuma(img, width, height)
+	signature := FrameSignature{luma: luma}
+	signature.EdgeDensity, signature.HasText = textLikeContent(luma, width, height)
+	signature.Hash = differenceHash(luma, width, height)
+	return signature
+}
+
+func downsampleLuma(img image.Image, width int, height int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, width*height)
+	counts := make([]int, width*height)
+	sourceWidth := bounds.Dx()
+	sourceHeight := bounds.Dy()
+	for y := 0; y < sourceHeight; y++ {
+		row := y * height / sourceHeight * width
+		for x := 0; x < sourceWidth; x++ {
+			cell := row + x*width/sourceWidth
+			sums[cell] += pixelLuma(img, bounds.Min.X+x, bounds.Min.Y+y)
+			counts[cell]++
+		}
+	}
+	for index := range sums {
+		if counts[index] > 0 {
+			sums[index] /= float64(counts[index])
+		}
+	}
+	return sums
+}
+
+func pixelLuma(img image.Image, x int, y int) float64 {
+	switch typed := img.(type) {
+	case *image.Gray:
+		return float64(typed.GrayAt(x, y).Y)
+	case *image.RGBA:
+		offset := typed.PixOffset(x, y)
+		return 0.299*float64(typed.Pix[offset]) + 0.587*float64(typed.Pix[offset+1]) + 0.114*float64(typed.Pix[offset+2])
+	case *image.NRGBA:
+		offset := typed.PixOffset(x, y)
+		alpha := float64(typed.Pix[offset+3]) / 255
+		return alpha * (0.299*float64(typed.Pix[offset]) + 0.587*float64(typed.Pix[offset+1]) + 0.114*float64(typed.Pix[offset+2]))
+	}
+	r, g, b, _ := img.At(x, y).RGBA()
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+}
+
+func textLikeContent(luma []float64, width int, height int) (float64, bool) {
+	if width < 2 {
+		return 0, false
+	}
+	edges := 0
+	edgeRows := 0
+	for y := 0; y < height; y++ {
+		rowEdges := 0
+		for x := 0; x+1 < width; x++ {
+			delta := luma[y*width+x+1] - luma[y*width+x]
+			if delta >= edgeThreshold || delta <= -edgeThreshold {
+				rowEdges++
+			}
+		}
+		edges += rowEdges
+		if rowEdges >= minEdgesPerRow {
+			edgeRows++
+		}
+	}
+	density := float64(edges) / float64((width-1)*height)
+	return density, density >= minEdgeDensity && edgeRows >= minEdgeRows
+}
+
+func differenceHash(luma []float64, width int, height int) [hashColumns * hashRows / 64]uint64 {
+	grid := make([]float64, (hashColumns+1)*hashRows)
+	counts := make([]int, len(grid))
+	for y := 0; y < height; y++ {
+		row := y * hashRows / height * (hashColumns + 1)
+		for x := 0; x < width; x++ {
+			cell := row + x*(hashColumns+1)/width
+			grid[cell] += luma[y*width+x]
+			counts[cell]++
+		}
+	}
+	for index := range grid {
+		if counts[index] > 0 {
+			grid[index] /= float64(counts[index])
+		}
+	}
+	var hash [hashColumns * hashRows / 64]uint64
+	bit := 0
+	for y := 0; y < hashRows; y++ {
+		for x := 0; x < hashColumns; x++ {
+			delta := grid[y*(hashColumns+1)+x] - grid[y*(hashColumns+1)+x+1]
+			if delta > hashDeadZone || delta < -hashDeadZone {
+				hash[bit/64] |= 1 << uint(bit%64)
+			}
+			bit++
+		}
+	}
+	return hash
+}
@@ -0,0 +1,56 @@
+package ocr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFrameFilter(t *testing.T) {
+	draw := func(from int, to int, base uint8) image.Image {
+		img := image.NewGray(image.Rect(0, 0, 320, 60))
+		for y := 0; y < 60; y++ {
+			for x := 0; x < 320; x++ {
+				value := base
+				if y >= 20 && y < 40 && x >= from && x < to && (x/4)%2 == 0 {
+					value = 240
+				}
+				img.SetGray(x, y, color.Gray{Y: value})
+			}
+		}
+		return img
+	}
+	reference := AnalyzeImage(draw(40, 280, 20))
+	tests := []struct {
+		name      string
+		frame     image.Image
+		hasText   bool
+		duplicate bool
+	}{
+		{name: "blank frame has no text", frame: draw(0, 0, 20)},
+		{name: "identical subtitle is reused", frame: draw(40, 280, 20), hasText: true, duplicate: true},
+		{name: "slight brightness shift is reused", frame: draw(40, 280, 24), hasText: true, duplicate: true},
+		{name: "different subtitle is sent again", frame: draw(40, 140, 20), hasText: true},
+		{name: "subtitle replacing another of the same width is sent again", frame: draw(42, 282, 20), hasText: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deduplicator := NewDeduplicator(DefaultDedupDistance)
+			deduplicator.Remember(reference, 7)
+			signature := AnalyzeImage(test.frame)
+			if signature.HasText != test.hasText {
+				t.Fatalf("HasText = %v (density %.4f), want %v", signature.HasText, signature.EdgeDensity, test.hasText)
+			}
+			if !test.hasText {
+				return
+			}
+			source, ok := deduplicator.Lookup(signature)
+			if ok != test.duplicate || (ok && source != 7) {
+				t.Fatalf("Lookup = %d, %v (distance %d), want duplicate %v", source, ok, signature.Distance(reference), test.duplicate)
+			}
+		})
+	}
+	if _, ok := NewDeduplicator(DefaultDedupDistance).Lookup(reference); ok {
+		t.Fatal("empty deduplicator reported a duplicate")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/gayhub/4subs/internal/ocr"
 )
 
 type Client struct {
@@ -23,9 +25,14 @@ type Client struct {
 }
 
 type chatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []chatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Temperature    float64         `json:"temperature,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
 }
 
 type chatMessage struct {
@@ -39,6 +46,19 @@ type contentItem struct {
 	ImageURL map[string]any `json:"image_url,omitempty"`
 }
 
+type imageResponse struct {
+	Text       string   `json:"text"`
+	Confidence *float64 `json:"confidence"`
+}
+
+type batchResponse struct {
+	Frames []struct {
+		Index      int      `json:"index"`
+		Text       string   `json:"text"`
+		Confidence *float64 `json:"confidence"`
+	} `json:"frames"`
+}
+
 type chatCompletionResponse struct {
 	Choices []struct {
 		Message struct {
@@ -62,36 +82,135 @@ func (c Client) RecognizeImage(ctx context.Context, imagePath string) (string, f
 	if !c.Ready() {
 		return "", 0, errors.New("OCR 尚未配置，请先填写 OCR_API_KEY 与 OCR_MODEL")
 	}
-	raw, err := os.ReadFile(imagePath)
+	imageURL, err := imageDataURL(imagePath)
 	if err != nil {
 		return "", 0, err
 	}
-	mimeType := "image/png"
-	if ext := strings.ToLower(filepath.Ext(imagePath)); ext == ".jpg" || ext == ".jpeg" {
-		mimeType = "image/jpeg"
+	content, err := c.complete(ctx, []contentItem{
+		{Type: "text", Text: "你是视频字幕 OCR。请识别图片中的字幕文字，只返回 JSON：" +
+			`{"text":"字幕文字","confidence":0.95}` +
+			"。confidence 为 0 到 1 之间的小数，表示你对识别结果逐字正确的把握；字迹模糊、被遮挡或需要猜测时请给出较低的值。" +
+			"如果没有清晰可读字幕，text 为空字符串。保留必要换行，不要解释，不要输出代码块。" + languageHintText(ctx)},
+		{Type: "image_url", ImageURL: map[string]any{"url": imageURL}},
+	}, true)
+	if err != nil {
+		return "", 0, err
+	}
+	text, confidence := parseImageContent(content)
+	return text, confidence, nil
+}
+
+func languageHintText(ctx context.Context) string {
+	language := ocr.LanguageHint(ctx)
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("字幕原文语言为 %s，请按该语言的文字与标点识别，不要翻译。", language)
+}
+
+func parseImageContent(content string) (string, float64) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start >= 0 && end > start {
+		var payload imageResponse
+		if err := json.Unmarshal([]byte(content[start:end+1]), &payload); err == nil {
+			return strings.TrimSpace(payload.Text), clampConfidence(payload.Confidence)
+		}
+	}
+	return strings.TrimSpace(content), 0
+}
+
+func clampConfidence(value *float64) float64 {
+	if value == nil || *value <= 0 {
+		return 0
+	}
+	if *value > 1 {
+		if *value <= 100 {
+			return *value / 100
+		}
+		return 1
+	}
+	return *value
+}
+
+func (c Client) BatchSupported() bool {
+	return true
+}
+
+func (c Client) RecognizeBatch(ctx context.Context, imagePaths []string) ([]ocr.Result, error) {
+	if !c.Ready() {
+		return nil, errors.New("OCR 尚未配置，请先填写 OCR_API_KEY 与 OCR_MODEL")
+	}
+	items := make([]contentItem, 0, len(imagePaths)*2+1)
+	items = append(items, contentItem{Type: "text", Text: fmt.Sprintf(
+		"你是视频字幕 OCR。下面依次给出 %d 张字幕区域截图，编号 1 到 %d。请逐张识别字幕文字，只返回 JSON："+
+			`{"frames":[{"index":1,"text":"字幕文字","confidence":0.95}]}`+
+			"。每张图片都要返回一项；confidence 为 0 到 1 之间的小数，表示对该张识别结果逐字正确的把握，模糊或需要猜测时给出较低的值；"+
+			"没有清晰可读字幕时 text 为空字符串。保留必要换行，不要解释，不要输出代码块。%s",
+		len(imagePaths), len(imagePaths), languageHintText(ctx))})
+	for index, imagePath := range imagePaths {
+		imageURL, err := imageDataURL(imagePath)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items,
+			contentItem{Type: "text", Text: fmt.Sprintf("图片 %d：", index+1)},
+			contentItem{Type: "image_url", ImageURL: map[string]any{"url": imageURL}},
+		)
+	}
+	content, err := c.complete(ctx, items, true)
+	if err != nil {
+		return nil, err
+	}
+	return parseBatchContent(content, len(imagePaths))
+}
+
+func parseBatchContent(content string, count int) ([]ocr.Result, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("批量 OCR 未返回 JSON")
 	}
-	imageURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(raw)
+	var payload batchResponse
+	if err := json.Unmarshal([]byte(content[start:end+1]), &payload); err != nil {
+		return nil, fmt.Errorf("批量 OCR 结果解析失败: %w", err)
+	}
+	results := make([]ocr.Result, count)
+	seen := make([]bool, count)
+	for _, frame := range payload.Frames {
+		if frame.Index < 1 || frame.Index > count {
+			continue
+		}
+		results[frame.Index-1] = ocr.Result{Text: strings.TrimSpace(frame.Text), Confidence: clampConfidence(frame.Confidence)}
+		seen[frame.Index-1] = true
+	}
+	for index := range results {
+		if !seen[index] {
+			results[index].Err = ocr.ErrMissingBatchResult
+		}
+	}
+	return results, nil
+}
+
+func (c Client) complete(ctx context.Context, content []contentItem, jsonOutput bool) (string, error) {
 	requestBody := chatCompletionRequest{
 		Model: c.Model,
 		Messages: []chatMessage{
-			{
-				Role: "user",
-				Content: []contentItem{
-					{Type: "text", Text: "你是视频字幕 OCR。请只返回图片中的字幕文字；如果没有清晰可读字幕，返回空字符串。不要解释，不要添加标点说明，不要输出代码块。保留必要换行。"},
-					{Type: "image_url", ImageURL: map[string]any{"url": imageURL}},
-				},
-			},
+			{Role: "user", Content: content},
 		},
 		Temperature: 0,
 	}
+	if jsonOutput {
+		requestBody.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
 	body, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 	endpoint := strings.TrimRight(strings.TrimSpace(c.BaseURL), "/") + "/chat/completions"
 	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 	request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(c.APIKey))
 	request.Header.Set("Content-Type", "application/json")
@@ -101,23 +220,43 @@ func (c Client) RecognizeImage(ctx context.Context, imagePath string) (string, f
 	}
 	response, err := httpClient.Do(request)
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 	defer func() { _ = response.Body.Close() }()
 	responseBody, err := io.ReadAll(response.Body)
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 	var payload chatCompletionResponse
-	if err := json.Unmarshal(responseBody, &payload); err == nil && payload.Error != nil && payload.Error.Message != "" {
-		return "", 0, errors.New(payload.Error.Message)
-	}
+	decodeErr := json.Unmarshal(responseBody, &payload)
 	if response.StatusCode >= 400 {
-		return "", 0, fmt.Errorf("OCR 请求失败: %s", strings.TrimSpace(string(responseBody)))
+		message := strings.TrimSpace(string(responseBody))
+		if decodeErr == nil && payload.Error != nil && payload.Error.Message != "" {
+			message = payload.Error.Message
+		}
+		return "", &ocr.StatusError{
+			StatusCode: response.StatusCode,
+			RetryAfter: ocr.ParseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+			Message:    message,
+		}
+	}
+	if decodeErr == nil && payload.Error != nil && payload.Error.Message != "" {
+		return "", errors.New(payload.Error.Message)
 	}
 	if len(payload.Choices) == 0 {
-		return "", 0, errors.New("OCR 未返回可用结果")
+		return "", errors.New("OCR 未返回可用结果")
+	}
+	return payload.Choices[0].Message.Content, nil
+}
+
+func imageDataURL(imagePath string) (string, error) {
+	raw, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", err
+	}
+	mimeType := "image/png"
+	if ext := strings.ToLower(filepath.Ext(imagePath)); ext == ".jpg" || ext == ".jpeg" {
+		mimeType = "image/jpeg"
 	}
-	text := strings.TrimSpace(payload.Choices[0].Message.Content)
-	return text, 1, nil
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(raw), nil
 }
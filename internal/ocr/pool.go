@@ -0,0 +1,362 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxRetryAfter     = 2 * time.Minute
+	breakerWindow     = 20
+	breakerMinSamples = 10
+	breakerErrorRate  = 0.6
+)
+
+var ErrCircuitOpen = errors.New("OCR 错误率过高，已提前中止识别")
+
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("OCR 请求失败: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("OCR 请求失败: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+type PoolOptions struct {
+	Workers           int
+	RequestsPerSecond float64
+	MaxRetries        int
+	BatchSize         int
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+}
+
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		Workers:           4,
+		RequestsPerSecond: 4,
+		MaxRetries:        3,
+		BatchSize:         1,
+		BaseBackoff:       time.Second,
+		MaxBackoff:        30 * time.Second,
+	}
+}
+
+type Result struct {
+	Text       string
+	Confidence float64
+	Err        error
+}
+
+type PoolStats struct {
+	Requests int
+	Retries  int
+	Failed   int
+}
+
+type Pool struct {
+	options PoolOptions
+	slots   chan struct{}
+	mu      sync.Mutex
+	next    time.Time
+}
+
+func NewPool(options PoolOptions) *Pool {
+	defaults := DefaultPoolOptions()
+	if options.Workers <= 0 {
+		options.Workers = defaults.Workers
+	}
+	if options.RequestsPerSecond < 0 {
+		options.RequestsPerSecond = 0
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = defaults.BaseBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaults.MaxBackoff
+	}
+	if options.MaxBackoff < options.BaseBackoff {
+		options.MaxBackoff = options.BaseBackoff
+	}
+	return &Pool{options: options, slots: make(chan struct{}, options.Workers)}
+}
+
+func (p *Pool) RecognizeAll(ctx context.Context, provider Provider, paths []string) ([]Result, PoolStats, error) {
+	results := make([]Result, len(paths))
+	if len(paths) == 0 {
+		return results, PoolStats{}, nil
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	units := p.units(provider, len(paths))
+	tasks := make(chan []int)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		stats     PoolStats
+		outcomes  []bool
+		breakErr  error
+		firstFail error
+	)
+	count := func(attempts int) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats.Requests += attempts
+		if attempts > 1 {
+			stats.Retries += attempts - 1
+		}
+	}
+	record := func(index int, result Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[index] = result
+		if breakErr != nil {
+			return
+		}
+		failed := result.Err != nil
+		if failed {
+			stats.Failed++
+			if firstFail == nil {
+				firstFail = result.Err
+			}
+		}
+		outcomes = append(outcomes, failed)
+		if len(outcomes) > breakerWindow {
+			outcomes = outcomes[1:]
+		}
+		if len(outcomes) >= breakerMinSamples {
+			failures := 0
+			for _, outcome := range outcomes {
+				if outcome {
+					failures++
+				}
+			}
+			if float64(failures)/float64(len(outcomes)) >= breakerErrorRate {
+				breakErr = fmt.Errorf("%w（最近 %d 次中失败 %d 次）: %v", ErrCircuitOpen, len(outcomes), failures, firstFail)
+				cancel()
+			}
+		}
+	}
+	workers := p.options.Workers
+	if workers > len(units) {
+		workers = len(units)
+	}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for unit := range tasks {
+				if len(unit) > 1 {
+					batchPaths := make([]string, len(unit))
+					for position, index := range unit {
+						batchPaths[position] = paths[index]
+					}
+					batchResults, attempts := p.recognizeBatch(runCtx, provider.(BatchProvider), batchPaths)
+					count(attempts)
+					for position, index := range unit {
+						if batchResults != nil && !errors.Is(batchResults[position].Err, ErrMissingBatchResult) {
+							record(index, batchResults[position])
+							continue
+						}
+						result, attempts := p.recognize(runCtx, provider, paths[index])
+						count(attempts)
+						record(index, result)
+					}
+					continue
+				}
+				result, attempts := p.recognize(runCtx, provider, paths[unit[0]])
+				count(attempts)
+				record(unit[0], result)
+			}
+		}()
+	}
+dispatch:
+	for _, unit := range units {
+		select {
+		case tasks <- unit:
+		case <-runCtx.Done():
+			break dispatch
+		}
+	}
+	close(tasks)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, stats, err
+	}
+	if breakErr != nil {
+		return results, stats, breakErr
+	}
+	return results, stats, nil
+}
+
+func (p *Pool) units(provider Provider, count int) [][]int {
+	size := 1
+	if batcher, ok := provider.(BatchProvider); ok && batcher.BatchSupported() && p.options.BatchSize > 1 {
+		size = p.options.BatchSize
+	}
+	units := make([][]int, 0, (count+size-1)/size)
+	for start := 0; start < count; start += size {
+		end := start + size
+		if end > count {
+			end = count
+		}
+		unit := make([]int, 0, end-start)
+		for index := start; index < end; index++ {
+			unit = append(unit, index)
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+func (p *Pool) recognize(ctx context.Context, provider Provider, path string) (Result, int) {
+	var result Result
+	attempts, err := p.attempt(ctx, func() error {
+		text, confidence, err := provider.RecognizeImage(ctx, path)
+		result = Result{Text: text, Confidence: confidence}
+		return err
+	})
+	if err != nil {
+		return Result{Err: err}, attempts
+	}
+	return result, attempts
+}
+
+func (p *Pool) recognizeBatch(ctx context.Context, provider BatchProvider, paths []string) ([]Result, int) {
+	var results []Result
+	attempts, err := p.attempt(ctx, func() error {
+		recognized, err := provider.RecognizeBatch(ctx, paths)
+		if err == nil && len(recognized) != len(paths) {
+			err = errors.New("批量 OCR 返回数量与图片数量不一致")
+		}
+		results = recognized
+		return err
+	})
+	if err != nil {
+		return nil, attempts
+	}
+	return results, attempts
+}
+
+func (p *Pool) attempt(ctx context.Context, call func() error) (int, error) {
+	attempts := 0
+	for {
+		if err := p.acquire(ctx); err != nil {
+			return attempts, err
+		}
+		err := call()
+		<-p.slots
+		attempts++
+		if err == nil {
+			return attempts, nil
+		}
+		wait, retryable := p.backoff(err, attempts-1)
+		if !retryable || attempts > p.options.MaxRetries || ctx.Err() != nil {
+			return attempts, err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Pool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if p.options.RequestsPerSecond <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / p.options.RequestsPerSecond)
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(interval)
+	p.mu.Unlock()
+	if wait := at.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			<-p.slots
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+func (p *Pool) backoff(err error, attempt int) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if !statusErr.Retryable() {
+			return 0, false
+		}
+	} else {
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			return 0, false
+		}
+	}
+	wait := p.options.BaseBackoff << uint(attempt)
+	if wait > p.options.MaxBackoff || wait <= 0 {
+		wait = p.options.MaxBackoff
+	}
+	wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+	if statusErr != nil && statusErr.RetryAfter > wait {
+		wait = statusErr.RetryAfter
+		if wait > maxRetryAfter {
+			wait = maxRetryAfter
+		}
+	}
+	return wait, true
+}
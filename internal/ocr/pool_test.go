@@ -0,0 +1,180 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	mu        sync.Mutex
+	calls     map[string]int
+	recognize func(path string, call int) (string, error)
+}
+
+func (s *stubProvider) Name() string { return "stub" }
+
+func (s *stubProvider) Ready() bool { return true }
+
+func (s *stubProvider) RecognizeImage(ctx context.Context, path string) (string, float64, error) {
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = map[string]int{}
+	}
+	s.calls[path]++
+	call := s.calls[path]
+	s.mu.Unlock()
+	text, err := s.recognize(path, call)
+	return text, 0.9, err
+}
+
+func TestPoolRecognizeAll(t *testing.T) {
+	tests := []struct {
+		name      string
+		paths     int
+		recognize func(path string, call int) (string, error)
+		requests  int
+		retries   int
+		failed    int
+		minWait   time.Duration
+		circuit   bool
+	}{
+		{
+			name:      "all succeed in order",
+			paths:     6,
+			recognize: func(path string, call int) (string, error) { return "text-" + path, nil },
+			requests:  6,
+		},
+		{
+			name:  "429 honours retry-after",
+			paths: 1,
+			recognize: func(path string, call int) (string, error) {
+				if call == 1 {
+					return "", &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 80 * time.Millisecond}
+				}
+				return "text-" + path, nil
+			},
+			requests: 2,
+			retries:  1,
+			minWait:  80 * time.Millisecond,
+		},
+		{
+			name:  "5xx retries until the limit",
+			paths: 1,
+			recognize: func(path string, call int) (string, error) {
+				return "", &StatusError{StatusCode: http.StatusBadGateway}
+			},
+			requests: 3,
+			retries:  2,
+			failed:   1,
+		},
+		{
+			name:  "4xx is not retried",
+			paths: 2,
+			recognize: func(path string, call int) (string, error) {
+				if path == "0" {
+					return "", &StatusError{StatusCode: http.StatusBadRequest}
+				}
+				return "text-" + path, nil
+			},
+			requests: 2,
+			failed:   1,
+		},
+		{
+			name:  "plain errors are not retried",
+			paths: 1,
+			recognize: func(path string, call int) (string, error) {
+				return "", errors.New("decode failed")
+			},
+			requests: 1,
+			failed:   1,
+		},
+		{
+			name:  "high error rate opens the circuit",
+			paths: 40,
+			recognize: func(path string, call int) (string, error) {
+				return "", &StatusError{StatusCode: http.StatusUnauthorized}
+			},
+			circuit: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := make([]string, test.paths)
+			for index := range paths {
+				paths[index] = string(rune('0' + index))
+			}
+			provider := &stubProvider{recognize: test.recognize}
+			pool := NewPool(PoolOptions{Workers: 2, MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+			started := time.Now()
+			results, stats, err := pool.RecognizeAll(context.Background(), provider, paths)
+			if test.circuit {
+				if !errors.Is(err, ErrCircuitOpen) {
+					t.Fatalf("expected circuit error, got %v", err)
+				}
+				if stats.Requests >= test.paths {
+					t.Fatalf("circuit did not stop early: %d requests", stats.Requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Requests != test.requests || stats.Retries != test.retries || stats.Failed != test.failed {
+				t.Fatalf("stats %+v, want requests %d retries %d failed %d", stats, test.requests, test.retries, test.failed)
+			}
+			if elapsed := time.Since(started); elapsed < test.minWait {
+				t.Fatalf("finished after %s, want at least %s", elapsed, test.minWait)
+			}
+			for index, result := range results {
+				if result.Err == nil && result.Text != "text-"+paths[index] {
+					t.Fatalf("result %d is %q", index, result.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	provider := &stubProvider{recognize: func(path string, call int) (string, error) { return path, nil }}
+	pool := NewPool(PoolOptions{Workers: 4, RequestsPerSecond: 50})
+	started := time.Now()
+	if _, _, err := pool.RecognizeAll(context.Background(), provider, []string{"a", "b", "c", "d", "e", "f"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Fatalf("6 requests at 50/s finished in %s", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "0", want: 0},
+		{value: "-5", want: 0},
+		{value: now.Add(10 * time.Second).Format(http.TimeFormat), want: 10 * time.Second},
+		{value: now.Add(-10 * time.Second).Format(http.TimeFormat), want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, test := range tests {
+		if got := ParseRetryAfter(test.value, now); got != test.want {
+			t.Fatalf("ParseRetryAfter(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestStatusErrorMessage(t *testing.T) {
+	err := &StatusError{StatusCode: 503, Message: "busy"}
+	if !err.Retryable() || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("unexpected status error %v", err)
+	}
+}
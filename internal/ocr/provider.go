@@ -1,9 +1,36 @@
 package ocr
 
-import "context"
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrMissingBatchResult = errors.New("批量 OCR 结果中缺少该图片")
+
+type languageHintKey struct{}
+
+func WithLanguageHint(ctx context.Context, language string) context.Context {
+	language = strings.TrimSpace(language)
+	if language == "" || strings.EqualFold(language, "auto") {
+		return ctx
+	}
+	return context.WithValue(ctx, languageHintKey{}, language)
+}
+
+func LanguageHint(ctx context.Context) string {
+	language, _ := ctx.Value(languageHintKey{}).(string)
+	return language
+}
 
 type Provider interface {
 	Name() string
 	Ready() bool
 	RecognizeImage(ctx context.Context, imagePath string) (string, float64, error)
 }
+
+type BatchProvider interface {
+	Provider
+	BatchSupported() bool
+	RecognizeBatch(ctx context.Context, imagePaths []string) ([]Result, error)
+}
@@ -4,35 +4,60 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gayhub/4subs/internal/subtitle"
 )
 
 type Observation struct {
 	At         time.Duration `json:"at"`
+	End        time.Duration `json:"end,omitempty"`
 	Text       string        `json:"text"`
 	Confidence float64       `json:"confidence"`
 }
 
 type TimelineOptions struct {
-	MinDuration     time.Duration
-	MaxJoinGap      time.Duration
-	MinTextLength   int
-	StableFrames    int
-	ConfidenceFloor float64
+	MinDuration         time.Duration
+	MaxJoinGap          time.Duration
+	MinTextLength       int
+	StableFrames        int
+	ConfidenceFloor     float64
+	SimilarityThreshold float64
+	MaxEditDistance     int
 }
 
 func DefaultTimelineOptions() TimelineOptions {
 	return TimelineOptions{
-		MinDuration:     800 * time.Millisecond,
-		MaxJoinGap:      400 * time.Millisecond,
-		MinTextLength:   1,
-		StableFrames:    2,
-		ConfidenceFloor: 0.45,
+		MinDuration:         800 * time.Millisecond,
+		MaxJoinGap:          400 * time.Millisecond,
+		MinTextLength:       1,
+		StableFrames:        2,
+		ConfidenceFloor:     0.45,
+		SimilarityThreshold: 0.8,
+		MaxEditDistance:     4,
 	}
 }
 
+type Cue struct {
+	Block      subtitle.Block
+	Confidence float64
+	Agreement  float64
+	Frames     int
+}
+
 func BuildBlocks(observations []Observation, options TimelineOptions) []subtitle.Block {
+	cues := BuildCues(observations, options)
+	if len(cues) == 0 {
+		return nil
+	}
+	blocks := make([]subtitle.Block, len(cues))
+	for index, cue := range cues {
+		blocks[index] = cue.Block
+	}
+	return blocks
+}
+
+func BuildCues(observations []Observation, options TimelineOptions) []Cue {
 	if options.MinDuration <= 0 {
 		options.MinDuration = 800 * time.Millisecond
 	}
@@ -42,38 +67,154 @@ func BuildBlocks(observations []Observation, options TimelineOptions) []subtitle
 	if options.StableFrames <= 0 {
 		options.StableFrames = 2
 	}
+	if options.SimilarityThreshold <= 0 || options.SimilarityThreshold > 1 {
+		options.SimilarityThreshold = 1
+	}
 	filtered := normalizeObservations(observations, options)
 	if len(filtered) == 0 {
 		return nil
 	}
-	blocks := make([]subtitle.Block, 0, len(filtered))
-	current := filtered[0]
-	count := 1
+	cues := make([]Cue, 0, len(filtered))
+	current := newObservationGroup(filtered[0])
 	index := 1
 	for i := 1; i < len(filtered); i++ {
 		next := filtered[i]
-		if next.Text == current.Text && next.At-current.End <= options.MaxJoinGap {
-			current.End = next.At
-			count++
+		if next.At-current.end <= options.MaxJoinGap && current.matches(next.Text, options) {
+			current.add(next)
 			continue
 		}
-		if count >= options.StableFrames {
-			blocks = append(blocks, makeBlock(index, current, options.MinDuration))
+		if current.count >= options.StableFrames || current.span {
+			cues = append(cues, current.cue(index, options.MinDuration))
 			index++
 		}
-		current = next
-		count = 1
+		current = newObservationGroup(next)
+	}
+	if current.count >= options.StableFrames || current.span {
+		cues = append(cues, current.cue(index, options.MinDuration))
+	}
+	return compactCues(cues, options)
+}
+
+type textVariant struct {
+	text       string
+	count      int
+	confidence float64
+	rated      int
+	duration   time.Duration
+	order      int
+}
+
+type observationGroup struct {
+	at       time.Duration
+	end      time.Duration
+	span     bool
+	count    int
+	last     string
+	variants []*textVariant
+}
+
+func newObservationGroup(item normalizedObservation) *observationGroup {
+	group := &observationGroup{at: item.At, end: item.End}
+	group.add(item)
+	return group
+}
+
+func (g *observationGroup) add(item normalizedObservation) {
+	if item.End > g.end {
+		g.end = item.End
+	}
+	g.span = g.span || item.Span
+	g.count++
+	g.last = item.Text
+	for _, variant := range g.variants {
+		if variant.text == item.Text {
+			variant.record(item)
+			return
+		}
+	}
+	variant := &textVariant{text: item.Text, order: len(g.variants)}
+	variant.record(item)
+	g.variants = append(g.variants, variant)
+}
+
+func (v *textVariant) record(item normalizedObservation) {
+	v.count++
+	v.duration += item.End - item.At
+	if item.Confidence > 0 {
+		v.confidence += item.Confidence
+		v.rated++
+	}
+}
+
+func (g *observationGroup) bestVariant() *textVariant {
+	best := g.variants[0]
+	for _, variant := range g.variants[1:] {
+		if variant.better(best) {
+			best = variant
+		}
+	}
+	return best
+}
+
+func (g *observationGroup) best() string {
+	return g.bestVariant().text
+}
+
+func (v *textVariant) better(other *textVariant) bool {
+	if v.count != other.count {
+		return v.count > other.count
+	}
+	if v.duration != other.duration {
+		return v.duration > other.duration
+	}
+	if v.meanConfidence() != other.meanConfidence() {
+		return v.meanConfidence() > other.meanConfidence()
+	}
+	return v.order < other.order
+}
+
+func (g *observationGroup) matches(text string, options TimelineOptions) bool {
+	return similarText(text, g.best(), options) || similarText(text, g.last, options)
+}
+
+func (v *textVariant) meanConfidence() float64 {
+	if v.rated == 0 {
+		return 0
+	}
+	return v.confidence / float64(v.rated)
+}
+
+func (g *observationGroup) cue(index int, minDuration time.Duration) Cue {
+	best := g.bestVariant()
+	cue := Cue{
+		Block:      makeBlock(index, normalizedObservation{At: g.at, End: g.end, Text: best.text, Span: g.span}, minDuration),
+		Confidence: best.meanConfidence(),
+		Frames:     g.count,
 	}
-	if count >= options.StableFrames {
-		blocks = append(blocks, makeBlock(index, current, options.MinDuration))
+	if g.count > 1 {
+		key := comparableText(best.text)
+		agreeing := 0
+		for _, variant := range g.variants {
+			if variant.text == best.text || comparableText(variant.text) == key {
+				agreeing += variant.count
+			}
+		}
+		cue.Agreement = float64(agreeing) / float64(g.count)
+		if cue.Confidence > 0 {
+			cue.Confidence *= cue.Agreement
+		} else {
+			cue.Confidence = cue.Agreement
+		}
 	}
-	return compactBlocks(blocks, options.MaxJoinGap)
+	return cue
 }
 
 type normalizedObservation struct {
-	At   time.Duration
-	End  time.Duration
-	Text string
+	At         time.Duration
+	End        time.Duration
+	Text       string
+	Confidence float64
+	Span       bool
 }
 
 func normalizeObservations(observations []Observation, options TimelineOptions) []normalizedObservation {
@@ -97,13 +238,25 @@ func normalizeObservations(observations []Observation, options TimelineOptions)
 	})
 	result := make([]normalizedObservation, 0, len(items))
 	for _, item := range items {
-		result = append(result, normalizedObservation{At: item.At, End: item.At, Text: item.Text})
+		if item.End > item.At {
+			result = append(result, normalizedObservation{At: item.At, End: item.End, Text: item.Text, Confidence: item.Confidence, Span: true})
+			continue
+		}
+		result = append(result, normalizedObservation{At: item.At, End: item.At, Text: item.Text, Confidence: item.Confidence})
 	}
 	return result
 }
 
 func makeBlock(index int, item normalizedObservation, minDuration time.Duration) subtitle.Block {
 	end := item.End
+	if item.Span && end > item.At {
+		return subtitle.Block{
+			Index: index,
+			Start: item.At,
+			End:   end,
+			Lines: strings.Split(item.Text, "\n"),
+		}
+	}
 	if end <= item.At {
 		end = item.At + minDuration
 	}
@@ -118,32 +271,113 @@ func makeBlock(index int, item normalizedObservation, minDuration time.Duration)
 	}
 }
 
-func compactBlocks(blocks []subtitle.Block, maxJoinGap time.Duration) []subtitle.Block {
-	if len(blocks) == 0 {
+func compactCues(cues []Cue, options TimelineOptions) []Cue {
+	if len(cues) == 0 {
 		return nil
 	}
-	result := make([]subtitle.Block, 0, len(blocks))
-	current := blocks[0]
-	for i := 1; i < len(blocks); i++ {
-		next := blocks[i]
-		if joinable(current, next, maxJoinGap) {
-			current.End = next.End
+	result := make([]Cue, 0, len(cues))
+	current := cues[0]
+	for i := 1; i < len(cues); i++ {
+		next := cues[i]
+		if joinable(current.Block, next.Block, options) {
+			if next.Block.End-next.Block.Start > current.Block.End-current.Block.Start {
+				current.Block.Lines = next.Block.Lines
+			}
+			current.Block.End = next.Block.End
+			current.Confidence = lowerConfidence(current.Confidence, next.Confidence)
+			current.Agreement = lowerConfidence(current.Agreement, next.Agreement)
+			current.Frames += next.Frames
 			continue
 		}
-		current.Index = len(result) + 1
+		current.Block.Index = len(result) + 1
 		result = append(result, current)
 		current = next
 	}
-	current.Index = len(result) + 1
+	current.Block.Index = len(result) + 1
 	result = append(result, current)
 	return result
 }
 
-func joinable(left subtitle.Block, right subtitle.Block, maxJoinGap time.Duration) bool {
-	if cleanText(strings.Join(left.Lines, "\n")) != cleanText(strings.Join(right.Lines, "\n")) {
+func lowerConfidence(left float64, right float64) float64 {
+	if left <= 0 {
+		return right
+	}
+	if right <= 0 || left < right {
+		return left
+	}
+	return right
+}
+
+func joinable(left subtitle.Block, right subtitle.Block, options TimelineOptions) bool {
+	if right.Start-left.End > options.MaxJoinGap {
 		return false
 	}
-	return right.Start-left.End <= maxJoinGap
+	return similarText(cleanText(strings.Join(left.Lines, "\n")), cleanText(strings.Join(right.Lines, "\n")), options)
+}
+
+func similarText(left string, right string, options TimelineOptions) bool {
+	if left == right {
+		return true
+	}
+	if options.SimilarityThreshold >= 1 {
+		return false
+	}
+	a := []rune(comparableText(left))
+	b := []rune(comparableText(right))
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	limit := int(float64(longest) * (1 - options.SimilarityThreshold))
+	if options.MaxEditDistance > 0 && options.MaxEditDistance < limit {
+		limit = options.MaxEditDistance
+	}
+	distance, ok := boundedEditDistance(a, b, limit)
+	return ok && 1-float64(distance)/float64(longest) >= options.SimilarityThreshold
+}
+
+func comparableText(value string) string {
+	var builder strings.Builder
+	for _, r := range value {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			continue
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+func boundedEditDistance(a []rune, b []rune, limit int) (int, bool) {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return 0, false
+	}
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if current[j] < rowMin {
+				rowMin = current[j]
+			}
+		}
+		if rowMin > limit {
+			return 0, false
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)], previous[len(b)] <= limit
 }
 
 func cleanText(value string) string {
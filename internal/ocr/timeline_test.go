@@ -0,0 +1,142 @@
+package ocr
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildCues(t *testing.T) {
+	second := func(value float64) time.Duration {
+		return time.Duration(math.Round(value*1000)) * time.Millisecond
+	}
+	frames := func(start float64, texts ...string) []Observation {
+		observations := make([]Observation, len(texts))
+		for index, text := range texts {
+			observations[index] = Observation{At: second(start + float64(index)*0.25), Text: text, Confidence: 0.9}
+		}
+		return observations
+	}
+	type want struct {
+		text  string
+		start time.Duration
+		end   time.Duration
+	}
+	tests := []struct {
+		name         string
+		observations []Observation
+		options      func(*TimelineOptions)
+		want         []want
+	}{
+		{
+			name:         "one misread character stays in one cue",
+			observations: frames(1, "Where are you going", "Where are you going", "Wbere are you going", "Where are you going"),
+			want:         []want{{"Where are you going", second(1), second(1.8)}},
+		},
+		{
+			name:         "majority variant wins even when it appears later",
+			observations: frames(1, "He1lo world", "Hello world", "Hello world"),
+			want:         []want{{"Hello world", second(1), second(1.8)}},
+		},
+		{
+			name:         "different text starts a new cue",
+			observations: frames(1, "Good morning", "Good morning", "See you tomorrow", "See you tomorrow"),
+			want:         []want{{"Good morning", second(1), second(1.8)}, {"See you tomorrow", second(1.5), second(2.3)}},
+		},
+		{
+			name:         "exact matching keeps a misread fragment apart",
+			observations: frames(1, "Where are you going", "Where are you going", "Wbere are you going", "Wbere are you going"),
+			options: func(options *TimelineOptions) {
+				options.SimilarityThreshold = 1
+			},
+			want: []want{{"Where are you going", second(1), second(1.8)}, {"Wbere are you going", second(1.5), second(2.3)}},
+		},
+		{
+			name:         "single unstable frame is dropped",
+			observations: frames(1, "Noise", "Hello world", "Hello world"),
+			want:         []want{{"Hello world", second(1.25), second(2.05)}},
+		},
+		{
+			name: "low confidence frames are ignored",
+			observations: []Observation{
+				{At: second(1), Text: "Hello", Confidence: 0.2},
+				{At: second(1.25), Text: "Hello", Confidence: 0.2},
+			},
+		},
+		{
+			name: "gap larger than the join window splits identical text",
+			observations: []Observation{
+				{At: second(1), Text: "Again", Confidence: 0.9},
+				{At: second(1.25), Text: "Again", Confidence: 0.9},
+				{At: second(5), Text: "Again", Confidence: 0.9},
+				{At: second(5.25), Text: "Again", Confidence: 0.9},
+			},
+			want: []want{{"Again", second(1), second(1.8)}, {"Again", second(5), second(5.8)}},
+		},
+		{
+			name: "spans keep their own end time",
+			observations: []Observation{
+				{At: second(1), End: second(3.5), Text: "Long line", Confidence: 0.9},
+			},
+			want: []want{{"Long line", second(1), second(3.5)}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := DefaultTimelineOptions()
+			if test.options != nil {
+				test.options(&options)
+			}
+			cues := BuildCues(test.observations, options)
+			if len(cues) != len(test.want) {
+				t.Fatalf("got %d cues, want %d: %+v", len(cues), len(test.want), cues)
+			}
+			for index, cue := range cues {
+				text := strings.Join(cue.Block.Lines, "\n")
+				if cue.Block.Index != index+1 || text != test.want[index].text || cue.Block.Start != test.want[index].start || cue.Block.End != test.want[index].end {
+					t.Fatalf("cue %d is %d %q %s-%s, want %q %s-%s", index, cue.Block.Index, text, cue.Block.Start, cue.Block.End, test.want[index].text, test.want[index].start, test.want[index].end)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildCuesAgreement(t *testing.T) {
+	cues := BuildCues([]Observation{
+		{At: time.Second, Text: "Hello world", Confidence: 0.8},
+		{At: 1250 * time.Millisecond, Text: "He1lo world", Confidence: 0.8},
+		{At: 1500 * time.Millisecond, Text: "Hello world", Confidence: 0.8},
+		{At: 1750 * time.Millisecond, Text: "Hello world", Confidence: 0.8},
+	}, DefaultTimelineOptions())
+	if len(cues) != 1 {
+		t.Fatalf("got %d cues", len(cues))
+	}
+	if cues[0].Frames != 4 || cues[0].Agreement != 0.75 {
+		t.Fatalf("frames %d agreement %.2f, want 4 and 0.75", cues[0].Frames, cues[0].Agreement)
+	}
+	if want := 0.8 * 0.75; cues[0].Confidence < want-1e-9 || cues[0].Confidence > want+1e-9 {
+		t.Fatalf("confidence %.3f, want %.3f", cues[0].Confidence, want)
+	}
+}
+
+func TestSimilarText(t *testing.T) {
+	options := DefaultTimelineOptions()
+	tests := []struct {
+		left    string
+		right   string
+		similar bool
+	}{
+		{"你好世界", "你好世界", true},
+		{"Hello, world!", "hello world", true},
+		{"Where are you going", "Wbere are you going", true},
+		{"Yes", "No", false},
+		{"你好", "再见", false},
+		{"This is a long sentence here", "Totally different words now", false},
+	}
+	for _, test := range tests {
+		if got := similarText(test.left, test.right, options); got != test.similar {
+			t.Fatalf("similarText(%q, %q) = %v, want %v", test.left, test.right, got, test.similar)
+		}
+	}
+}
@@ -13,13 +13,19 @@ func DefaultSteps() []model.PipelineStep {
 		{
 			Key:         "extract_subtitle",
 			Title:       "文本字幕提取",
-			Description: "优先读取同名外挂字幕；若存在文本字幕轨，则提取并转成标准 SRT。",
+			Description: "按任务或默认的字幕来源策略顺序（外挂字幕、内嵌文本字幕轨、图形字幕轨 OCR、硬字幕抽帧 OCR、ASR）依次尝试，取得源字幕即停止并记录实际生效的策略；外挂字幕按语言标记与 Subs 目录布局挑选，文本字幕轨按源语言挑选（或使用任务指定的轨道）。",
 			Owner:       "ffmpeg",
 		},
+		{
+			Key:         "sync_subtitle",
+			Title:       "音频同步",
+			Description: "可选：对外挂字幕做本地语音活动检测，估算整体偏移与漂移并校正时间轴。",
+			Owner:       "音频同步模块",
+		},
 		{
 			Key:         "ocr_extract",
 			Title:       "OCR 抽帧",
-			Description: "当找不到文本字幕时，截取底部字幕区域关键帧，准备交给远程 OCR。",
+			Description: "若视频带图形字幕轨（PGS/VobSub/DVB），按每条字幕事件渲染图片；否则截取底部字幕区域关键帧，准备交给远程 OCR。",
 			Owner:       "ffmpeg",
 		},
 		{
@@ -28,10 +34,16 @@ func DefaultSteps() []model.PipelineStep {
 			Description: "调用远程视觉 API 识别硬字幕，并恢复为带时间轴的字幕块。",
 			Owner:       "OCR 适配层",
 		},
+		{
+			Key:         "fuse_sources",
+			Title:       "OCR / ASR 融合",
+			Description: "可选：硬字幕 OCR 成功后再做一次 ASR，用字符级序列比对把两者对齐；保留 OCR 文字并按 ASR 词或片段边界校正时间轴，差异较大的字幕标记到质检报告待人工核对。",
+			Owner:       "融合模块",
+		},
 		{
 			Key:         "extract_audio",
 			Title:       "音频提取",
-			Description: "如果 OCR 仍不可用或失败，则从视频中提取单声道语音音频。",
+			Description: "轮到 ASR 策略时，从视频中提取单声道语音音频。",
 			Owner:       "ffmpeg",
 		},
 		{
@@ -40,6 +52,18 @@ func DefaultSteps() []model.PipelineStep {
 			Description: "调用 OpenAI 兼容音频转写接口，返回带 segment 时间戳的字幕块。",
 			Owner:       "ASR 适配层",
 		},
+		{
+			Key:         "diarize",
+			Title:       "说话人分离",
+			Description: "可选：调用 HTTP 说话人分离服务，按时间重叠为每条字幕标注说话人，供翻译参考并写入 ASS Name 字段或 SRT 对话破折号。",
+			Owner:       "说话人分离适配层",
+		},
+		{
+			Key:         "detect_language",
+			Title:       "源语言识别",
+			Description: "优先采用 ASR 返回的语言，否则按文字脚本与常用词识别源字幕语言并写回任务；源语言与目标语言相同时跳过翻译。",
+			Owner:       "后端服务",
+		},
 		{
 			Key:         "translate",
 			Title:       "DeepSeek 翻译",
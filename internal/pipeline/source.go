@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gayhub/4subs/internal/model"
+)
+
+const (
+	SourceSidecar      = "sidecar"
+	SourceEmbeddedText = "embedded_text"
+	SourceBitmapOCR    = "bitmap_ocr"
+	SourceFrameOCR     = "frame_ocr"
+	SourceASR          = "asr"
+)
+
+var sourceStrategyOrder = []string{SourceSidecar, SourceEmbeddedText, SourceBitmapOCR, SourceFrameOCR, SourceASR}
+
+var sourceStrategyTitles = map[string]string{
+	SourceSidecar:      "外挂字幕",
+	SourceEmbeddedText: "内嵌文本字幕轨",
+	SourceBitmapOCR:    "图形字幕轨 OCR",
+	SourceFrameOCR:     "硬字幕抽帧 OCR",
+	SourceASR:          "ASR 语音转写",
+}
+
+func DefaultSourceStrategies() []model.SourceStrategy {
+	strategies := make([]model.SourceStrategy, 0, len(sourceStrategyOrder))
+	for _, key := range sourceStrategyOrder {
+		strategies = append(strategies, model.SourceStrategy{Key: key, Enabled: true})
+	}
+	return strategies
+}
+
+func SourceStrategyTitle(key string) string {
+	if title, ok := sourceStrategyTitles[key]; ok {
+		return title
+	}
+	return key
+}
+
+func NormalizeSourceStrategies(strategies []model.SourceStrategy) []model.SourceStrategy {
+	if len(strategies) == 0 {
+		return DefaultSourceStrategies()
+	}
+	result := make([]model.SourceStrategy, 0, len(sourceStrategyOrder))
+	seen := map[string]bool{}
+	for _, strategy := range strategies {
+		strategy.Key = strings.ToLower(strings.TrimSpace(strategy.Key))
+		if _, ok := sourceStrategyTitles[strategy.Key]; !ok || seen[strategy.Key] {
+			continue
+		}
+		seen[strategy.Key] = true
+		result = append(result, strategy)
+	}
+	for _, key := range sourceStrategyOrder {
+		if !seen[key] {
+			result = append(result, model.SourceStrategy{Key: key})
+		}
+	}
+	return result
+}
+
+func NormalizeSourceStrategyKeys(keys []string) ([]string, error) {
+	result := make([]string, 0, len(keys))
+	seen := map[string]bool{}
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" || seen[key] {
+			continue
+		}
+		if _, ok := sourceStrategyTitles[key]; !ok {
+			return nil, fmt.Errorf("未知的字幕来源策略: %s，可选 %s", key, strings.Join(sourceStrategyOrder, ", "))
+		}
+		seen[key] = true
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func EnabledSourceStrategies(strategies []model.SourceStrategy) []string {
+	keys := make([]string, 0, len(strategies))
+	for _, strategy := range NormalizeSourceStrategies(strategies) {
+		if strategy.Enabled {
+			keys = append(keys, strategy.Key)
+		}
+	}
+	return keys
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -15,12 +17,17 @@ import (
 	openaiasr "github.com/gayhub/4subs/internal/asr/openai"
 	"github.com/gayhub/4subs/internal/config"
 	"github.com/gayhub/4subs/internal/db"
+	"github.com/gayhub/4subs/internal/jobdata"
 	"github.com/gayhub/4subs/internal/joblog"
 	"github.com/gayhub/4subs/internal/jobrunner"
 	"github.com/gayhub/4subs/internal/library"
+	"github.com/gayhub/4subs/internal/media"
 	"github.com/gayhub/4subs/internal/model"
+	ocrprovider "github.com/gayhub/4subs/internal/ocr"
+	ocrcommand "github.com/gayhub/4subs/internal/ocr/command"
 	openaivision "github.com/gayhub/4subs/internal/ocr/openai"
 	"github.com/gayhub/4subs/internal/pipeline"
+	"github.com/gayhub/4subs/internal/subtitle"
 	"github.com/gayhub/4subs/internal/translator/deepseek"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -31,27 +38,42 @@ type Server struct {
 	repo       *db.Repository
 	translator deepseek.Client
 	asr        openaiasr.Client
-	ocr        openaivision.Client
+	ocr        ocrprovider.Provider
 	runner     *jobrunner.Runner
 	logger     *joblog.Store
+	data       *jobdata.Store
 }
 
 type createJobRequest struct {
-	MediaAssetID   *int64   `json:"media_asset_id"`
-	MediaPath      string   `json:"media_path"`
-	FileName       string   `json:"file_name"`
-	SourceLanguage string   `json:"source_language"`
-	TargetLanguage string   `json:"target_language"`
-	OutputFormats  []string `json:"output_formats"`
-	Details        string   `json:"details"`
+	MediaAssetID   *int64            `json:"media_asset_id"`
+	MediaPath      string            `json:"media_path"`
+	FileName       string            `json:"file_name"`
+	SourceLanguage string            `json:"source_language"`
+	TargetLanguage string            `json:"target_language"`
+	OutputFormats  []string          `json:"output_formats"`
+	StreamIndex    *int              `json:"subtitle_stream_index"`
+	OCRRegions     []model.OCRRegion `json:"ocr_regions"`
+	Strategies     []string          `json:"source_strategies"`
+	Details        string            `json:"details"`
 }
 
+type retryJobRequest struct {
+	SourceStrategies *[]string `json:"source_strategies"`
+}
+
+type ocrRegionsRequest struct {
+	Regions []model.OCRRegion `json:"regions"`
+}
+
+const maxUploadSubtitleBytes = 10 << 20
+
 type previewResponse struct {
 	Kind     string `json:"kind"`
 	Path     string `json:"path"`
 	Exists   bool   `json:"exists"`
 	Editable bool   `json:"editable"`
 	Content  string `json:"content"`
+	Warning  string `json:"warning,omitempty"`
 }
 
 type previewSaveRequest struct {
@@ -60,12 +82,25 @@ type previewSaveRequest struct {
 
 func New(cfg config.Config, repo *db.Repository) *Server {
 	translatorClient := deepseek.Client{BaseURL: cfg.DeepSeekBaseURL, APIKey: cfg.DeepSeekAPIKey, Model: cfg.DeepSeekModel}
-	asrClient := openaiasr.Client{BaseURL: cfg.ASRBaseURL, APIKey: cfg.ASRAPIKey, Model: cfg.ASRModel}
-	ocrClient := openaivision.Client{BaseURL: cfg.OCRBaseURL, APIKey: cfg.OCRAPIKey, Model: cfg.OCRModel}
+	asrClient := openaiasr.Client{
+		BaseURL:        cfg.ASRBaseURL,
+		APIKey:         cfg.ASRAPIKey,
+		Model:          cfg.ASRModel,
+		WordTimestamps: cfg.ASRWordTimestamps,
+	}
+	ocrClient := newOCRProvider(cfg)
 	logger := joblog.New(cfg.WorkDir)
-	runner := jobrunner.New(cfg, repo, translatorClient, asrClient, ocrClient, logger)
+	data := jobdata.New(cfg.WorkDir)
+	runner := jobrunner.New(cfg, repo, translatorClient, asrClient, ocrClient, logger, data)
 	runner.ResumePending(context.Background())
-	return &Server{cfg: cfg, repo: repo, translator: translatorClient, asr: asrClient, ocr: ocrClient, runner: runner, logger: logger}
+	return &Server{cfg: cfg, repo: repo, translator: translatorClient, asr: asrClient, ocr: ocrClient, runner: runner, logger: logger, data: data}
+}
+
+func newOCRProvider(cfg config.Config) ocrprovider.Provider {
+	if cfg.OCRProvider == config.OCRProviderCommand {
+		return ocrcommand.Client{CommandLine: cfg.OCRCommand, Timeout: time.Duration(cfg.OCRCommandTimeoutSec) * time.Second}
+	}
+	return openaivision.Client{BaseURL: cfg.OCRBaseURL, APIKey: cfg.OCRAPIKey, Model: cfg.OCRModel}
 }
 
 func (s *Server) Routes() http.Handler {
@@ -82,11 +117,23 @@ func (s *Server) Routes() http.Handler {
 		api.Get("/pipeline", s.handlePipeline)
 		api.Get("/settings", s.handleGetSettings)
 		api.Put("/settings", s.handleSaveSettings)
+		api.Get("/ocr/cache", s.handleGetOCRCache)
+		api.Delete("/ocr/cache", s.handleClearOCRCache)
 		api.Get("/media", s.handleListMedia)
 		api.Post("/media/scan", s.handleScanMedia)
+		api.Get("/media/{id}/streams", s.handleGetMediaStreams)
+		api.Get("/media/{id}/ocr-regions", s.handleGetMediaOCRRegions)
+		api.Put("/media/{id}/ocr-regions", s.handleSaveMediaOCRRegions)
+		api.Post("/media/{id}/ocr-regions/detect", s.handleDetectMediaOCRRegions)
 		api.Get("/jobs", s.handleListJobs)
 		api.Get("/jobs/{id}", s.handleGetJob)
 		api.Get("/jobs/{id}/logs", s.handleGetJobLogs)
+		api.Get("/jobs/{id}/parse-warnings", s.handleGetJobParseWarnings)
+		api.Get("/jobs/{id}/qa", s.handleGetJobQA)
+		api.Post("/jobs/{id}/timing", s.handleAdjustJobTiming)
+		api.Get("/jobs/{id}/speakers", s.handleGetJobSpeakers)
+		api.Put("/jobs/{id}/speakers", s.handleRenameJobSpeakers)
+		api.Post("/subtitles/timing", s.handleAdjustUploadedTiming)
 		api.Post("/jobs", s.handleCreateJob)
 		api.Post("/jobs/{id}/retry", s.handleRetryJob)
 		api.Post("/jobs/{id}/cancel", s.handleCancelJob)
@@ -168,8 +215,34 @@ func (s *Server) handleOverview(writer http.ResponseWriter, request *http.Reques
 }
 
 func (s *Server) handlePipeline(writer http.ResponseWriter, request *http.Request) {
+	settings, err := s.repo.GetSettings(request.Context())
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	jobs, err := s.repo.ListJobs(request.Context(), 100)
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	usage := map[string]int{}
+	for _, job := range jobs {
+		if job.SourceStrategy != "" {
+			usage[job.SourceStrategy]++
+		}
+	}
+	strategies := make([]map[string]any, 0, len(settings.SourceStrategies))
+	for _, strategy := range settings.SourceStrategies {
+		strategies = append(strategies, map[string]any{
+			"key":     strategy.Key,
+			"title":   pipeline.SourceStrategyTitle(strategy.Key),
+			"enabled": strategy.Enabled,
+			"used":    usage[strategy.Key],
+		})
+	}
 	s.writeJSON(writer, http.StatusOK, map[string]any{
-		"steps": pipeline.DefaultSteps(),
+		"steps":             pipeline.DefaultSteps(),
+		"source_strategies": strategies,
 		"runtime": map[string]any{
 			"ffmpeg_bin":           s.cfg.FFmpegBin,
 			"work_dir":             s.cfg.WorkDir,
@@ -180,8 +253,9 @@ func (s *Server) handlePipeline(writer http.ResponseWriter, request *http.Reques
 			"asr_model":            s.cfg.ASRModel,
 			"asr_ready":            s.asr.Ready(),
 			"ocr_provider":         s.cfg.OCRProvider,
-			"ocr_model":            s.cfg.OCRModel,
+			"ocr_model":            ocrModelLabel(s.cfg),
 			"ocr_ready":            s.ocr.Ready(),
+			"diarization_ready":    strings.TrimSpace(s.cfg.DiarizeURL) != "",
 			"job_concurrency":      s.cfg.JobConcurrency,
 		},
 	})
@@ -202,7 +276,12 @@ func (s *Server) handleSaveSettings(writer http.ResponseWriter, request *http.Re
 		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("请求体解析失败: %w", err))
 		return
 	}
-	if err := s.repo.SaveSettings(request.Context(), normalizeSettings(settings)); err != nil {
+	settings = normalizeSettings(settings)
+	if len(pipeline.EnabledSourceStrategies(settings.SourceStrategies)) == 0 {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("至少需要启用一种字幕来源策略"))
+		return
+	}
+	if err := s.repo.SaveSettings(request.Context(), settings); err != nil {
 		s.writeError(writer, http.StatusBadRequest, err)
 		return
 	}
@@ -214,6 +293,24 @@ func (s *Server) handleSaveSettings(writer http.ResponseWriter, request *http.Re
 	s.writeJSON(writer, http.StatusOK, fresh)
 }
 
+func (s *Server) handleGetOCRCache(writer http.ResponseWriter, request *http.Request) {
+	stats, err := s.repo.GetOCRCacheStats(request.Context())
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, stats)
+}
+
+func (s *Server) handleClearOCRCache(writer http.ResponseWriter, request *http.Request) {
+	cleared, err := s.repo.ClearOCRCache(request.Context())
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, map[string]any{"cleared": cleared})
+}
+
 func (s *Server) handleListMedia(writer http.ResponseWriter, request *http.Request) {
 	assets, err := s.repo.ListMediaAssets(request.Context(), parseLimit(request.URL.Query().Get("limit"), 200))
 	if err != nil {
@@ -223,6 +320,145 @@ func (s *Server) handleListMedia(writer http.ResponseWriter, request *http.Reque
 	s.writeJSON(writer, http.StatusOK, map[string]any{"items": assets})
 }
 
+func (s *Server) handleGetMediaStreams(writer http.ResponseWriter, request *http.Request) {
+	assetID, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("媒体 ID 无效"))
+		return
+	}
+	asset, err := s.repo.GetMediaAsset(request.Context(), assetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("媒体不存在"))
+			return
+		}
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	streams, err := media.ProbeStreams(request.Context(), s.cfg.FFprobeBin, asset.FilePath)
+	if err != nil {
+		s.writeError(writer, http.StatusBadGateway, err)
+		return
+	}
+	settings, err := s.repo.GetSettings(request.Context())
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	subtitles := media.SubtitleStreams(streams)
+	sidecars := media.DiscoverSidecars(asset.FilePath)
+	response := map[string]any{"items": subtitles, "sidecars": sidecars}
+	if selected, ok := media.SelectSidecar(sidecars, settings.SourceLanguage); ok {
+		response["sidecar"] = selected.Path
+	}
+	if selected, ok := media.SelectSubtitleStream(subtitles, settings.SourceLanguage); ok {
+		response["recommended_index"] = selected.Index
+	}
+	s.writeJSON(writer, http.StatusOK, response)
+}
+
+func (s *Server) handleGetMediaOCRRegions(writer http.ResponseWriter, request *http.Request) {
+	asset, ok := s.lookupMediaAsset(writer, request)
+	if !ok {
+		return
+	}
+	s.writeMediaOCRRegions(writer, request, asset)
+}
+
+func (s *Server) handleSaveMediaOCRRegions(writer http.ResponseWriter, request *http.Request) {
+	asset, ok := s.lookupMediaAsset(writer, request)
+	if !ok {
+		return
+	}
+	var payload ocrRegionsRequest
+	if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("请求体解析失败: %w", err))
+		return
+	}
+	if len(payload.Regions) == 0 {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("至少需要一个字幕区域"))
+		return
+	}
+	if err := validateOCRRegions(payload.Regions); err != nil {
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.repo.SaveMediaOCRRegions(request.Context(), asset.FilePath, payload.Regions, "manual"); err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeMediaOCRRegions(writer, request, asset)
+}
+
+func (s *Server) handleDetectMediaOCRRegions(writer http.ResponseWriter, request *http.Request) {
+	asset, ok := s.lookupMediaAsset(writer, request)
+	if !ok {
+		return
+	}
+	detected, err := media.DetectSubtitleRegions(request.Context(), s.cfg.FFmpegBin, s.cfg.FFprobeBin, asset.FilePath)
+	if err != nil {
+		s.writeError(writer, http.StatusBadGateway, err)
+		return
+	}
+	regions := make([]model.OCRRegion, 0, len(detected))
+	for _, region := range detected {
+		regions = append(regions, model.OCRRegion{TopPercent: region.TopPercent, HeightPercent: region.HeightPercent})
+	}
+	if _, err := s.repo.SaveMediaOCRRegions(request.Context(), asset.FilePath, regions, "detected"); err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeMediaOCRRegions(writer, request, asset)
+}
+
+func (s *Server) writeMediaOCRRegions(writer http.ResponseWriter, request *http.Request, asset model.MediaAsset) {
+	record, found, err := s.repo.GetMediaOCRRegions(request.Context(), asset.FilePath)
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	response := map[string]any{
+		"default": model.OCRRegion{TopPercent: float64(s.cfg.OCRCropTopPercent), HeightPercent: float64(s.cfg.OCRCropHeightPercent)},
+		"regions": []model.OCRRegion{},
+	}
+	if found {
+		response["regions"] = record.Regions
+		response["source"] = record.Source
+		response["updated_at"] = record.UpdatedAt
+	}
+	s.writeJSON(writer, http.StatusOK, response)
+}
+
+func (s *Server) lookupMediaAsset(writer http.ResponseWriter, request *http.Request) (model.MediaAsset, bool) {
+	assetID, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("媒体 ID 无效"))
+		return model.MediaAsset{}, false
+	}
+	asset, err := s.repo.GetMediaAsset(request.Context(), assetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("媒体不存在"))
+			return model.MediaAsset{}, false
+		}
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return model.MediaAsset{}, false
+	}
+	return asset, true
+}
+
+func validateOCRRegions(regions []model.OCRRegion) error {
+	if len(regions) > 3 {
+		return fmt.Errorf("字幕区域最多只能设置 3 个")
+	}
+	for index, region := range regions {
+		if region.TopPercent < 0 || region.TopPercent >= 100 || region.HeightPercent <= 0 || region.HeightPercent > 100 || region.TopPercent+region.HeightPercent > 100 {
+			return fmt.Errorf("第 %d 个字幕区域无效：起始位置需在 0~100 之间，且起始位置加高度不能超过 100", index+1)
+		}
+	}
+	return nil
+}
+
 func (s *Server) handleScanMedia(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	settings, err := s.repo.GetSettings(ctx)
@@ -282,6 +518,156 @@ func (s *Server) handleGetJobLogs(writer http.ResponseWriter, request *http.Requ
 	s.writeJSON(writer, http.StatusOK, map[string]any{"items": entries})
 }
 
+func (s *Server) handleGetJobParseWarnings(writer http.ResponseWriter, request *http.Request) {
+	jobID := chi.URLParam(request, "id")
+	if _, err := s.repo.GetJob(request.Context(), jobID); err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("任务不存在"))
+			return
+		}
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	record, err := s.data.Load(jobID)
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, map[string]any{"items": record.ParseWarnings})
+}
+
+func (s *Server) handleGetJobQA(writer http.ResponseWriter, request *http.Request) {
+	job, err := s.repo.GetJob(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("任务不存在"))
+			return
+		}
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	settings, err := s.repo.GetSettings(request.Context())
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	record, err := s.data.Load(job.ID)
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	if len(record.Blocks) == 0 || len(record.Translations) == 0 {
+		s.writeError(writer, http.StatusNotFound, fmt.Errorf("任务尚未生成可质检的译文"))
+		return
+	}
+	blocks, translations := subtitle.ApplyLayout(record.Blocks, record.Translations, jobrunner.LayoutOptions(settings))
+	options := jobrunner.QAOptions(settings, job)
+	options.OCRConfidence = record.OCRConfidence
+	options.FusionFlags = record.FusionFlags
+	report := subtitle.CheckQA(blocks, translations, options)
+	if record.ReviewDrift != "" {
+		report.Warning = fmt.Sprintf("输出字幕的人工修改没有同步到任务数据（%s），以下质检结果基于上次同步的内容", record.ReviewDrift)
+	}
+	s.writeJSON(writer, http.StatusOK, report)
+}
+
+func (s *Server) handleAdjustJobTiming(writer http.ResponseWriter, request *http.Request) {
+	var operation subtitle.TimingOperation
+	if err := json.NewDecoder(request.Body).Decode(&operation); err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("请求体解析失败: %w", err))
+		return
+	}
+	job, err := s.runner.AdjustTiming(request.Context(), chi.URLParam(request, "id"), operation)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("任务不存在"))
+			return
+		}
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, job)
+}
+
+func (s *Server) handleGetJobSpeakers(writer http.ResponseWriter, request *http.Request) {
+	job, err := s.repo.GetJob(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("任务不存在"))
+			return
+		}
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	record, err := s.data.Load(job.ID)
+	if err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, map[string]any{
+		"items": subtitle.SummarizeSpeakers(record.Blocks, record.Speakers),
+	})
+}
+
+func (s *Server) handleRenameJobSpeakers(writer http.ResponseWriter, request *http.Request) {
+	var payload struct {
+		Speakers map[string]string `json:"speakers"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("请求体解析失败: %w", err))
+		return
+	}
+	job, err := s.runner.RenameSpeakers(request.Context(), chi.URLParam(request, "id"), payload.Speakers)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.writeError(writer, http.StatusNotFound, fmt.Errorf("任务不存在"))
+			return
+		}
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, job)
+}
+
+func (s *Server) handleAdjustUploadedTiming(writer http.ResponseWriter, request *http.Request) {
+	request.Body = http.MaxBytesReader(writer, request.Body, maxUploadSubtitleBytes)
+	if err := request.ParseMultipartForm(maxUploadSubtitleBytes); err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("上传内容解析失败: %w", err))
+		return
+	}
+	file, header, err := request.FormFile("file")
+	if err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("缺少字幕文件: %w", err))
+		return
+	}
+	defer func() { _ = file.Close() }()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	var operation subtitle.TimingOperation
+	if err := json.Unmarshal([]byte(request.FormValue("operation")), &operation); err != nil {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("operation 字段解析失败: %w", err))
+		return
+	}
+	blocks, _, err := subtitle.ParseSRTWithWarnings(string(raw))
+	if err != nil {
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	adjusted, err := subtitle.ApplyTiming(blocks, operation)
+	if err != nil {
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	baseName := strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	writer.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", firstNonEmpty(baseName, "subtitle")+".retimed.srt"))
+	writer.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(writer, subtitle.RenderSRT(adjusted))
+}
+
 func (s *Server) handleCreateJob(writer http.ResponseWriter, request *http.Request) {
 	var payload createJobRequest
 	if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
@@ -306,6 +692,14 @@ func (s *Server) handleCreateJob(writer http.ResponseWriter, request *http.Reque
 		payload.MediaPath = asset.FilePath
 		payload.FileName = firstNonEmpty(payload.FileName, asset.RelativePath, filepath.Base(asset.FilePath))
 	}
+	if payload.StreamIndex != nil && *payload.StreamIndex < 0 {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("字幕轨序号不能为负数"))
+		return
+	}
+	if err := validateOCRRegions(payload.OCRRegions); err != nil {
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
 	job, err := s.repo.CreateJob(request.Context(), db.CreateJobInput{
 		MediaAssetID:   payload.MediaAssetID,
 		MediaPath:      payload.MediaPath,
@@ -314,7 +708,10 @@ func (s *Server) handleCreateJob(writer http.ResponseWriter, request *http.Reque
 		TargetLanguage: firstNonEmpty(payload.TargetLanguage, settings.TargetLanguage),
 		Provider:       settings.TranslationProvider,
 		OutputFormats:  normalizeFormats(payload.OutputFormats, settings.OutputFormats),
-		Details:        firstNonEmpty(payload.Details, "任务已创建，后台会先找字幕，找不到再自动转为 ASR。"),
+		StreamIndex:    payload.StreamIndex,
+		OCRRegions:     payload.OCRRegions,
+		Strategies:     payload.Strategies,
+		Details:        firstNonEmpty(payload.Details, "任务已创建，后台会按字幕来源策略依次尝试取得源字幕。"),
 	})
 	if err != nil {
 		s.writeError(writer, http.StatusBadRequest, err)
@@ -340,6 +737,23 @@ func (s *Server) handleRetryJob(writer http.ResponseWriter, request *http.Reques
 		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("只有失败或已取消的任务才能重试"))
 		return
 	}
+	var payload retryJobRequest
+	if err := json.NewDecoder(request.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(writer, http.StatusBadRequest, fmt.Errorf("请求体解析失败: %w", err))
+		return
+	}
+	strategies := job.SourceStrategies
+	if payload.SourceStrategies != nil {
+		strategies = *payload.SourceStrategies
+	}
+	if err := s.repo.UpdateJobSourceStrategies(request.Context(), job.ID, strategies); err != nil {
+		s.writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.repo.UpdateJobDetectedLanguage(request.Context(), job.ID, ""); err != nil {
+		s.writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
 	paths := db.JobOutputPaths{}
 	if err := s.repo.UpdateJobProgress(request.Context(), job.ID, "queued", "queued", 0, "任务已重新排队", paths, ""); err != nil {
 		s.writeError(writer, http.StatusInternalServerError, err)
@@ -495,11 +909,17 @@ func (s *Server) handleSaveJobPreview(writer http.ResponseWriter, request *http.
 		return
 	}
 	_ = s.logger.Append(job.ID, "info", "review", fmt.Sprintf("%s 字幕已人工保存", strings.ToUpper(kind)), targetPath)
+	warning, err := s.runner.SyncReview(request.Context(), job, kind, content)
+	if err != nil {
+		warning = fmt.Sprintf("字幕已保存，但同步人工修改到任务数据失败: %v", err)
+		_ = s.logger.Append(job.ID, "warn", "review", warning, targetPath)
+	}
 	fresh, err := s.readPreview(job, kind)
 	if err != nil {
 		s.writeError(writer, http.StatusInternalServerError, err)
 		return
 	}
+	fresh.Warning = warning
 	s.writeJSON(writer, http.StatusOK, fresh)
 }
 
@@ -561,12 +981,20 @@ func normalizeSettings(settings model.AppSettings) model.AppSettings {
 	settings.TranslationProvider = firstNonEmpty(settings.TranslationProvider, "deepseek")
 	settings.TranslationModel = firstNonEmpty(settings.TranslationModel, "deepseek-chat")
 	settings.TranslationPrompt = firstNonEmpty(settings.TranslationPrompt, "请逐条翻译字幕文本，只输出目标语言译文，不要解释，不要合并或拆分字幕。")
+	settings.SourceStrategies = pipeline.NormalizeSourceStrategies(settings.SourceStrategies)
 	if settings.MaxSubtitlePerBatch <= 0 {
 		settings.MaxSubtitlePerBatch = 20
 	}
 	return settings
 }
 
+func ocrModelLabel(cfg config.Config) string {
+	if cfg.OCRProvider == config.OCRProviderCommand {
+		return cfg.OCRCommand
+	}
+	return cfg.OCRModel
+}
+
 func trimNonEmpty(values []string) []string {
 	result := make([]string, 0, len(values))
 	for _, value := range values {
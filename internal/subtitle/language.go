@@ -0,0 +1,188 @@
+package subtitle
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const maxDetectRunes = 20000
+
+var markupPattern = regexp.MustCompile(`\{[^}]*\}|<[^>]*>`)
+
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "you", "that", "is", "to", "it", "of", "what", "this", "i'm", "don't", "your", "have", "are", "with"},
+	"es": {"el", "la", "que", "de", "no", "es", "y", "en", "lo", "los", "por", "qué", "una", "para", "con", "está"},
+	"fr": {"le", "la", "les", "et", "est", "je", "vous", "que", "pas", "de", "c'est", "une", "nous", "qui", "il", "ça"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "sie", "du", "es", "ein", "zu", "wir", "mit", "was", "auf"},
+	"it": {"il", "che", "di", "non", "è", "la", "un", "per", "sono", "mi", "una", "lo", "ho", "ma", "cosa", "questo"},
+	"pt": {"o", "que", "de", "não", "é", "a", "um", "uma", "para", "com", "você", "eu", "os", "está", "isso", "do"},
+	"nl": {"de", "het", "een", "en", "is", "ik", "niet", "je", "dat", "van", "wat", "we", "zijn", "met", "hij", "maar"},
+	"pl": {"nie", "się", "to", "jest", "że", "na", "co", "jak", "ja", "tak", "mnie", "ale", "ty", "już", "czy", "mi"},
+	"tr": {"bir", "ve", "bu", "ne", "mi", "ben", "sen", "için", "çok", "da", "de", "var", "değil", "o", "gibi", "ama"},
+	"id": {"yang", "dan", "ini", "itu", "aku", "kamu", "tidak", "apa", "ada", "saya", "di", "ke", "dengan", "akan", "kita", "untuk"},
+	"sv": {"och", "att", "det", "är", "jag", "inte", "du", "en", "som", "på", "har", "vi", "med", "för", "den", "vad"},
+}
+
+var vietnameseLetters = "ăâđêôơưạảấầẩẫậắằẳẵặẹẻẽếềểễệỉịọỏốồổỗộớờởỡợụủứừửữựỳỵỷỹ"
+
+var simplifiedOnly = "这说们会个来时为过对没还吗么发样现话让点听东车门问间见觉经开"
+
+var traditionalOnly = "這說們會個來時為過對沒還嗎麼發樣現話讓點聽東車門問間見覺經開"
+
+func DetectLanguage(blocks []Block) (string, float64) {
+	var builder strings.Builder
+	count := 0
+	for _, block := range blocks {
+		for _, line := range block.Lines {
+			line = markupPattern.ReplaceAllString(line, " ")
+			builder.WriteString(line)
+			builder.WriteString("\n")
+			count += len([]rune(line))
+		}
+		if count >= maxDetectRunes {
+			break
+		}
+	}
+	return DetectTextLanguage(builder.String())
+}
+
+func DetectTextLanguage(text string) (string, float64) {
+	scripts := map[string]int{}
+	letters := 0
+	simplified, traditional := 0, 0
+	vietnamese := 0
+	ukrainian := 0
+	persian := 0
+	for _, char := range text {
+		if !unicode.IsLetter(char) {
+			continue
+		}
+		letters++
+		lower := unicode.ToLower(char)
+		switch {
+		case unicode.Is(unicode.Hiragana, char) || unicode.Is(unicode.Katakana, char):
+			scripts["kana"]++
+		case unicode.Is(unicode.Han, char):
+			scripts["han"]++
+			if strings.ContainsRune(simplifiedOnly, char) {
+				simplified++
+			} else if strings.ContainsRune(traditionalOnly, char) {
+				traditional++
+			}
+		case unicode.Is(unicode.Hangul, char):
+			scripts["hangul"]++
+		case unicode.Is(unicode.Cyrillic, char):
+			scripts["cyrillic"]++
+			if strings.ContainsRune("іїєґ", lower) {
+				ukrainian++
+			}
+		case unicode.Is(unicode.Arabic, char):
+			scripts["arabic"]++
+			if strings.ContainsRune("پچژگ", char) {
+				persian++
+			}
+		case unicode.Is(unicode.Hebrew, char):
+			scripts["hebrew"]++
+		case unicode.Is(unicode.Thai, char):
+			scripts["thai"]++
+		case unicode.Is(unicode.Greek, char):
+			scripts["greek"]++
+		case unicode.Is(unicode.Devanagari, char):
+			scripts["devanagari"]++
+		case unicode.Is(unicode.Latin, char):
+			scripts["latin"]++
+			if strings.ContainsRune(vietnameseLetters, lower) {
+				vietnamese++
+			}
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+	share := func(keys ...string) float64 {
+		total := 0
+		for _, key := range keys {
+			total += scripts[key]
+		}
+		return float64(total) / float64(letters)
+	}
+	sample := min(1, float64(letters)/20)
+	if scripts["kana"] > 0 && float64(scripts["kana"]) >= 0.1*float64(scripts["kana"]+scripts["han"]) {
+		return "ja", share("kana", "han") * sample
+	}
+	dominant, best := "", 0
+	for script, value := range scripts {
+		if value > best || (value == best && script < dominant) {
+			dominant, best = script, value
+		}
+	}
+	confidence := share(dominant) * sample
+	switch dominant {
+	case "han":
+		if traditional > simplified {
+			return "zh-Hant", confidence
+		}
+		return "zh", confidence
+	case "hangul":
+		return "ko", confidence
+	case "cyrillic":
+		if ukrainian > 0 && ukrainian*50 >= scripts["cyrillic"] {
+			return "uk", confidence
+		}
+		return "ru", confidence
+	case "arabic":
+		if persian > 0 && persian*50 >= scripts["arabic"] {
+			return "fa", confidence
+		}
+		return "ar", confidence
+	case "hebrew":
+		return "he", confidence
+	case "thai":
+		return "th", confidence
+	case "greek":
+		return "el", confidence
+	case "devanagari":
+		return "hi", confidence
+	case "latin":
+		if vietnamese*20 >= scripts["latin"] {
+			return "vi", confidence
+		}
+		code, ratio := detectLatinLanguage(text)
+		return code, confidence * ratio
+	}
+	return "", 0
+}
+
+func detectLatinLanguage(text string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(char rune) bool {
+		return !unicode.IsLetter(char) && char != '\'' && char != '’'
+	})
+	scores := map[string]int{}
+	for _, word := range words {
+		word = strings.ReplaceAll(word, "’", "'")
+		for code, stopwords := range latinStopwords {
+			for _, stopword := range stopwords {
+				if word == stopword {
+					scores[code]++
+					break
+				}
+			}
+		}
+	}
+	best, second := "", 0
+	for code, score := range scores {
+		if best == "" || score > scores[best] || (score == scores[best] && code < best) {
+			best = code
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	for code, score := range scores {
+		if code != best && score > second {
+			second = score
+		}
+	}
+	return best, float64(scores[best]-second) / float64(scores[best])
+}
@@ -0,0 +1,220 @@
+package subtitle
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+type LayoutOptions struct {
+	WrapEnabled       bool
+	MaxLineWidth      int
+	ExtendTiming      bool
+	MaxCharsPerSecond float64
+	MinGap            time.Duration
+	MaxExtension      time.Duration
+}
+
+type layoutToken struct {
+	text        string
+	spaceBefore bool
+}
+
+const (
+	noBreakBefore = "，。！？、；：）》」』】〕…—,.!?;:)]}%\"'”’"
+	noBreakAfter  = "（《「『【〔([{“‘"
+	preferBreak   = "，。！？；：、,.!?;:"
+)
+
+func DefaultLayoutOptions() LayoutOptions {
+	return LayoutOptions{
+		WrapEnabled:       true,
+		MaxLineWidth:      42,
+		ExtendTiming:      false,
+		MaxCharsPerSecond: 20,
+		MinGap:            100 * time.Millisecond,
+		MaxExtension:      2 * time.Second,
+	}
+}
+
+func ApplyLayout(blocks []Block, translations []string, options LayoutOptions) ([]Block, []string) {
+	defaults := DefaultLayoutOptions()
+	if options.MaxLineWidth <= 0 {
+		options.MaxLineWidth = defaults.MaxLineWidth
+	}
+	if options.MaxCharsPerSecond <= 0 {
+		options.MaxCharsPerSecond = defaults.MaxCharsPerSecond
+	}
+	if options.MinGap < 0 {
+		options.MinGap = 0
+	}
+	laidOut := make([]Block, len(blocks))
+	copy(laidOut, blocks)
+	wrapped := make([]string, len(translations))
+	copy(wrapped, translations)
+	if options.WrapEnabled {
+		for index, translation := range wrapped {
+			if strings.TrimSpace(translation) == "" {
+				continue
+			}
+			wrapped[index] = strings.Join(WrapText(translation, options.MaxLineWidth), "\n")
+		}
+	}
+	if options.ExtendTiming {
+		for index := range laidOut {
+			text := laidOut[index].Lines
+			if index < len(wrapped) {
+				text = append(append([]string{}, text...), splitTranslationLines(wrapped[index])...)
+			}
+			laidOut[index].End = extendedEnd(laidOut, index, readableCharCount(text), options)
+		}
+	}
+	return laidOut, wrapped
+}
+
+func extendedEnd(blocks []Block, index int, chars int, options LayoutOptions) time.Duration {
+	block := blocks[index]
+	if block.End <= block.Start {
+		return block.End
+	}
+	needed := time.Duration(float64(chars) / options.MaxCharsPerSecond * float64(time.Second))
+	if block.End-block.Start >= needed {
+		return block.End
+	}
+	end := block.Start + needed
+	if options.MaxExtension > 0 && end > block.End+options.MaxExtension {
+		end = block.End + options.MaxExtension
+	}
+	if next := NextInRegion(blocks, index); next >= 0 {
+		if limit := blocks[next].Start - options.MinGap; end > limit {
+			end = limit
+		}
+	}
+	if end < block.End {
+		return block.End
+	}
+	return end
+}
+
+func WrapText(text string, maxWidth int) []string {
+	tokens := tokenizeForWrap(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+	if maxWidth <= 0 || tokensWidth(tokens) <= maxWidth {
+		return []string{joinTokens(tokens)}
+	}
+	if split, ok := balancedSplit(tokens, maxWidth); ok {
+		return []string{joinTokens(tokens[:split]), joinTokens(tokens[split:])}
+	}
+	return greedyWrap(tokens, maxWidth)
+}
+
+func tokenizeForWrap(text string) []layoutToken {
+	tokens := make([]layoutToken, 0, len(text))
+	var word strings.Builder
+	var previous rune
+	pendingSpace := false
+	flushWord := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, layoutToken{text: word.String(), spaceBefore: pendingSpace})
+			word.Reset()
+			pendingSpace = false
+		}
+	}
+	for _, char := range strings.TrimSpace(text) {
+		if unicode.IsSpace(char) {
+			flushWord()
+			pendingSpace = len(tokens) > 0
+			continue
+		}
+		if pendingSpace && isWideRune(previous) && isWideRune(char) {
+			pendingSpace = false
+		}
+		switch {
+		case word.Len() == 0 && len(tokens) > 0 && !pendingSpace && strings.ContainsRune(noBreakBefore, char):
+			tokens[len(tokens)-1].text += string(char)
+		case isWideRune(char):
+			flushWord()
+			tokens = append(tokens, layoutToken{text: string(char), spaceBefore: pendingSpace})
+			pendingSpace = false
+		default:
+			word.WriteRune(char)
+		}
+		previous = char
+	}
+	flushWord()
+	return mergeOpeningPunctuation(tokens)
+}
+
+func mergeOpeningPunctuation(tokens []layoutToken) []layoutToken {
+	result := make([]layoutToken, 0, len(tokens))
+	for index := 0; index < len(tokens); index++ {
+		token := tokens[index]
+		for index+1 < len(tokens) && isOpeningPunctuation(token.text) && !tokens[index+1].spaceBefore {
+			index++
+			token.text += tokens[index].text
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+func isOpeningPunctuation(text string) bool {
+	runes := []rune(text)
+	return len(runes) > 0 && strings.ContainsRune(noBreakAfter, runes[len(runes)-1])
+}
+
+func balancedSplit(tokens []layoutToken, maxWidth int) (int, bool) {
+	best := -1
+	bestScore := 0
+	for split := 1; split < len(tokens); split++ {
+		left := tokensWidth(tokens[:split])
+		right := tokensWidth(tokens[split:])
+		if left > maxWidth || right > maxWidth {
+			continue
+		}
+		score := left - right
+		if score < 0 {
+			score = -score
+		}
+		lastRunes := []rune(tokens[split-1].text)
+		if strings.ContainsRune(preferBreak, lastRunes[len(lastRunes)-1]) {
+			score -= maxWidth / 4
+		}
+		if best < 0 || score < bestScore {
+			best = split
+			bestScore = score
+		}
+	}
+	return best, best > 0
+}
+
+func greedyWrap(tokens []layoutToken, maxWidth int) []string {
+	lines := make([]string, 0, 3)
+	start := 0
+	for start < len(tokens) {
+		end := start + 1
+		for end < len(tokens) && tokensWidth(tokens[start:end+1]) <= maxWidth {
+			end++
+		}
+		lines = append(lines, joinTokens(tokens[start:end]))
+		start = end
+	}
+	return lines
+}
+
+func tokensWidth(tokens []layoutToken) int {
+	return DisplayWidth(joinTokens(tokens))
+}
+
+func joinTokens(tokens []layoutToken) string {
+	var builder strings.Builder
+	for index, token := range tokens {
+		if index > 0 && token.spaceBefore {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(token.text)
+	}
+	return builder.String()
+}
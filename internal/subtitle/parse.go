@@ -0,0 +1,232 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ParseWarning struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+var (
+	timingLinePattern    = regexp.MustCompile(`^\s*([0-9:.,]+)\s*-{1,2}>\s*([0-9:.,]+)`)
+	strictTimestampRegex = regexp.MustCompile(`^\d{2}:\d{2}:\d{2},\d{3}$`)
+)
+
+type parsedLine struct {
+	number      int
+	text        string
+	blankBefore bool
+}
+
+type pendingCue struct {
+	line    int
+	start   time.Duration
+	end     time.Duration
+	invalid bool
+	body    []parsedLine
+}
+
+type srtParser struct {
+	blocks       []Block
+	warnings     []ParseWarning
+	current      *pendingCue
+	preamble     []parsedLine
+	blankPending bool
+}
+
+func ParseSRTWithWarnings(content string) ([]Block, []ParseWarning, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	if strings.TrimSpace(content) == "" {
+		return nil, nil, errors.New("字幕内容为空")
+	}
+	parser := &srtParser{}
+	for offset, raw := range strings.Split(content, "\n") {
+		parser.consume(offset+1, raw)
+	}
+	parser.flush()
+	parser.dropPreamble()
+	sort.SliceStable(parser.warnings, func(i, j int) bool {
+		return parser.warnings[i].Line < parser.warnings[j].Line
+	})
+	if len(parser.blocks) == 0 {
+		return nil, parser.warnings, errors.New("未解析到有效的 SRT 字幕块")
+	}
+	for index := range parser.blocks {
+		parser.blocks[index].Index = index + 1
+	}
+	return parser.blocks, parser.warnings, nil
+}
+
+func (p *srtParser) consume(number int, raw string) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		p.blankPending = true
+		return
+	}
+	if timingLinePattern.MatchString(trimmed) {
+		p.startCue(number, trimmed)
+		p.blankPending = false
+		return
+	}
+	line := parsedLine{number: number, text: trimmed, blankBefore: p.blankPending}
+	p.blankPending = false
+	if p.current == nil {
+		p.preamble = append(p.preamble, line)
+		return
+	}
+	p.current.body = append(p.current.body, line)
+}
+
+func (p *srtParser) startCue(number int, timing string) {
+	p.takeIndexLine(number)
+	p.flush()
+	p.dropPreamble()
+
+	cue := &pendingCue{line: number}
+	start, end, loose, err := parseTimingLine(timing)
+	if err != nil {
+		p.warn(number, fmt.Sprintf("无法解析时间轴 %q，该条字幕已跳过", timing))
+		cue.invalid = true
+		p.current = cue
+		return
+	}
+	if loose {
+		p.warn(number, fmt.Sprintf("时间戳 %q 不是标准 SRT 格式，已按兼容格式解析", timing))
+	}
+	if end < start {
+		p.warn(number, "结束时间早于开始时间")
+	}
+	cue.start = start
+	cue.end = end
+	p.current = cue
+}
+
+func (p *srtParser) takeIndexLine(timingLine int) {
+	lines := &p.preamble
+	if p.current != nil {
+		lines = &p.current.body
+	}
+	count := len(*lines)
+	if count == 0 || (*lines)[count-1].number != timingLine-1 {
+		p.warn(timingLine, "缺少字幕序号，已自动编号")
+		return
+	}
+	last := (*lines)[count-1]
+	standalone := last.blankBefore || (p.current == nil && count == 1)
+	if _, err := strconv.Atoi(last.text); err == nil && (standalone || count > 1) {
+		*lines = (*lines)[:count-1]
+		return
+	}
+	if standalone {
+		*lines = (*lines)[:count-1]
+		p.warn(last.number, fmt.Sprintf("字幕序号 %q 不是数字，已自动编号", last.text))
+		return
+	}
+	p.warn(timingLine, "缺少字幕序号，且与上一条字幕之间没有空行，已自动编号")
+}
+
+func (p *srtParser) flush() {
+	cue := p.current
+	p.current = nil
+	if cue == nil || cue.invalid {
+		return
+	}
+	lines := make([]string, 0, len(cue.body))
+	for position, line := range cue.body {
+		if position > 0 && line.blankBefore {
+			p.warn(line.number, "字幕正文中包含空行，已合并到同一条字幕")
+		}
+		lines = append(lines, line.text)
+	}
+	if len(lines) == 0 {
+		p.warn(cue.line, "字幕块没有正文，已跳过")
+		return
+	}
+	p.blocks = append(p.blocks, Block{Start: cue.start, End: cue.end, Lines: lines})
+}
+
+func (p *srtParser) dropPreamble() {
+	for _, line := range p.preamble {
+		p.warn(line.number, fmt.Sprintf("内容 %q 不属于任何字幕块，已忽略", line.text))
+	}
+	p.preamble = nil
+}
+
+func (p *srtParser) warn(line int, reason string) {
+	p.warnings = append(p.warnings, ParseWarning{Line: line, Reason: reason})
+}
+
+func parseTimingLine(line string) (time.Duration, time.Duration, bool, error) {
+	matches := timingLinePattern.FindStringSubmatch(line)
+	if matches == nil {
+		return 0, 0, false, fmt.Errorf("非法时间轴: %s", line)
+	}
+	start, startLoose, err := parseSRTTimestamp(matches[1])
+	if err != nil {
+		return 0, 0, false, err
+	}
+	end, endLoose, err := parseSRTTimestamp(matches[2])
+	if err != nil {
+		return 0, 0, false, err
+	}
+	loose := startLoose || endLoose || !strings.Contains(line, "-->")
+	return start, end, loose, nil
+}
+
+func parseSRTTimestamp(raw string) (time.Duration, bool, error) {
+	raw = strings.TrimSpace(raw)
+	loose := !strictTimestampRegex.MatchString(raw)
+	clock := raw
+	fraction := ""
+	if position := strings.LastIndexAny(raw, ",."); position >= 0 {
+		clock = raw[:position]
+		fraction = raw[position+1:]
+	}
+	fields := strings.Split(clock, ":")
+	if fraction == "" && len(fields) == 4 {
+		fraction = fields[3]
+		fields = fields[:3]
+	}
+	if len(fields) == 2 {
+		fields = append([]string{"0"}, fields...)
+	}
+	if len(fields) != 3 {
+		return 0, loose, fmt.Errorf("非法时间戳: %s", raw)
+	}
+	values := make([]int, 3)
+	for position, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil || value < 0 {
+			return 0, loose, fmt.Errorf("非法时间戳: %s", raw)
+		}
+		values[position] = value
+	}
+	if values[1] >= 60 || values[2] >= 60 {
+		return 0, loose, fmt.Errorf("非法时间戳: %s", raw)
+	}
+	milliseconds := 0
+	if fraction != "" {
+		if len(fraction) > 3 {
+			fraction = fraction[:3]
+		}
+		for len(fraction) < 3 {
+			fraction += "0"
+		}
+		value, err := strconv.Atoi(fraction)
+		if err != nil {
+			return 0, loose, fmt.Errorf("非法时间戳: %s", raw)
+		}
+		milliseconds = value
+	}
+	return time.Duration(values[0])*time.Hour + time.Duration(values[1])*time.Minute + time.Duration(values[2])*time.Second + time.Duration(milliseconds)*time.Millisecond, loose, nil
+}
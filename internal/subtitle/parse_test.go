@@ -0,0 +1,109 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSRTWithWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		lines        [][]string
+		starts       []time.Duration
+		warningLines []int
+	}{
+		{
+			name:    "standard file",
+			content: "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nWorld\n",
+			lines:   [][]string{{"Hello"}, {"World"}},
+			starts:  []time.Duration{time.Second, 3 * time.Second},
+		},
+		{
+			name:    "bom and crlf",
+			content: "\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n",
+			lines:   [][]string{{"Hello"}},
+			starts:  []time.Duration{time.Second},
+		},
+		{
+			name:    "arrow inside dialogue is text",
+			content: "1\n00:00:01,000 --> 00:00:02,000\nA --> B\n",
+			lines:   [][]string{{"A --> B"}},
+			starts:  []time.Duration{time.Second},
+		},
+		{
+			name:         "every loose timestamp is reported",
+			content:      "1\n0:00:01.000 --> 0:00:02.000\nA\n\n2\n0:00:03.000 --> 0:00:04.000\nB\n",
+			lines:        [][]string{{"A"}, {"B"}},
+			starts:       []time.Duration{time.Second, 3 * time.Second},
+			warningLines: []int{2, 6},
+		},
+		{
+			name:         "numeric last line stays in the body without a blank line",
+			content:      "1\n00:00:01,000 --> 00:00:02,000\n1984\n00:00:03,000 --> 00:00:04,000\nB\n",
+			lines:        [][]string{{"1984"}, {"B"}},
+			starts:       []time.Duration{time.Second, 3 * time.Second},
+			warningLines: []int{4},
+		},
+		{
+			name:         "missing index is numbered in order",
+			content:      "5\n00:00:01,000 --> 00:00:02,000\nA\n\n00:00:03,000 --> 00:00:04,000\nB\n\n1\n00:00:05,000 --> 00:00:06,000\nC\n",
+			lines:        [][]string{{"A"}, {"B"}, {"C"}},
+			starts:       []time.Duration{time.Second, 3 * time.Second, 5 * time.Second},
+			warningLines: []int{5},
+		},
+		{
+			name:         "blank line inside body is merged",
+			content:      "1\n00:00:01,000 --> 00:00:02,000\nA\n\nB\n\n2\n00:00:03,000 --> 00:00:04,000\nC\n",
+			lines:        [][]string{{"A", "B"}, {"C"}},
+			starts:       []time.Duration{time.Second, 3 * time.Second},
+			warningLines: []int{5},
+		},
+		{
+			name:         "unparseable timing skips the cue",
+			content:      "1\n00:00:01,000 --> 00:00:02,000\nA\n\n2\n00:99:03,000 --> 00:00:04,000\nB\n",
+			lines:        [][]string{{"A"}},
+			starts:       []time.Duration{time.Second},
+			warningLines: []int{6},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks, warnings, err := ParseSRTWithWarnings(test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(blocks) != len(test.lines) {
+				t.Fatalf("got %d blocks, want %d: %+v", len(blocks), len(test.lines), blocks)
+			}
+			for index, block := range blocks {
+				if block.Index != index+1 {
+					t.Fatalf("block %d has index %d", index, block.Index)
+				}
+				if strings.Join(block.Lines, "\n") != strings.Join(test.lines[index], "\n") {
+					t.Fatalf("block %d lines %q, want %q", index, block.Lines, test.lines[index])
+				}
+				if block.Start != test.starts[index] {
+					t.Fatalf("block %d start %s, want %s", index, block.Start, test.starts[index])
+				}
+			}
+			if len(warnings) != len(test.warningLines) {
+				t.Fatalf("got warnings %+v, want lines %v", warnings, test.warningLines)
+			}
+			for index, warning := range warnings {
+				if warning.Line != test.warningLines[index] {
+					t.Fatalf("got warnings %+v, want lines %v", warnings, test.warningLines)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSRTWithWarningsRejectsEmpty(t *testing.T) {
+	for _, content := range []string{"", "  \n\n", "no cues here\n"} {
+		if _, _, err := ParseSRTWithWarnings(content); err == nil {
+			t.Fatalf("expected error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const EmptyTranslationPlaceholder = "[翻译为空]"
+
+const (
+	QASeverityError   = "error"
+	QASeverityWarning = "warning"
+)
+
+type QAOptions struct {
+	MaxCharsPerSecond float64
+	MaxLineLength     int
+	MaxLinesPerSide   int
+	TargetLanguage    string
+	MinOCRConfidence  float64
+	OCRConfidence     []float64
+	FusionFlags       []string
+	SourceIsTarget    bool
+}
+
+type QAIssue struct {
+	BlockIndex int    `json:"block_index"`
+	Code       string `json:"code"`
+	Severity   string `json:"severity"`
+	Side       string `json:"side,omitempty"`
+	Message    string `json:"message"`
+}
+
+type QAReport struct {
+	BlockCount int               `json:"block_count"`
+	IssueCount int               `json:"issue_count"`
+	ErrorCount int               `json:"error_count"`
+	Issues     map[int][]QAIssue `json:"issues"`
+	Warning    string            `json:"warning,omitempty"`
+}
+
+type qaSide struct {
+	name  string
+	label string
+	lines []string
+}
+
+func DefaultQAOptions() QAOptions {
+	return QAOptions{
+		MaxCharsPerSecond: 20,
+		MaxLineLength:     42,
+		MaxLinesPerSide:   2,
+		MinOCRConfidence:  0.6,
+	}
+}
+
+func CheckQA(blocks []Block, translations []string, options QAOptions) QAReport {
+	defaults := DefaultQAOptions()
+	if options.MaxCharsPerSecond <= 0 {
+		options.MaxCharsPerSecond = defaults.MaxCharsPerSecond
+	}
+	if options.MaxLineLength <= 0 {
+		options.MaxLineLength = defaults.MaxLineLength
+	}
+	if options.MaxLinesPerSide <= 0 {
+		options.MaxLinesPerSide = defaults.MaxLinesPerSide
+	}
+	if options.MinOCRConfidence < 0 {
+		options.MinOCRConfidence = defaults.MinOCRConfidence
+	}
+	report := QAReport{BlockCount: len(blocks), Issues: map[int][]QAIssue{}}
+	add := func(issue QAIssue) {
+		report.Issues[issue.BlockIndex] = append(report.Issues[issue.BlockIndex], issue)
+		report.IssueCount++
+		if issue.Severity == QASeverityError {
+			report.ErrorCount++
+		}
+	}
+	previousInRegion := map[string]int{}
+	for position, block := range blocks {
+		number := position + 1
+		duration := block.End - block.Start
+		if duration <= 0 {
+			add(QAIssue{BlockIndex: number, Code: "invalid_duration", Severity: QASeverityError, Message: fmt.Sprintf("显示时长无效（%s → %s）", formatSRTTimestamp(block.Start), formatSRTTimestamp(block.End))})
+		}
+		if previous, ok := previousInRegion[block.Region]; ok && block.Start < blocks[previous].End {
+			add(QAIssue{BlockIndex: number, Code: "overlap", Severity: QASeverityError, Message: fmt.Sprintf("与第 %d 条字幕时间重叠 %d 毫秒", previous+1, (blocks[previous].End - block.Start).Milliseconds())})
+		}
+		previousInRegion[block.Region] = position
+		if len(options.OCRConfidence) == len(blocks) {
+			if confidence := options.OCRConfidence[position]; confidence > 0 && confidence < options.MinOCRConfidence {
+				add(QAIssue{BlockIndex: number, Code: "low_ocr_confidence", Severity: QASeverityWarning, Side: "source", Message: fmt.Sprintf("原文 OCR 置信度 %.2f，低于 %.2f，建议对照画面核对", confidence, options.MinOCRConfidence)})
+			}
+		}
+		if len(options.FusionFlags) == len(blocks) && options.FusionFlags[position] != "" {
+			add(QAIssue{BlockIndex: number, Code: "ocr_asr_mismatch", Severity: QASeverityWarning, Side: "source", Message: options.FusionFlags[position] + "，建议对照画面与音频核对"})
+		}
+
+		sides := []qaSide{{name: "source", label: "原文", lines: block.Lines}}
+		var translation string
+		if position < len(translations) {
+			translation = strings.TrimSpace(translations[position])
+			if !options.SourceIsTarget || !SameText(strings.Join(block.Lines, "\n"), translation) {
+				sides = append(sides, qaSide{name: "translation", label: "译文", lines: splitTranslationLines(translation)})
+			}
+		}
+		for _, side := range sides {
+			if len(side.lines) > options.MaxLinesPerSide {
+				add(QAIssue{BlockIndex: number, Code: "too_many_lines", Severity: QASeverityWarning, Side: side.name, Message: fmt.Sprintf("%s共 %d 行，超过 %d 行上限", side.label, len(side.lines), options.MaxLinesPerSide)})
+			}
+			for _, line := range side.lines {
+				if width := DisplayWidth(line); width > options.MaxLineLength {
+					add(QAIssue{BlockIndex: number, Code: "line_too_long", Severity: QASeverityWarning, Side: side.name, Message: fmt.Sprintf("%s单行宽度 %d，超过 %d 上限", side.label, width, options.MaxLineLength)})
+				}
+			}
+			if duration > 0 {
+				cps := float64(readableCharCount(side.lines)) / duration.Seconds()
+				if cps > options.MaxCharsPerSecond {
+					add(QAIssue{BlockIndex: number, Code: "reading_speed", Severity: QASeverityWarning, Side: side.name, Message: fmt.Sprintf("%s阅读速度 %.1f 字/秒，超过 %.1f 上限", side.label, cps, options.MaxCharsPerSecond)})
+				}
+			}
+		}
+
+		if position >= len(translations) {
+			add(QAIssue{BlockIndex: number, Code: "empty_translation", Severity: QASeverityError, Side: "translation", Message: "缺少译文"})
+			continue
+		}
+		if translation == "" || strings.Contains(translation, EmptyTranslationPlaceholder) {
+			add(QAIssue{BlockIndex: number, Code: "empty_translation", Severity: QASeverityError, Side: "translation", Message: "译文为空或仍是占位符 " + EmptyTranslationPlaceholder})
+			continue
+		}
+		if !options.SourceIsTarget && normalizeForCompare(translation) == normalizeForCompare(JoinText(block.Lines)) && letterCount(translation) > 0 {
+			add(QAIssue{BlockIndex: number, Code: "untranslated", Severity: QASeverityWarning, Side: "translation", Message: "译文与原文完全相同，可能未翻译"})
+			continue
+		}
+		if expected, ok := expectedScriptRatio(translation, options.TargetLanguage); ok && expected < 0.5 {
+			add(QAIssue{BlockIndex: number, Code: "wrong_script", Severity: QASeverityWarning, Side: "translation", Message: fmt.Sprintf("译文中目标语言文字仅占 %.0f%%，可能未翻译或语言错误", expected*100)})
+		}
+	}
+	return report
+}
+
+func DisplayWidth(value string) int {
+	width := 0
+	for _, char := range value {
+		if isWideRune(char) {
+			width += 2
+			continue
+		}
+		width++
+	}
+	return width
+}
+
+func isWideRune(char rune) bool {
+	return unicode.In(char, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(char >= 0x3000 && char <= 0x303F) ||
+		(char >= 0xFF00 && char <= 0xFF60)
+}
+
+func readableCharCount(lines []string) int {
+	count := 0
+	for _, line := range lines {
+		for _, char := range line {
+			if unicode.IsSpace(char) || unicode.IsPunct(char) {
+				continue
+			}
+			count++
+		}
+	}
+	return count
+}
+
+func letterCount(value string) int {
+	count := 0
+	for _, char := range value {
+		if unicode.IsLetter(char) {
+			count++
+		}
+	}
+	return count
+}
+
+func normalizeForCompare(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+func expectedScriptRatio(text string, targetLanguage string) (float64, bool) {
+	tables := scriptTablesForLanguage(targetLanguage)
+	if len(tables) == 0 {
+		return 0, false
+	}
+	letters := 0
+	matched := 0
+	for _, char := range text {
+		if !unicode.IsLetter(char) {
+			continue
+		}
+		letters++
+		if unicode.In(char, tables...) {
+			matched++
+		}
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	return float64(matched) / float64(letters), true
+}
+
+func scriptTablesForLanguage(language string) []*unicode.RangeTable {
+	code := strings.ToLower(strings.TrimSpace(language))
+	if index := strings.IndexAny(code, "-_"); index >= 0 {
+		code = code[:index]
+	}
+	switch code {
+	case "zh", "yue":
+		return []*unicode.RangeTable{unicode.Han}
+	case "ja":
+		return []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana}
+	case "ko":
+		return []*unicode.RangeTable{unicode.Hangul, unicode.Han}
+	case "ru", "uk", "bg", "sr", "be":
+		return []*unicode.RangeTable{unicode.Cyrillic}
+	case "ar", "fa", "ur":
+		return []*unicode.RangeTable{unicode.Arabic}
+	case "he":
+		return []*unicode.RangeTable{unicode.Hebrew}
+	case "th":
+		return []*unicode.RangeTable{unicode.Thai}
+	case "el":
+		return []*unicode.RangeTable{unicode.Greek}
+	case "en", "fr", "de", "es", "it", "pt", "nl", "sv", "da", "no", "nb", "fi", "pl", "cs", "tr", "id", "ms", "vi", "ro", "hu":
+		return []*unicode.RangeTable{unicode.Latin}
+	}
+	return nil
+}
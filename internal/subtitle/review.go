@@ -0,0 +1,347 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ReviewInput struct {
+	Format       string
+	Content      string
+	Blocks       []Block
+	Translations []string
+	Speakers     map[string]string
+	Layout       LayoutOptions
+	Order        string
+}
+
+type ReviewResult struct {
+	Blocks       []Block
+	Translations []string
+	Edited       int
+}
+
+type reviewCue struct {
+	start       time.Duration
+	end         time.Duration
+	lines       []string
+	origin      []string
+	translation []string
+	tagged      bool
+}
+
+func MergeReview(input ReviewInput) (ReviewResult, error) {
+	if len(input.Blocks) != len(input.Translations) {
+		return ReviewResult{}, errors.New("任务数据中的字幕块与译文数量不一致")
+	}
+	var cues []reviewCue
+	var err error
+	switch input.Format {
+	case "srt":
+		cues, err = parseReviewSRT(input.Content)
+	case "ass":
+		cues, err = parseReviewASS(input.Content)
+	default:
+		err = fmt.Errorf("不支持的字幕格式: %s", input.Format)
+	}
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	if len(cues) != len(input.Blocks) {
+		return ReviewResult{}, fmt.Errorf("字幕有 %d 条，与任务数据的 %d 条不一致", len(cues), len(input.Blocks))
+	}
+	named := NameSpeakers(input.Blocks, input.Speakers)
+	rendered, wrapped := ApplyLayout(named, input.Translations, input.Layout)
+	translationAbove := strings.TrimSpace(input.Order) == "translation_above"
+	result := ReviewResult{Blocks: make([]Block, len(cues)), Translations: make([]string, len(cues))}
+	for index, cue := range cues {
+		block := input.Blocks[index]
+		block.Lines = append([]string{}, block.Lines...)
+		translation := input.Translations[index]
+		expectedOrigin := rendered[index].Lines
+		expectedTranslation := splitTranslationLines(wrapped[index])
+		sameText := SameText(strings.Join(expectedOrigin, "\n"), input.Translations[index])
+		if sameText {
+			expectedTranslation = nil
+		}
+		origin, translated := cue.origin, cue.translation
+		if !cue.tagged {
+			first, second := expectedOrigin, expectedTranslation
+			if translationAbove {
+				first, second = second, first
+			}
+			if input.Format == "srt" {
+				if dash := dialogueDash(rendered, index); dash != "" {
+					first, second = withDash(first, dash), withDash(second, dash)
+				}
+			}
+			split := bestReviewSplit(cue.lines, first, second)
+			origin, translated = cue.lines[:split], cue.lines[split:]
+			if translationAbove {
+				origin, translated = cue.lines[split:], cue.lines[:split]
+			}
+		}
+		if input.Format == "srt" {
+			if dash := dialogueDash(rendered, index); dash != "" {
+				origin = stripReviewDash(origin, expectedOrigin)
+				translated = stripReviewDash(translated, expectedTranslation)
+			}
+		}
+		edited := false
+		if !sameReviewTime(input.Format, cue.start, rendered[index].Start) {
+			block.Start = cue.start
+			edited = true
+		}
+		if !sameReviewTime(input.Format, cue.end, rendered[index].End) {
+			block.End = cue.end
+			edited = true
+		}
+		if !equalReviewLines(origin, expectedOrigin) {
+			block.Lines = append([]string{}, origin...)
+			edited = true
+			if sameText && len(translated) == 0 {
+				translation = strings.Join(origin, "\n")
+			}
+		}
+		if !(sameText && len(translated) == 0) && !equalReviewLines(translated, expectedTranslation) {
+			translation = strings.Join(translated, "\n")
+			if translation == EmptyTranslationPlaceholder {
+				translation = ""
+			}
+			edited = true
+		}
+		if edited {
+			result.Edited++
+		}
+		result.Blocks[index] = block
+		result.Translations[index] = translation
+	}
+	return result, nil
+}
+
+func parseReviewSRT(content string) ([]reviewCue, error) {
+	blocks, err := ParseSRT(content)
+	if err != nil {
+		return nil, err
+	}
+	cues := make([]reviewCue, 0, len(blocks))
+	for _, block := range blocks {
+		cues = append(cues, reviewCue{start: block.Start, end: block.End, lines: trimReviewLines(block.Lines)})
+	}
+	return cues, nil
+}
+
+func parseReviewASS(content string) ([]reviewCue, error) {
+	fields := []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+	cues := make([]reviewCue, 0)
+	for number, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "format":
+			fields = fields[:0]
+			for _, field := range strings.Split(value, ",") {
+				fields = append(fields, strings.ToLower(strings.TrimSpace(field)))
+			}
+		case "dialogue":
+			values := strings.SplitN(strings.TrimSpace(value), ",", len(fields))
+			if len(values) != len(fields) {
+				return nil, fmt.Errorf("第 %d 行 Dialogue 字段数量不正确", number+1)
+			}
+			cue := reviewCue{}
+			for position, field := range fields {
+				var err error
+				switch field {
+				case "start":
+					cue.start, err = parseASSTimestamp(values[position])
+				case "end":
+					cue.end, err = parseASSTimestamp(values[position])
+				case "text":
+					cue.origin, cue.translation, cue.lines, cue.tagged = parseASSEventText(values[position])
+				}
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 行: %w", number+1, err)
+				}
+			}
+			cues = append(cues, cue)
+		}
+	}
+	if len(cues) == 0 {
+		return nil, errors.New("未解析到任何 Dialogue 字幕")
+	}
+	return cues, nil
+}
+
+func parseASSEventText(text string) ([]string, []string, []string, bool) {
+	var origin, translation, all []string
+	var current strings.Builder
+	inTranslation := false
+	tagged := false
+	flush := func() {
+		line := strings.TrimSpace(current.String())
+		current.Reset()
+		if line == "" {
+			return
+		}
+		all = append(all, line)
+		if inTranslation {
+			translation = append(translation, line)
+		} else {
+			origin = append(origin, line)
+		}
+	}
+	runes := []rune(text)
+	for position := 0; position < len(runes); position++ {
+		char := runes[position]
+		switch {
+		case char == '{':
+			closing := position + 1
+			for closing < len(runes) && runes[closing] != '}' {
+				closing++
+			}
+			if closing == len(runes) {
+				current.WriteString(string(runes[position:]))
+				position = closing
+				continue
+			}
+			tag := string(runes[position+1 : closing])
+			position = closing
+			switch {
+			case strings.Contains(tag, `\fs26`):
+				flush()
+				inTranslation, tagged = true, true
+			case strings.Contains(tag, `\fs32`):
+				flush()
+				inTranslation, tagged = false, true
+			}
+		case char == '\\' && position+1 < len(runes):
+			position++
+			switch runes[position] {
+			case 'N', 'n':
+				flush()
+			case 'h':
+				current.WriteRune(' ')
+			case '\\', '{', '}':
+				current.WriteRune(runes[position])
+			default:
+				current.WriteRune(char)
+				current.WriteRune(runes[position])
+			}
+		default:
+			current.WriteRune(char)
+		}
+	}
+	flush()
+	return origin, translation, all, tagged
+}
+
+func parseASSTimestamp(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	fields := strings.Split(raw, ":")
+	if len(fields) != 3 {
+		return 0, fmt.Errorf("非法时间戳: %s", raw)
+	}
+	hours, err := strconv.Atoi(fields[0])
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("非法时间戳: %s", raw)
+	}
+	minutes, err := strconv.Atoi(fields[1])
+	if err != nil || minutes < 0 || minutes >= 60 {
+		return 0, fmt.Errorf("非法时间戳: %s", raw)
+	}
+	seconds, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil || seconds < 0 || seconds >= 60 {
+		return 0, fmt.Errorf("非法时间戳: %s", raw)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*1000+0.5)*time.Millisecond, nil
+}
+
+func bestReviewSplit(lines []string, first []string, second []string) int {
+	best, bestScore := len(first), -1.0
+	if best > len(lines) {
+		best = len(lines)
+	}
+	for split := 0; split <= len(lines); split++ {
+		score := textSimilarity(strings.Join(lines[:split], "\n"), strings.Join(first, "\n")) + textSimilarity(strings.Join(lines[split:], "\n"), strings.Join(second, "\n"))
+		if score > bestScore+1e-9 || (score > bestScore-1e-9 && absInt(split-len(first)) < absInt(best-len(first))) {
+			best, bestScore = split, score
+		}
+	}
+	return best
+}
+
+func textSimilarity(left string, right string) float64 {
+	a, b := []rune(normalizeForCompare(left)), []rune(normalizeForCompare(right))
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for column := range previous {
+		previous[column] = column
+	}
+	for row := 1; row <= len(a); row++ {
+		current[0] = row
+		for column := 1; column <= len(b); column++ {
+			substitution := 1
+			if a[row-1] == b[column-1] {
+				substitution = 0
+			}
+			current[column] = min(previous[column-1]+substitution, previous[column]+1, current[column-1]+1)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(b)])/float64(max(len(a), len(b)))
+}
+
+func stripReviewDash(lines []string, original []string) []string {
+	if len(lines) == 0 || (len(original) > 0 && strings.HasPrefix(strings.TrimSpace(original[0]), "-")) {
+		return lines
+	}
+	result := append([]string{}, lines...)
+	result[0] = strings.TrimSpace(strings.TrimPrefix(result[0], "-"))
+	return result
+}
+
+func sameReviewTime(format string, edited time.Duration, expected time.Duration) bool {
+	if format == "ass" {
+		return formatASSTimestamp(edited) == formatASSTimestamp(expected)
+	}
+	return formatSRTTimestamp(edited) == formatSRTTimestamp(expected)
+}
+
+func equalReviewLines(left []string, right []string) bool {
+	left, right = trimReviewLines(left), trimReviewLines(right)
+	if len(left) != len(right) {
+		return false
+	}
+	for index := range left {
+		if left[index] != right[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func trimReviewLines(lines []string) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,221 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeReview(t *testing.T) {
+	baseBlocks := []Block{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, Lines: []string{"Hello"}},
+		{Index: 2, Start: 3 * time.Second, End: 4 * time.Second, Lines: []string{"How are you"}},
+		{Index: 3, Start: 5 * time.Second, End: 6 * time.Second, Lines: []string{"OK"}},
+	}
+	baseTranslations := []string{"你好", "你好吗", "OK"}
+	speakerBlocks := []Block{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, Lines: []string{"Hi"}, Speaker: "A"},
+		{Index: 2, Start: 3 * time.Second, End: 4 * time.Second, Lines: []string{"Hey"}, Speaker: "B"},
+	}
+	tests := []struct {
+		name         string
+		format       string
+		order        string
+		blocks       []Block
+		translations []string
+		edit         func(string) string
+		lines        [][]string
+		wantTexts    []string
+		starts       []time.Duration
+		edited       int
+		wantErr      bool
+	}{
+		{
+			name:      "unchanged srt",
+			format:    "srt",
+			lines:     [][]string{{"Hello"}, {"How are you"}, {"OK"}},
+			wantTexts: []string{"你好", "你好吗", "OK"},
+		},
+		{
+			name:      "unchanged ass",
+			format:    "ass",
+			lines:     [][]string{{"Hello"}, {"How are you"}, {"OK"}},
+			wantTexts: []string{"你好", "你好吗", "OK"},
+		},
+		{
+			name:   "edited srt timing",
+			format: "srt",
+			edit: func(content string) string {
+				return strings.Replace(content, "00:00:03,000 --> 00:00:04,000", "00:00:03,250 --> 00:00:04,500", 1)
+			},
+			lines:     [][]string{{"Hello"}, {"How are you"}, {"OK"}},
+			wantTexts: []string{"你好", "你好吗", "OK"},
+			starts:    []time.Duration{time.Second, 3250 * time.Millisecond, 5 * time.Second},
+			edited:    1,
+		},
+		{
+			name:   "edited ass timing",
+			format: "ass",
+			edit: func(content string) string {
+				return strings.Replace(content, "0:00:05.00", "0:00:04.80", 1)
+			},
+			lines:     [][]string{{"Hello"}, {"How are you"}, {"OK"}},
+			wantTexts: []string{"你好", "你好吗", "OK"},
+			starts:    []time.Duration{time.Second, 3 * time.Second, 4800 * time.Millisecond},
+			edited:    1,
+		},
+		{
+			name:   "edited srt translation",
+			format: "srt",
+			edit: func(content string) string {
+				return strings.Replace(content, "你好吗", "你还好吗", 1)
+			},
+			lines:     [][]string{{"Hello"}, {"How are you"}, {"OK"}},
+			wantTexts: []string{"你好", "你还好吗", "OK"},
+			edited:    1,
+		},
+		{
+			name:   "edited ass origin",
+			format: "ass",
+			edit: func(content string) string {
+				return strings.Replace(content, "How are you", "How are you doing", 1)
+			},
+			lines:     [][]string{{"Hello"}, {"How are you doing"}, {"OK"}},
+			wantTexts: []string{"你好", "你好吗", "OK"},
+			edited:    1,
+		},
+		{
+			name:   "edited origin with translation above",
+			format: "srt",
+			order:  "translation_above",
+			edit: func(content string) string {
+				return strings.Replace(content, "Hello", "Hello there", 1)
+			},
+			lines:     [][]string{{"Hello there"}, {"How are you"}, {"OK"}},
+			wantTexts: []string{"你好", "你好吗", "OK"},
+			edited:    1,
+		},
+		{
+			name:   "reordered cues merge by position",
+			format: "srt",
+			edit: func(content string) string {
+				content = strings.Replace(content, "Hello\n你好", "How are you\n你好吗#", 1)
+				return strings.Replace(content, "How are you\n你好吗\n", "Hello\n你好\n", 1)
+			},
+			lines:     [][]string{{"How are you"}, {"Hello"}, {"OK"}},
+			wantTexts: []string{"你好吗#", "你好", "OK"},
+			edited:    2,
+		},
+		{
+			name:   "removed cue is rejected",
+			format: "srt",
+			edit: func(content string) string {
+				return strings.Replace(content, "3\n00:00:05,000 --> 00:00:06,000\nOK\n", "", 1)
+			},
+			wantErr: true,
+		},
+		{
+			name:   "added ass dialogue is rejected",
+			format: "ass",
+			edit: func(content string) string {
+				return content + "Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,Extra\n"
+			},
+			wantErr: true,
+		},
+		{
+			name:         "dialogue dash is not treated as an edit",
+			format:       "srt",
+			blocks:       speakerBlocks,
+			translations: []string{"嗨", "嘿"},
+			lines:        [][]string{{"Hi"}, {"Hey"}},
+			wantTexts:    []string{"嗨", "嘿"},
+		},
+		{
+			name:         "edited line behind a dialogue dash",
+			format:       "srt",
+			blocks:       speakerBlocks,
+			translations: []string{"嗨", "嘿"},
+			edit: func(content string) string {
+				return strings.Replace(content, "- Hey", "- Hey you", 1)
+			},
+			lines:     [][]string{{"Hi"}, {"Hey you"}},
+			wantTexts: []string{"嗨", "嘿"},
+			edited:    1,
+		},
+		{
+			name:         "mismatched task data is rejected",
+			format:       "srt",
+			translations: []string{"你好"},
+			wantErr:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks, translations := test.blocks, test.translations
+			if blocks == nil {
+				blocks = baseBlocks
+			}
+			if translations == nil {
+				translations = baseTranslations
+			}
+			layout := LayoutOptions{}
+			content := renderReviewFixture(t, test.format, test.order, blocks, translations, layout)
+			if test.edit != nil {
+				content = test.edit(content)
+			}
+			result, err := MergeReview(ReviewInput{
+				Format:       test.format,
+				Content:      content,
+				Blocks:       blocks,
+				Translations: translations,
+				Layout:       layout,
+				Order:        test.order,
+			})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Edited != test.edited {
+				t.Fatalf("edited %d, want %d", result.Edited, test.edited)
+			}
+			for index, block := range result.Blocks {
+				if strings.Join(block.Lines, "\n") != strings.Join(test.lines[index], "\n") {
+					t.Fatalf("block %d lines %q, want %q", index, block.Lines, test.lines[index])
+				}
+				if result.Translations[index] != test.wantTexts[index] {
+					t.Fatalf("block %d translation %q, want %q", index, result.Translations[index], test.wantTexts[index])
+				}
+				if test.starts != nil && block.Start != test.starts[index] {
+					t.Fatalf("block %d start %s, want %s", index, block.Start, test.starts[index])
+				}
+				if block.Speaker != blocks[index].Speaker {
+					t.Fatalf("block %d speaker %q, want %q", index, block.Speaker, blocks[index].Speaker)
+				}
+			}
+		})
+	}
+}
+
+func renderReviewFixture(t *testing.T, format string, order string, blocks []Block, translations []string, layout LayoutOptions) string {
+	t.Helper()
+	if len(blocks) != len(translations) {
+		return RenderSRT(blocks)
+	}
+	rendered, wrapped := ApplyLayout(NameSpeakers(blocks, nil), translations, layout)
+	var content string
+	var err error
+	if format == "ass" {
+		content, err = RenderBilingualASS(rendered, wrapped, order)
+	} else {
+		content, err = RenderBilingualSRT(rendered, wrapped, order)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
@@ -0,0 +1,62 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SpeakerSummary struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func SummarizeSpeakers(blocks []Block, names map[string]string) []SpeakerSummary {
+	summaries := make([]SpeakerSummary, 0, 4)
+	positions := map[string]int{}
+	for _, block := range blocks {
+		if block.Speaker == "" {
+			continue
+		}
+		position, ok := positions[block.Speaker]
+		if !ok {
+			position = len(summaries)
+			positions[block.Speaker] = position
+			name := strings.TrimSpace(names[block.Speaker])
+			if name == "" {
+				name = fmt.Sprintf("说话人 %d", position+1)
+			}
+			summaries = append(summaries, SpeakerSummary{ID: block.Speaker, Name: name})
+		}
+		summaries[position].Count++
+	}
+	return summaries
+}
+
+func NameSpeakers(blocks []Block, names map[string]string) []Block {
+	summaries := SummarizeSpeakers(blocks, names)
+	if len(summaries) == 0 {
+		return blocks
+	}
+	display := make(map[string]string, len(summaries))
+	for _, summary := range summaries {
+		display[summary.ID] = summary.Name
+	}
+	named := make([]Block, len(blocks))
+	for index, block := range blocks {
+		block.Speaker = display[block.Speaker]
+		named[index] = block
+	}
+	return named
+}
+
+func dialogueDash(blocks []Block, index int) string {
+	if index == 0 || blocks[index].Speaker == "" || blocks[index-1].Speaker == "" || blocks[index].Speaker == blocks[index-1].Speaker {
+		return ""
+	}
+	return "- "
+}
+
+func assSpeakerName(name string) string {
+	return strings.NewReplacer(",", "，", "\n", " ", "\r", " ").Replace(strings.TrimSpace(name))
+}
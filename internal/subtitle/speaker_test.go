@@ -0,0 +1,108 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeSpeakers(t *testing.T) {
+	blocks := []Block{
+		{Lines: []string{"a"}, Speaker: "SPEAKER_01"},
+		{Lines: []string{"b"}},
+		{Lines: []string{"c"}, Speaker: "SPEAKER_00"},
+		{Lines: []string{"d"}, Speaker: "SPEAKER_01"},
+	}
+	tests := []struct {
+		name  string
+		names map[string]string
+		want  []SpeakerSummary
+		named []string
+	}{
+		{
+			name:  "default names follow first appearance",
+			want:  []SpeakerSummary{{ID: "SPEAKER_01", Name: "说话人 1", Count: 2}, {ID: "SPEAKER_00", Name: "说话人 2", Count: 1}},
+			named: []string{"说话人 1", "", "说话人 2", "说话人 1"},
+		},
+		{
+			name:  "custom names override defaults",
+			names: map[string]string{"SPEAKER_00": " Alice ", "SPEAKER_01": ""},
+			want:  []SpeakerSummary{{ID: "SPEAKER_01", Name: "说话人 1", Count: 2}, {ID: "SPEAKER_00", Name: "Alice", Count: 1}},
+			named: []string{"说话人 1", "", "Alice", "说话人 1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			summaries := SummarizeSpeakers(blocks, test.names)
+			if len(summaries) != len(test.want) {
+				t.Fatalf("got %+v, want %+v", summaries, test.want)
+			}
+			for index := range summaries {
+				if summaries[index] != test.want[index] {
+					t.Fatalf("got %+v, want %+v", summaries, test.want)
+				}
+			}
+			for index, block := range NameSpeakers(blocks, test.names) {
+				if block.Speaker != test.named[index] {
+					t.Fatalf("block %d named %q, want %q", index, block.Speaker, test.named[index])
+				}
+			}
+			if blocks[0].Speaker != "SPEAKER_01" {
+				t.Fatal("input blocks were modified")
+			}
+		})
+	}
+}
+
+func TestRenderSpeakers(t *testing.T) {
+	blocks := []Block{
+		{Start: time.Second, End: 2 * time.Second, Lines: []string{"Hi"}, Speaker: "Alice"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Lines: []string{"Hello"}, Speaker: "Bob, Jr."},
+		{Start: 3 * time.Second, End: 4 * time.Second, Lines: []string{"How are you"}, Speaker: "Bob, Jr."},
+		{Start: 4 * time.Second, End: 5 * time.Second, Lines: []string{"- Fine"}, Speaker: "Alice"},
+		{Start: 5 * time.Second, End: 6 * time.Second, Lines: []string{"Narration"}},
+	}
+	translations := []string{"嗨", "你好", "你好吗", "- 很好", "旁白"}
+	tests := []struct {
+		name     string
+		format   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "srt dashes only on speaker changes",
+			format:   "srt",
+			contains: []string{"\nHi\n嗨\n", "\n- Hello\n- 你好\n", "\nHow are you\n你好吗\n", "\n- Fine\n- 很好\n", "\nNarration\n旁白\n"},
+			excludes: []string{"- - Fine", "- Hi"},
+		},
+		{
+			name:     "ass carries speaker names",
+			format:   "ass",
+			contains: []string{",Default,Alice,0,0,0,,", ",Default,Bob， Jr.,0,0,0,,", ",Default,,0,0,0,,{\\fs32\\c&H00FFFFFF&}Narration"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var content string
+			var err error
+			if test.format == "ass" {
+				content, err = RenderBilingualASS(blocks, translations, "")
+			} else {
+				content, err = RenderBilingualSRT(blocks, translations, "")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, fragment := range test.contains {
+				if !strings.Contains(content, fragment) {
+					t.Fatalf("output missing %q:\n%s", fragment, content)
+				}
+			}
+			for _, fragment := range test.excludes {
+				if strings.Contains(content, fragment) {
+					t.Fatalf("output contains %q:\n%s", fragment, content)
+				}
+			}
+		})
+	}
+}
@@ -11,66 +11,32 @@ import (
 )
 
 type Block struct {
-	Index int           `json:"index"`
-	Start time.Duration `json:"start"`
-	End   time.Duration `json:"end"`
-	Lines []string      `json:"lines"`
+	Index   int           `json:"index"`
+	Start   time.Duration `json:"start"`
+	End     time.Duration `json:"end"`
+	Lines   []string      `json:"lines"`
+	Speaker string        `json:"speaker,omitempty"`
+	Region  string        `json:"region,omitempty"`
 }
 
+const RegionTop = "top"
+
 func ParseFile(path string) ([]Block, error) {
+	blocks, _, err := ParseFileWithWarnings(path)
+	return blocks, err
+}
+
+func ParseFileWithWarnings(path string) ([]Block, []ParseWarning, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return ParseSRT(string(raw))
+	return ParseSRTWithWarnings(string(raw))
 }
 
 func ParseSRT(content string) ([]Block, error) {
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	content = strings.ReplaceAll(content, "\r", "\n")
-	content = strings.TrimSpace(content)
-	if content == "" {
-		return nil, errors.New("字幕内容为空")
-	}
-	chunks := strings.Split(content, "\n\n")
-	blocks := make([]Block, 0, len(chunks))
-	for _, chunk := range chunks {
-		lines := splitNonEmptyLines(chunk)
-		if len(lines) < 3 {
-			continue
-		}
-		index, err := strconv.Atoi(strings.TrimSpace(lines[0]))
-		if err != nil {
-			continue
-		}
-		timeParts := strings.Split(lines[1], "-->")
-		if len(timeParts) != 2 {
-			continue
-		}
-		start, err := parseSRTTimestamp(strings.TrimSpace(timeParts[0]))
-		if err != nil {
-			continue
-		}
-		end, err := parseSRTTimestamp(strings.TrimSpace(timeParts[1]))
-		if err != nil {
-			continue
-		}
-		body := make([]string, 0, len(lines)-2)
-		for _, line := range lines[2:] {
-			trimmed := strings.TrimSpace(line)
-			if trimmed != "" {
-				body = append(body, trimmed)
-			}
-		}
-		if len(body) == 0 {
-			continue
-		}
-		blocks = append(blocks, Block{Index: index, Start: start, End: end, Lines: body})
-	}
-	if len(blocks) == 0 {
-		return nil, errors.New("未解析到有效的 SRT 字幕块")
-	}
-	return blocks, nil
+	blocks, _, err := ParseSRTWithWarnings(content)
+	return blocks, err
 }
 
 func RenderSRT(blocks []Block) string {
@@ -105,6 +71,13 @@ func RenderBilingualSRT(blocks []Block, translations []string, layout string) (s
 		builder.WriteString("\n")
 		originLines := block.Lines
 		translationLines := splitTranslationLines(translations[index])
+		if SameText(strings.Join(originLines, "\n"), translations[index]) {
+			translationLines = nil
+		}
+		if dash := dialogueDash(blocks, index); dash != "" {
+			originLines = withDash(originLines, dash)
+			translationLines = withDash(translationLines, dash)
+		}
 		if strings.TrimSpace(layout) == "translation_above" {
 			for _, line := range translationLines {
 				builder.WriteString(line)
@@ -147,19 +120,49 @@ func RenderBilingualASS(blocks []Block, translations []string, layout string) (s
 	builder.WriteString("[Events]\n")
 	builder.WriteString("Format: Layer,Start,End,Style,Name,MarginL,MarginR,MarginV,Effect,Text\n")
 	for index, block := range blocks {
-		originText := escapeASSText(strings.Join(block.Lines, "\\N"))
-		translationText := escapeASSText(strings.Join(splitTranslationLines(translations[index]), "\\N"))
+		originText := escapeASSText(strings.Join(block.Lines, "\n"))
+		translationText := escapeASSText(strings.Join(splitTranslationLines(translations[index]), "\n"))
 		var eventText string
-		if strings.TrimSpace(layout) == "translation_above" {
+		if SameText(strings.Join(block.Lines, "\n"), translations[index]) {
+			eventText = fmt.Sprintf("{\\fs32\\c&H00FFFFFF&}%s", originText)
+		} else if strings.TrimSpace(layout) == "translation_above" {
 			eventText = fmt.Sprintf("{\\fs26\\c&H00A5FF&}%s\\N{\\rDefault\\fs32\\c&H00FFFFFF&}%s", translationText, originText)
 		} else {
 			eventText = fmt.Sprintf("{\\fs32\\c&H00FFFFFF&}%s\\N{\\fs26\\c&H00A5FF&}%s", originText, translationText)
 		}
-		builder.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", formatASSTimestamp(block.Start), formatASSTimestamp(block.End), eventText))
+		if block.Region == RegionTop {
+			eventText = "{\\an8}" + eventText
+		}
+		builder.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Default,%s,0,0,0,,%s\n", formatASSTimestamp(block.Start), formatASSTimestamp(block.End), assSpeakerName(block.Speaker), eventText))
 	}
 	return builder.String(), nil
 }
 
+func NextInRegion(blocks []Block, index int) int {
+	for next := index + 1; next < len(blocks); next++ {
+		if blocks[next].Region == blocks[index].Region {
+			return next
+		}
+	}
+	return -1
+}
+
+func SameText(left string, right string) bool {
+	left = strings.Join(strings.Fields(strings.ToLower(left)), "")
+	return left != "" && left == strings.Join(strings.Fields(strings.ToLower(right)), "")
+}
+
+func withDash(lines []string, dash string) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	result := append([]string{}, lines...)
+	if first := strings.TrimSpace(result[0]); !strings.HasPrefix(first, "-") {
+		result[0] = dash + first
+	}
+	return result
+}
+
 func JoinText(lines []string) string {
 	return strings.Join(lines, "\n")
 }
@@ -174,51 +177,11 @@ func splitTranslationLines(text string) []string {
 		}
 	}
 	if len(lines) == 0 {
-		return []string{"[翻译为空]"}
+		return []string{EmptyTranslationPlaceholder}
 	}
 	return lines
 }
 
-func splitNonEmptyLines(chunk string) []string {
-	chunk = strings.ReplaceAll(chunk, "\r\n", "\n")
-	parts := strings.Split(chunk, "\n")
-	result := make([]string, 0, len(parts))
-	for _, part := range parts {
-		if strings.TrimSpace(part) != "" {
-			result = append(result, part)
-		}
-	}
-	return result
-}
-
-func parseSRTTimestamp(raw string) (time.Duration, error) {
-	parts := strings.Split(raw, ",")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("非法时间戳: %s", raw)
-	}
-	hms := strings.Split(parts[0], ":")
-	if len(hms) != 3 {
-		return 0, fmt.Errorf("非法时间戳: %s", raw)
-	}
-	hours, err := strconv.Atoi(hms[0])
-	if err != nil {
-		return 0, err
-	}
-	minutes, err := strconv.Atoi(hms[1])
-	if err != nil {
-		return 0, err
-	}
-	seconds, err := strconv.Atoi(hms[2])
-	if err != nil {
-		return 0, err
-	}
-	milliseconds, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, err
-	}
-	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(milliseconds)*time.Millisecond, nil
-}
-
 func formatSRTTimestamp(value time.Duration) string {
 	totalMilliseconds := value.Milliseconds()
 	hours := totalMilliseconds / 3600000
@@ -0,0 +1,110 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	TimingShift     = "shift"
+	TimingLinear    = "linear"
+	TimingFrameRate = "framerate"
+)
+
+type TimingOperation struct {
+	Kind          string  `json:"kind"`
+	OffsetMS      int64   `json:"offset_ms,omitempty"`
+	SourceFirstMS int64   `json:"source_first_ms,omitempty"`
+	TargetFirstMS int64   `json:"target_first_ms,omitempty"`
+	SourceLastMS  int64   `json:"source_last_ms,omitempty"`
+	TargetLastMS  int64   `json:"target_last_ms,omitempty"`
+	FromFPS       float64 `json:"from_fps,omitempty"`
+	ToFPS         float64 `json:"to_fps,omitempty"`
+}
+
+func (o TimingOperation) Describe() string {
+	switch strings.ToLower(strings.TrimSpace(o.Kind)) {
+	case TimingShift:
+		return fmt.Sprintf("整体平移 %+d 毫秒", o.OffsetMS)
+	case TimingLinear:
+		return fmt.Sprintf("两点线性校正 %s→%s, %s→%s",
+			formatSRTTimestamp(time.Duration(o.SourceFirstMS)*time.Millisecond), formatSRTTimestamp(time.Duration(o.TargetFirstMS)*time.Millisecond),
+			formatSRTTimestamp(time.Duration(o.SourceLastMS)*time.Millisecond), formatSRTTimestamp(time.Duration(o.TargetLastMS)*time.Millisecond))
+	case TimingFrameRate:
+		return fmt.Sprintf("帧率转换 %.3f → %.3f fps", o.FromFPS, o.ToFPS)
+	}
+	return o.Kind
+}
+
+func ApplyTiming(blocks []Block, operation TimingOperation) ([]Block, error) {
+	switch strings.ToLower(strings.TrimSpace(operation.Kind)) {
+	case TimingShift:
+		return Shift(blocks, time.Duration(operation.OffsetMS)*time.Millisecond), nil
+	case TimingLinear:
+		return LinearCorrect(blocks,
+			time.Duration(operation.SourceFirstMS)*time.Millisecond, time.Duration(operation.TargetFirstMS)*time.Millisecond,
+			time.Duration(operation.SourceLastMS)*time.Millisecond, time.Duration(operation.TargetLastMS)*time.Millisecond)
+	case TimingFrameRate:
+		return ConvertFrameRate(blocks, operation.FromFPS, operation.ToFPS)
+	}
+	return nil, fmt.Errorf("不支持的时间轴操作: %s", operation.Kind)
+}
+
+func Shift(blocks []Block, offset time.Duration) []Block {
+	return mapTimes(blocks, func(value time.Duration) time.Duration {
+		return value + offset
+	})
+}
+
+func LinearCorrect(blocks []Block, sourceFirst time.Duration, targetFirst time.Duration, sourceLast time.Duration, targetLast time.Duration) ([]Block, error) {
+	if sourceLast == sourceFirst {
+		return nil, errors.New("两个参考点的原始时间不能相同")
+	}
+	if (targetLast > targetFirst) != (sourceLast > sourceFirst) || targetLast == targetFirst {
+		return nil, errors.New("参考点顺序不一致，无法进行线性校正")
+	}
+	scale := float64(targetLast-targetFirst) / float64(sourceLast-sourceFirst)
+	return mapTimes(blocks, func(value time.Duration) time.Duration {
+		return targetFirst + time.Duration(math.Round(float64(value-sourceFirst)*scale))
+	}), nil
+}
+
+func ConvertFrameRate(blocks []Block, fromFPS float64, toFPS float64) ([]Block, error) {
+	if fromFPS <= 0 || toFPS <= 0 {
+		return nil, errors.New("帧率必须大于 0")
+	}
+	scale := fromFPS / toFPS
+	return mapTimes(blocks, func(value time.Duration) time.Duration {
+		return time.Duration(math.Round(float64(value) * scale))
+	}), nil
+}
+
+func Retime(blocks []Block, scale float64, offset time.Duration) []Block {
+	if scale <= 0 {
+		scale = 1
+	}
+	return mapTimes(blocks, func(value time.Duration) time.Duration {
+		return time.Duration(math.Round(float64(value)*scale)) + offset
+	})
+}
+
+func mapTimes(blocks []Block, transform func(time.Duration) time.Duration) []Block {
+	result := make([]Block, len(blocks))
+	for index, block := range blocks {
+		block.Start = clampTime(transform(block.Start))
+		block.End = clampTime(transform(block.End))
+		block.Lines = append([]string{}, block.Lines...)
+		result[index] = block
+	}
+	return result
+}
+
+func clampTime(value time.Duration) time.Duration {
+	if value < 0 {
+		return 0
+	}
+	return value.Round(time.Millisecond)
+}
@@ -0,0 +1,82 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyTiming(t *testing.T) {
+	blocks := []Block{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, Lines: []string{"A"}},
+		{Index: 2, Start: 10 * time.Second, End: 12 * time.Second, Lines: []string{"B"}},
+	}
+	tests := []struct {
+		name      string
+		operation TimingOperation
+		want      [][2]time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "shift forward",
+			operation: TimingOperation{Kind: TimingShift, OffsetMS: 1500},
+			want:      [][2]time.Duration{{2500 * time.Millisecond, 3500 * time.Millisecond}, {11500 * time.Millisecond, 13500 * time.Millisecond}},
+		},
+		{
+			name:      "shift before zero clamps",
+			operation: TimingOperation{Kind: TimingShift, OffsetMS: -1500},
+			want:      [][2]time.Duration{{0, 500 * time.Millisecond}, {8500 * time.Millisecond, 10500 * time.Millisecond}},
+		},
+		{
+			name:      "linear correction maps both reference points",
+			operation: TimingOperation{Kind: TimingLinear, SourceFirstMS: 1000, TargetFirstMS: 2000, SourceLastMS: 10000, TargetLastMS: 20000},
+			want:      [][2]time.Duration{{2 * time.Second, 4 * time.Second}, {20 * time.Second, 24 * time.Second}},
+		},
+		{
+			name:      "linear correction rejects equal sources",
+			operation: TimingOperation{Kind: TimingLinear, SourceFirstMS: 1000, TargetFirstMS: 2000, SourceLastMS: 1000, TargetLastMS: 3000},
+			wantErr:   true,
+		},
+		{
+			name:      "linear correction rejects reversed targets",
+			operation: TimingOperation{Kind: TimingLinear, SourceFirstMS: 1000, TargetFirstMS: 5000, SourceLastMS: 10000, TargetLastMS: 2000},
+			wantErr:   true,
+		},
+		{
+			name:      "frame rate conversion",
+			operation: TimingOperation{Kind: TimingFrameRate, FromFPS: 25, ToFPS: 23.976},
+			want:      [][2]time.Duration{{1043 * time.Millisecond, 2085 * time.Millisecond}, {10427 * time.Millisecond, 12513 * time.Millisecond}},
+		},
+		{
+			name:      "frame rate must be positive",
+			operation: TimingOperation{Kind: TimingFrameRate, FromFPS: 0, ToFPS: 25},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown kind",
+			operation: TimingOperation{Kind: "stretch"},
+			wantErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ApplyTiming(blocks, test.operation)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for index, block := range result {
+				if block.Start != test.want[index][0] || block.End != test.want[index][1] {
+					t.Fatalf("block %d is %s-%s, want %s-%s", index, block.Start, block.End, test.want[index][0], test.want[index][1])
+				}
+			}
+			if blocks[0].Start != time.Second {
+				t.Fatal("input blocks were modified")
+			}
+		})
+	}
+}
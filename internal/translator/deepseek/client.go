@@ -23,6 +23,7 @@ type Client struct {
 
 type translationItem struct {
 	Index       int    `json:"index"`
+	Speaker     string `json:"speaker,omitempty"`
 	SourceText  string `json:"source_text"`
 	Translation string `json:"translation,omitempty"`
 }
@@ -88,8 +89,10 @@ func (c Client) TranslateBlocks(ctx context.Context, prompt string, sourceLangua
 
 func (c Client) translateBatch(ctx context.Context, prompt string, sourceLanguage string, targetLanguage string, blocks []subtitle.Block) ([]string, error) {
 	items := make([]translationItem, 0, len(blocks))
+	hasSpeakers := false
 	for _, block := range blocks {
-		items = append(items, translationItem{Index: block.Index, SourceText: subtitle.JoinText(block.Lines)})
+		items = append(items, translationItem{Index: block.Index, Speaker: block.Speaker, SourceText: subtitle.JoinText(block.Lines)})
+		hasSpeakers = hasSpeakers || block.Speaker != ""
 	}
 	payloadJSON, err := json.Marshal(map[string]any{
 		"source_language": sourceLanguage,
@@ -103,6 +106,9 @@ func (c Client) translateBatch(ctx context.Context, prompt string, sourceLanguag
 	if systemPrompt == "" {
 		systemPrompt = "请逐条翻译字幕文本，只输出目标语言译文，不要解释，不要合并或拆分字幕。"
 	}
+	if hasSpeakers {
+		systemPrompt += "\nspeaker 字段是说话人名称，仅用于理解对话关系、称谓和语气，不要翻译或写进译文。"
+	}
 	systemPrompt = systemPrompt + "\n返回严格 JSON，格式为 {\"items\":[{\"index\":1,\"translation\":\"...\"}]}。禁止输出额外说明。"
 	requestBody := chatCompletionRequest{
 		Model: c.Model,
@@ -37,6 +37,16 @@ export function saveSettings(payload) {
   })
 }
 
+export function getOCRCache() {
+  return apiRequest('/api/v1/ocr/cache')
+}
+
+export function clearOCRCache() {
+  return apiRequest('/api/v1/ocr/cache', {
+    method: 'DELETE'
+  })
+}
+
 export function listMedia(limit = 200) {
   return apiRequest(`/api/v1/media?limit=${limit}`)
 }
@@ -47,6 +57,27 @@ export function scanMedia() {
   })
 }
 
+export function getMediaStreams(id) {
+  return apiRequest(`/api/v1/media/${id}/streams`)
+}
+
+export function getMediaOCRRegions(id) {
+  return apiRequest(`/api/v1/media/${id}/ocr-regions`)
+}
+
+export function saveMediaOCRRegions(id, regions) {
+  return apiRequest(`/api/v1/media/${id}/ocr-regions`, {
+    method: 'PUT',
+    body: JSON.stringify({ regions })
+  })
+}
+
+export function detectMediaOCRRegions(id) {
+  return apiRequest(`/api/v1/media/${id}/ocr-regions/detect`, {
+    method: 'POST'
+  })
+}
+
 export function listJobs(limit = 100) {
   return apiRequest(`/api/v1/jobs?limit=${limit}`)
 }
@@ -59,6 +90,28 @@ export function getJobLogs(id, limit = 200) {
   return apiRequest(`/api/v1/jobs/${id}/logs?limit=${limit}`)
 }
 
+export function getJobQA(id) {
+  return apiRequest(`/api/v1/jobs/${id}/qa`)
+}
+
+export function adjustJobTiming(id, operation) {
+  return apiRequest(`/api/v1/jobs/${id}/timing`, {
+    method: 'POST',
+    body: JSON.stringify(operation)
+  })
+}
+
+export function getJobSpeakers(id) {
+  return apiRequest(`/api/v1/jobs/${id}/speakers`)
+}
+
+export function renameJobSpeakers(id, speakers) {
+  return apiRequest(`/api/v1/jobs/${id}/speakers`, {
+    method: 'PUT',
+    body: JSON.stringify({ speakers })
+  })
+}
+
 export function createJob(payload) {
   return apiRequest('/api/v1/jobs', {
     method: 'POST',
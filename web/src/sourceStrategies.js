@@ -0,0 +1,24 @@
+export const sourceStrategyTitles = {
+  sidecar: '外挂字幕',
+  embedded_text: '内嵌文本字幕轨',
+  bitmap_ocr: '图形字幕轨 OCR',
+  frame_ocr: '硬字幕抽帧 OCR',
+  asr: 'ASR 语音转写'
+}
+
+export function defaultSourceStrategies() {
+  return Object.keys(sourceStrategyTitles).map((key) => ({ key, enabled: true }))
+}
+
+export function sourceStrategyTitle(key) {
+  return sourceStrategyTitles[key] || key
+}
+
+export function moveSourceStrategy(list, index, offset) {
+  const target = index + offset
+  if (target < 0 || target >= list.length) {
+    return
+  }
+  const [item] = list.splice(index, 1)
+  list.splice(target, 0, item)
+}